@@ -0,0 +1,242 @@
+package rift
+
+import (
+	"sync"
+
+	"github.com/satori/go.uuid"
+)
+
+// BatchCallback is invoked once every job in a batch has finished
+type BatchCallback func(*Batch)
+
+type batchJob struct {
+	job   Job
+	retry uint8
+}
+
+// Batch groups many jobs so they can be queued together and tracked as a
+// single unit of work
+type Batch struct {
+	ID uuid.UUID
+
+	queue *Queue
+	jobs  []ReservedJob
+
+	mutex      sync.Mutex
+	dispatched bool
+	pending    uint32
+	succeeded  uint32
+	failed     uint32
+	finished   chan bool
+
+	// callbacks
+	onComplete   []BatchCallback
+	onSuccess    []BatchCallback
+	onFailure    []BatchCallback
+	completeJobs []batchJob
+	successJobs  []batchJob
+	failureJobs  []batchJob
+}
+
+// NewBatch creates an empty batch of jobs for this queue
+func (q *Queue) NewBatch() *Batch {
+	return &Batch{
+		ID:       uuid.NewV4(),
+		queue:    q,
+		jobs:     make([]ReservedJob, 0),
+		finished: make(chan bool),
+	}
+}
+
+// Add a job to the batch and return the id it will be queued under. Jobs
+// cannot be added once the batch has been dispatched.
+func (b *Batch) Add(job Job, retry uint8) uuid.UUID {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.dispatched {
+		return uuid.Nil
+	}
+
+	reserved := newReservedJob(job, retry)
+	reserved.batch = b
+	b.jobs = append(b.jobs, reserved)
+
+	return reserved.ID
+}
+
+// OnComplete registers a callback fired when every job has finished,
+// regardless of the outcome
+func (b *Batch) OnComplete(fn BatchCallback) *Batch {
+	b.mutex.Lock()
+	b.onComplete = append(b.onComplete, fn)
+	b.mutex.Unlock()
+	return b
+}
+
+// OnSuccess registers a callback fired when every job has finished without error
+func (b *Batch) OnSuccess(fn BatchCallback) *Batch {
+	b.mutex.Lock()
+	b.onSuccess = append(b.onSuccess, fn)
+	b.mutex.Unlock()
+	return b
+}
+
+// OnFailure registers a callback fired when every job has finished and at
+// least one of them failed after exhausting its retries
+func (b *Batch) OnFailure(fn BatchCallback) *Batch {
+	b.mutex.Lock()
+	b.onFailure = append(b.onFailure, fn)
+	b.mutex.Unlock()
+	return b
+}
+
+// OnCompleteJob queues a job when every job has finished, regardless of the outcome
+func (b *Batch) OnCompleteJob(job Job, retry uint8) *Batch {
+	b.mutex.Lock()
+	b.completeJobs = append(b.completeJobs, batchJob{job, retry})
+	b.mutex.Unlock()
+	return b
+}
+
+// OnSuccessJob queues a job when every job has finished without error
+func (b *Batch) OnSuccessJob(job Job, retry uint8) *Batch {
+	b.mutex.Lock()
+	b.successJobs = append(b.successJobs, batchJob{job, retry})
+	b.mutex.Unlock()
+	return b
+}
+
+// OnFailureJob queues a job when every job has finished and at least one of
+// them failed
+func (b *Batch) OnFailureJob(job Job, retry uint8) *Batch {
+	b.mutex.Lock()
+	b.failureJobs = append(b.failureJobs, batchJob{job, retry})
+	b.mutex.Unlock()
+	return b
+}
+
+// Dispatch queues every job in the batch from a single goroutine and returns
+// the id of the batch
+func (b *Batch) Dispatch() uuid.UUID {
+	b.mutex.Lock()
+	if b.dispatched {
+		b.mutex.Unlock()
+		return b.ID
+	}
+	b.dispatched = true
+	b.pending = uint32(len(b.jobs))
+	jobs := b.jobs
+	b.mutex.Unlock()
+
+	b.queue.logger.log(LogBatchQueued, field("batch", b.ID.String()), field("size", len(jobs)))
+
+	if len(jobs) == 0 {
+		b.complete()
+		return b.ID
+	}
+
+	// every job can be cancelled once Dispatch returns, even those the
+	// goroutine hasn't reached yet
+	ids := make([]uuid.UUID, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	b.queue.markQueued(ids...)
+
+	go func() {
+		for _, job := range jobs {
+			b.queue.enqueue(job)
+		}
+	}()
+
+	return b.ID
+}
+
+// Wait blocks until every job in the batch has finished and its callbacks
+// have run
+func (b *Batch) Wait() {
+	<-b.finished
+}
+
+// Size is the number of jobs added to the batch
+func (b *Batch) Size() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.jobs)
+}
+
+// Pending is the number of jobs which have not finished yet
+func (b *Batch) Pending() uint32 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.pending
+}
+
+// Succeeded is the number of jobs which finished without error
+func (b *Batch) Succeeded() uint32 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.succeeded
+}
+
+// Failed is the number of jobs which failed after exhausting their retries
+func (b *Batch) Failed() uint32 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.failed
+}
+
+// done records the final outcome of one of the batch jobs
+func (b *Batch) done(err error) {
+	b.mutex.Lock()
+	if b.pending == 0 {
+		b.mutex.Unlock()
+		return
+	}
+	b.pending--
+	if err != nil {
+		b.failed++
+	} else {
+		b.succeeded++
+	}
+	finished := b.pending == 0
+	b.mutex.Unlock()
+
+	if finished {
+		b.complete()
+	}
+}
+
+// complete fires the batch callbacks and queues any callback jobs
+func (b *Batch) complete() {
+	b.mutex.Lock()
+	callbacks := append([]BatchCallback{}, b.onComplete...)
+	jobs := append([]batchJob{}, b.completeJobs...)
+	if b.failed > 0 {
+		callbacks = append(callbacks, b.onFailure...)
+		jobs = append(jobs, b.failureJobs...)
+	} else {
+		callbacks = append(callbacks, b.onSuccess...)
+		jobs = append(jobs, b.successJobs...)
+	}
+	b.mutex.Unlock()
+
+	b.queue.logger.log(LogBatchFinished,
+		field("batch", b.ID.String()),
+		field("succeeded", b.Succeeded()),
+		field("failed", b.Failed()),
+	)
+
+	for _, job := range jobs {
+		b.queue.Later(job.job, job.retry)
+	}
+
+	// callbacks run outside of the worker so a slow callback cannot hold it up
+	go func() {
+		for _, fn := range callbacks {
+			fn(b)
+		}
+		close(b.finished)
+	}()
+}
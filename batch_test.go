@@ -0,0 +1,111 @@
+package rift_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/bmartel/rift"
+	"github.com/satori/go.uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type BrokenJob struct{}
+
+func (t BrokenJob) Tag() string {
+	return "BrokenJob"
+}
+
+func (t BrokenJob) Deserialize(data map[string]interface{}) rift.Job {
+	return BrokenJob{}
+}
+
+func (t BrokenJob) Process(service rift.Service) error {
+	return fmt.Errorf("always broken")
+}
+
+var _ = Describe("Batch", func() {
+	var (
+		queue *rift.Queue
+	)
+
+	BeforeEach(func() {
+		queue = rift.New(&rift.Options{Tag: "Test", Workers: 10, Queues: 10}, nil)
+	})
+	AfterEach(func() {
+		queue.Close()
+	})
+
+	It("should track a batch of jobs and fire the success callbacks", func(done Done) {
+		var completed, succeeded, failed int32
+
+		batch := queue.NewBatch()
+		for i := 0; i < 50; i++ {
+			batch.Add(SampleJob{i + 1, "Rift", "Batched"}, 0)
+		}
+		batch.OnComplete(func(b *rift.Batch) { atomic.AddInt32(&completed, 1) }).
+			OnSuccess(func(b *rift.Batch) { atomic.AddInt32(&succeeded, 1) }).
+			OnFailure(func(b *rift.Batch) { atomic.AddInt32(&failed, 1) })
+
+		batch.Dispatch()
+		batch.Wait()
+
+		Expect(batch.Size()).To(Equal(50))
+		Expect(batch.Pending()).To(Equal(uint32(0)))
+		Expect(batch.Succeeded()).To(Equal(uint32(50)))
+		Expect(batch.Failed()).To(Equal(uint32(0)))
+		Expect(atomic.LoadInt32(&completed)).To(Equal(int32(1)))
+		Expect(atomic.LoadInt32(&succeeded)).To(Equal(int32(1)))
+		Expect(atomic.LoadInt32(&failed)).To(Equal(int32(0)))
+
+		close(done)
+	}, 3)
+
+	It("should fire the failure callbacks once retries are exhausted", func(done Done) {
+		var failed int32
+
+		batch := queue.NewBatch()
+		batch.Add(SampleJob{1, "Rift", "Batched"}, 0)
+		batch.Add(BrokenJob{}, 1)
+		batch.OnFailure(func(b *rift.Batch) { atomic.AddInt32(&failed, 1) })
+
+		batch.Dispatch()
+		batch.Wait()
+
+		Expect(batch.Succeeded()).To(Equal(uint32(1)))
+		Expect(batch.Failed()).To(Equal(uint32(1)))
+		Expect(atomic.LoadInt32(&failed)).To(Equal(int32(1)))
+
+		close(done)
+	}, 3)
+
+	It("should queue callback jobs when the batch finishes", func(done Done) {
+		batch := queue.NewBatch()
+		batch.Add(SampleJob{1, "Rift", "Batched"}, 0)
+		batch.OnSuccessJob(SampleJob{2, "Rift", "Callback"}, 0)
+		batch.OnFailureJob(SampleJob{3, "Rift", "Never queued"}, 0)
+
+		batch.Dispatch()
+		batch.Wait()
+
+		time.Sleep(time.Millisecond * 50)
+
+		stats := queue.Stats()
+		Expect(stats.QueuedJobs).To(Equal(uint32(2)))
+		Expect(stats.ProcessedJobs).To(Equal(uint32(2)))
+
+		close(done)
+	}, 3)
+
+	It("should complete an empty batch immediately", func(done Done) {
+		batch := queue.NewBatch()
+		batch.Dispatch()
+		batch.Wait()
+
+		Expect(batch.Add(SampleJob{1, "Rift", "Too late"}, 0)).To(Equal(uuid.Nil))
+
+		close(done)
+	}, 3)
+})
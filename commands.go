@@ -0,0 +1,199 @@
+package rift
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+	"github.com/satori/go.uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Enqueue creates a job from a remote request, the tag must have been
+// registered with the queue. The request data is a JSON object of the job's
+// field values, keyed the same way as its blueprint.
+func (q *Queue) Enqueue(ctx context.Context, req *summary.EnqueueRequest) (*summary.EnqueueReply, error) {
+	if req.App != "" && req.App != q.stats.App {
+		return nil, status.Errorf(codes.NotFound, "queue belongs to %s, not %s", q.stats.App, req.App)
+	}
+	if req.QueueId != "" && req.QueueId != q.id {
+		return nil, status.Errorf(codes.NotFound, "queue %s is not %s", q.id, req.QueueId)
+	}
+	if req.Retry > math.MaxUint8 {
+		return nil, status.Errorf(codes.InvalidArgument, "retry %d is more than %d", req.Retry, math.MaxUint8)
+	}
+
+	job, err := q.registry.DecodeJob(req.Tag, []byte(req.Data))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	id := q.LaterContext(ctx, job, uint8(req.Retry))
+	return &summary.EnqueueReply{Id: id.String(), QueueId: q.id}, nil
+}
+
+// RegisterEnqueueServer serves the Enqueue rpc of the queue on a grpc server,
+// letting other processes create its jobs directly. The other rpcs of the
+// Summary service are served by the stats server.
+func RegisterEnqueueServer(s *grpc.Server, q *Queue) {
+	summary.RegisterSummaryServer(s, enqueueServer{q})
+}
+
+type enqueueServer struct {
+	queue *Queue
+}
+
+func (s enqueueServer) Enqueue(ctx context.Context, req *summary.EnqueueRequest) (*summary.EnqueueReply, error) {
+	return s.queue.Enqueue(ctx, req)
+}
+
+func (s enqueueServer) Report(stream summary.Summary_ReportServer) error {
+	return status.Error(codes.Unimplemented, "reports are received by the stats server")
+}
+
+func (s enqueueServer) Commands(stream summary.Summary_CommandsServer) error {
+	return status.Error(codes.Unimplemented, "commands are sent by the stats server")
+}
+
+// commands is the subscription to the commands sent through the stats server
+type commands struct {
+	addr       string
+	dial       []grpc.DialOption
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	removed chan bool
+}
+
+func newCommands(opts *Options) *commands {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &commands{
+		addr:       opts.StatsAddr,
+		dial:       statsDialOptions(opts),
+		backoff:    opts.ReconnectBackoff,
+		maxBackoff: opts.MaxReconnectBackoff,
+		ctx:        ctx,
+		cancel:     cancel,
+		removed:    make(chan bool),
+	}
+}
+
+// startCommands keeps a commands stream open to the stats server until the
+// queue closes, reconnecting with the same backoff as the reporter
+func (q *Queue) startCommands() {
+	c := q.commands
+	defer close(c.removed)
+
+	attempts := 0
+	for {
+		connected, err := q.subscribeCommands()
+		if c.ctx.Err() != nil {
+			return
+		}
+		if connected {
+			attempts = 0
+		}
+		attempts++
+
+		delay := backoffDelay(c.backoff, c.maxBackoff, attempts)
+		q.logger.log(LogCommandsFailed, field("addr", c.addr), field("error", err.Error()), field("retry", delay.Seconds()))
+
+		select {
+		case <-time.After(delay):
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// subscribeCommands runs the commands sent over one stream, replying with
+// their outcome until the stream breaks
+func (q *Queue) subscribeCommands() (bool, error) {
+	c := q.commands
+
+	conn, err := grpc.DialContext(c.ctx, c.addr, c.dial...)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	stream, err := summary.NewSummaryClient(conn).Commands(c.ctx)
+	if err != nil {
+		return false, err
+	}
+	// the first message identifies the queue to the stats server
+	if err := stream.Send(&summary.CommandReply{App: q.stats.App, QueueId: q.id}); err != nil {
+		return false, err
+	}
+	q.logger.log(LogCommandsConnected, field("addr", c.addr))
+
+	for {
+		cmd, err := stream.Recv()
+		if err != nil {
+			return true, err
+		}
+		if err := stream.Send(q.command(c.ctx, cmd)); err != nil {
+			return true, err
+		}
+	}
+}
+
+// command runs a command, replying with its outcome
+func (q *Queue) command(ctx context.Context, cmd *summary.Command) *summary.CommandReply {
+	reply := &summary.CommandReply{Id: cmd.Id, App: q.stats.App, QueueId: q.id}
+
+	var err error
+	switch {
+	case cmd.Enqueue != nil:
+		q.logger.log(LogCommandReceived, field("command", "enqueue"), field("tag", cmd.Enqueue.Tag))
+		reply.Enqueue, err = q.Enqueue(ctx, cmd.Enqueue)
+	case cmd.Retry != nil:
+		q.logger.log(LogCommandReceived, field("command", "retry"), field("job", cmd.Retry.JobId))
+		err = q.jobCommand(cmd.Retry.JobId, q.Retry)
+		reply.JobIds = []string{cmd.Retry.JobId}
+	case cmd.Cancel != nil:
+		q.logger.log(LogCommandReceived, field("command", "cancel"), field("job", cmd.Cancel.JobId))
+		err = q.jobCommand(cmd.Cancel.JobId, q.Cancel)
+		reply.JobIds = []string{cmd.Cancel.JobId}
+	case cmd.Purge != nil:
+		q.logger.log(LogCommandReceived, field("command", "purge"), field("job", cmd.Purge.JobId), field("status", cmd.Purge.Status))
+		if cmd.Purge.JobId != "" {
+			err = q.jobCommand(cmd.Purge.JobId, q.PurgeJob)
+			reply.JobIds = []string{cmd.Purge.JobId}
+		} else {
+			reply.JobIds = q.Purge(cmd.Purge.Status)
+		}
+	default:
+		err = status.Error(codes.Unimplemented, "unknown command")
+	}
+
+	if err != nil {
+		reply.JobIds = nil
+		s := status.Convert(err)
+		reply.Code = uint32(s.Code())
+		reply.Error = s.Message()
+	}
+	return reply
+}
+
+// jobCommand runs a command on a single job, turning its error into a status
+func (q *Queue) jobCommand(jobID string, fn func(uuid.UUID) error) error {
+	id, err := uuid.FromString(jobID)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid job id %s", jobID)
+	}
+
+	switch err := fn(id); err {
+	case nil:
+		return nil
+	case ErrJobNotFound:
+		return status.Errorf(codes.NotFound, "job %s not found", jobID)
+	default:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+}
@@ -0,0 +1,145 @@
+package rift_test
+
+import (
+	"context"
+	"net"
+
+	"github.com/bmartel/rift"
+	"github.com/bmartel/rift/summary"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type RatioJob struct {
+	Count   int
+	Divisor int
+}
+
+func (t RatioJob) Tag() string {
+	return "RatioJob"
+}
+
+func (t RatioJob) Deserialize(data map[string]interface{}) rift.Job {
+	return RatioJob{
+		Count:   data["count"].(int) / data["divisor"].(int),
+		Divisor: data["divisor"].(int),
+	}
+}
+
+func (t RatioJob) Process(service rift.Service) error {
+	return nil
+}
+
+var _ = Describe("Commands", func() {
+	var (
+		queue  *rift.Queue
+		server *grpc.Server
+		client summary.SummaryClient
+		conn   *grpc.ClientConn
+	)
+
+	BeforeEach(func() {
+		queue = rift.New(&rift.Options{Tag: "Test", Workers: 2, Queues: 2}, nil)
+		queue.Register(SampleJob{}, RatioJob{})
+
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		server = grpc.NewServer()
+		rift.RegisterEnqueueServer(server, queue)
+		go server.Serve(lis)
+
+		conn, err = grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+		Expect(err).ToNot(HaveOccurred())
+		client = summary.NewSummaryClient(conn)
+	})
+
+	AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		queue.Close()
+	})
+
+	It("should create registered jobs from their JSON values", func(done Done) {
+		reply, err := client.Enqueue(context.Background(), &summary.EnqueueRequest{
+			App:  "Test",
+			Tag:  "SampleJob",
+			Data: `{"id": 2, "title": "Queued remotely", "body": "This job came from another process"}`,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reply.Id).ToNot(BeEmpty())
+		Expect(reply.QueueId).To(Equal(queue.Stats().QueueId))
+
+		Eventually(func() uint32 { return queue.ProcessedJobs() }).Should(Equal(uint32(1)))
+
+		close(done)
+	}, 3)
+
+	It("should reject jobs which can't be created", func() {
+		requests := []*summary.EnqueueRequest{
+			{Tag: "UnknownJob", Data: `{}`},
+			{Tag: "SampleJob", Data: `{"id": 2, "author": "Rift"}`},
+			{Tag: "SampleJob", Data: `{"id": "two"}`},
+			{Tag: "SampleJob", Data: `[1, 2]`},
+			{Tag: "RatioJob", Data: `{"count": 1}`},
+			{App: "Other", Tag: "SampleJob", Data: `{}`},
+		}
+		for _, req := range requests {
+			_, err := client.Enqueue(context.Background(), req)
+			Expect(err).To(HaveOccurred())
+			Expect(status.Code(err)).To(Or(Equal(codes.InvalidArgument), Equal(codes.NotFound)))
+		}
+		Expect(queue.QueuedJobs()).To(Equal(uint32(0)))
+	})
+
+	It("should convert values to the kinds of the blueprint", func() {
+		registry := rift.NewRegistry()
+		registry.SerializeJob(RatioJob{}, &summary.Stats{})
+
+		job, err := registry.DecodeJob("RatioJob", []byte(`{"count": "9", "divisor": 3}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(job).To(Equal(RatioJob{Count: 3, Divisor: 3}))
+
+		_, err = registry.DecodeJob("RatioJob", []byte(`{"count": 1.5, "divisor": 1}`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should run commands sent through the stats server", func(done Done) {
+		statsServer, srv, addr := serveReports("127.0.0.1:0")
+		defer statsServer.Stop()
+
+		remote := rift.New(&rift.Options{
+			Tag:            "Test",
+			Workers:        2,
+			Queues:         2,
+			StatsAddr:      addr,
+			RemoteCommands: true,
+		}, nil)
+		defer remote.Close()
+		remote.Register(SampleJob{})
+
+		srv.commands <- &summary.Command{Id: "1", Enqueue: &summary.EnqueueRequest{
+			Tag:  "SampleJob",
+			Data: `{"id": 3, "title": "Queued through the stats server", "body": "Sent as a command"}`,
+		}}
+		var reply *summary.CommandReply
+		Eventually(srv.replies).Should(Receive(&reply))
+		Expect(reply.Id).To(Equal("1"))
+		Expect(reply.Error).To(BeEmpty())
+		Expect(reply.QueueId).To(Equal(remote.Stats().QueueId))
+		Expect(reply.Enqueue.Id).ToNot(BeEmpty())
+
+		Eventually(srv.status(reply.Enqueue.Id)).Should(Equal("processed"))
+
+		srv.commands <- &summary.Command{Id: "2", Enqueue: &summary.EnqueueRequest{Tag: "UnknownJob"}}
+		Eventually(srv.replies).Should(Receive(&reply))
+		Expect(reply.Id).To(Equal("2"))
+		Expect(codes.Code(reply.Code)).To(Equal(codes.InvalidArgument))
+		Expect(reply.Error).To(ContainSubstring("UnknownJob"))
+
+		close(done)
+	}, 5)
+})
@@ -0,0 +1,202 @@
+package rift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+var (
+	// ErrJobCancelled is the outcome of a cancelled job, as reported to its batch
+	ErrJobCancelled = errors.New("job cancelled")
+	// ErrJobNotFound is returned for jobs no longer held by the queue
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// Retry queues a job again which failed after running out of retries, keeping
+// its id. The job is held until it is evicted from the stats history.
+func (q *Queue) Retry(id uuid.UUID) error {
+	q.controlMutex.Lock()
+	job, ok := q.unbury(id)
+	q.controlMutex.Unlock()
+
+	if !ok {
+		if status := q.jobStatus(id); status != "" {
+			return fmt.Errorf("job %s is %s, only jobs which ran out of retries can be retried", id, status)
+		}
+		return ErrJobNotFound
+	}
+
+	job.RequestedAt = time.Now()
+	job.StartedAt = time.Time{}
+	job.Requeued = 0
+	// the batch already counted the job as finished
+	job.batch = nil
+	q.submit(job)
+	return nil
+}
+
+// Cancel stops a job from running if it is still queued. A running job is
+// reported as cancelled once it returns rather than failing or being retried,
+// it is interrupted only if it is a ContextJob.
+func (q *Queue) Cancel(id uuid.UUID) error {
+	// the queue's own state is checked first, the stats only catch up with
+	// a job once the metrics capture has applied its update
+	q.controlMutex.Lock()
+	if cancel, ok := q.running[id]; ok {
+		q.cancelled[id] = true
+		q.controlMutex.Unlock()
+		cancel()
+		return nil
+	}
+	if q.queued[id] {
+		q.cancelled[id] = true
+		q.controlMutex.Unlock()
+		return nil
+	}
+	q.controlMutex.Unlock()
+
+	status := q.jobStatus(id)
+	if status == "" {
+		return ErrJobNotFound
+	}
+	return fmt.Errorf("job %s is %s and can't be cancelled", id, status)
+}
+
+// Purge removes the finished jobs with the given status from the stats
+// history, or every finished job if no status is given. It returns the ids
+// of the removed jobs, which can't be retried afterwards.
+func (q *Queue) Purge(status string) []string {
+	q.statsMutex.Lock()
+	purged := make([]string, 0)
+	for id, job := range q.stats.Jobs {
+		if finished(job.Status) && (status == "" || job.Status == status) {
+			q.history.remove(q.stats, id)
+			purged = append(purged, id)
+		}
+	}
+	q.statsMutex.Unlock()
+
+	q.forget(purged)
+	return purged
+}
+
+// PurgeJob removes a finished job from the stats history
+func (q *Queue) PurgeJob(id uuid.UUID) error {
+	q.statsMutex.Lock()
+	job, ok := q.stats.Jobs[id.String()]
+	if !ok {
+		q.statsMutex.Unlock()
+		return ErrJobNotFound
+	}
+	if !finished(job.Status) {
+		q.statsMutex.Unlock()
+		return fmt.Errorf("job %s is %s, only finished jobs can be purged", id, job.Status)
+	}
+	q.history.remove(q.stats, id.String())
+	q.statsMutex.Unlock()
+
+	q.forget([]string{id.String()})
+	return nil
+}
+
+// jobStatus is the latest status of a job in the stats, empty if it isn't held
+func (q *Queue) jobStatus(id uuid.UUID) string {
+	q.statsMutex.RLock()
+	defer q.statsMutex.RUnlock()
+
+	if job, ok := q.stats.Jobs[id.String()]; ok {
+		return job.Status
+	}
+	return ""
+}
+
+// bury holds a job which ran out of retries so it can be retried later. The
+// dead jobs are bounded by the retention limit, the longest buried are let
+// go first.
+func (q *Queue) bury(job ReservedJob) {
+	job.ctx = nil
+	q.controlMutex.Lock()
+	defer q.controlMutex.Unlock()
+
+	q.unbury(job.ID)
+	q.dead[job.ID] = q.buried.PushBack(job)
+	for q.buried.Len() > q.history.retention.MaxJobs {
+		q.unbury(q.buried.Front().Value.(ReservedJob).ID)
+	}
+}
+
+// unbury releases a dead job, must be called holding the control lock
+func (q *Queue) unbury(id uuid.UUID) (ReservedJob, bool) {
+	el, ok := q.dead[id]
+	if !ok {
+		return ReservedJob{}, false
+	}
+	delete(q.dead, id)
+	return q.buried.Remove(el).(ReservedJob), true
+}
+
+// markQueued records jobs as queued until a worker takes them
+func (q *Queue) markQueued(ids ...uuid.UUID) {
+	q.controlMutex.Lock()
+	for _, id := range ids {
+		q.queued[id] = true
+	}
+	q.controlMutex.Unlock()
+}
+
+// takeCancelled takes a job off the queued jobs as a worker picks it up,
+// reporting whether it was cancelled while queued
+func (q *Queue) takeCancelled(id uuid.UUID) bool {
+	q.controlMutex.Lock()
+	defer q.controlMutex.Unlock()
+
+	delete(q.queued, id)
+	cancelled := q.cancelled[id]
+	delete(q.cancelled, id)
+	return cancelled
+}
+
+// startRunning holds on to the cancellation of a job while it runs
+func (q *Queue) startRunning(id uuid.UUID) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	q.controlMutex.Lock()
+	q.running[id] = cancel
+	q.controlMutex.Unlock()
+	return ctx
+}
+
+// stopRunning releases a job once it returns, reporting whether it was
+// cancelled while running
+func (q *Queue) stopRunning(id uuid.UUID) bool {
+	q.controlMutex.Lock()
+	defer q.controlMutex.Unlock()
+
+	if cancel, ok := q.running[id]; ok {
+		cancel()
+		delete(q.running, id)
+	}
+	cancelled := q.cancelled[id]
+	delete(q.cancelled, id)
+	return cancelled
+}
+
+// forget releases the jobs evicted from the stats history
+func (q *Queue) forget(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+
+	q.controlMutex.Lock()
+	for _, s := range ids {
+		if id, err := uuid.FromString(s); err == nil {
+			q.unbury(id)
+			delete(q.cancelled, id)
+		}
+	}
+	q.controlMutex.Unlock()
+}
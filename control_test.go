@@ -0,0 +1,185 @@
+package rift_test
+
+import (
+	"context"
+
+	"github.com/bmartel/rift"
+	"github.com/satori/go.uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type BlockingJob struct{}
+
+func (t BlockingJob) Tag() string {
+	return "BlockingJob"
+}
+
+func (t BlockingJob) Deserialize(data map[string]interface{}) rift.Job {
+	return BlockingJob{}
+}
+
+func (t BlockingJob) Process(service rift.Service) error {
+	return nil
+}
+
+func (t BlockingJob) ProcessContext(ctx context.Context, service rift.Service) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+var _ = Describe("Control", func() {
+	var queue *rift.Queue
+
+	status := func(id uuid.UUID) func() string {
+		return func() string {
+			if job, ok := queue.Stats().Jobs[id.String()]; ok {
+				return job.Status
+			}
+			return ""
+		}
+	}
+
+	BeforeEach(func() {
+		queue = rift.New(&rift.Options{Tag: "Test", Workers: 1, Queues: 1}, nil)
+		queue.Register(SampleJob{}, BrokenJob{}, BlockingJob{})
+	})
+
+	AfterEach(func() {
+		queue.Close()
+	})
+
+	It("should retry a job which ran out of retries", func(done Done) {
+		id := queue.Later(BrokenJob{}, 0)
+		Eventually(status(id)).Should(Equal("failed"))
+
+		Expect(queue.Retry(id)).To(Succeed())
+		Eventually(func() uint32 { return queue.FailedJobs() }).Should(Equal(uint32(2)))
+		Eventually(status(id)).Should(Equal("failed"))
+
+		processed := queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+		Eventually(status(processed)).Should(Equal("processed"))
+		Expect(queue.Retry(processed)).ToNot(Succeed())
+		Expect(queue.Retry(uuid.NewV4())).To(Equal(rift.ErrJobNotFound))
+
+		close(done)
+	}, 3)
+
+	It("should record the payload and every attempt of a job", func(done Done) {
+		id := queue.Later(BrokenJob{}, 1)
+		Eventually(func() uint32 { return queue.FailedJobs() }).Should(Equal(uint32(2)))
+
+		job := queue.Stats().Jobs[id.String()]
+		Expect(job.Status).To(Equal("failed"))
+		Expect(job.Payload).To(MatchJSON(`{}`))
+		Expect(job.Attempts).To(HaveLen(2))
+		for _, attempt := range job.Attempts {
+			Expect(attempt.Worker).To(Equal(job.Worker))
+			Expect(attempt.FinishedAt).To(BeNumerically(">=", attempt.StartedAt))
+			Expect(attempt.Duration).To(BeNumerically(">", 0))
+			Expect(attempt.Error).To(Equal("always broken"))
+		}
+
+		processed := queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+		Eventually(status(processed)).Should(Equal("processed"))
+		job = queue.Stats().Jobs[processed.String()]
+		Expect(job.Payload).To(MatchJSON(`{"id": 1, "title": "Rift", "body": "Running a Managed Goroutine"}`))
+		Expect(job.Attempts).To(HaveLen(1))
+		Expect(job.Attempts[0].Error).To(BeEmpty())
+
+		close(done)
+	}, 3)
+
+	It("should cancel queued and running jobs", func(done Done) {
+		running := queue.Later(BlockingJob{}, 0)
+		Eventually(status(running)).Should(Equal("started"))
+		queued := queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+		Eventually(status(queued)).Should(Equal("queued"))
+		Expect(queue.Backlog()).To(Equal(uint32(1)))
+
+		Expect(queue.Cancel(queued)).To(Succeed())
+		Expect(queue.Cancel(running)).To(Succeed())
+
+		Eventually(status(running)).Should(Equal("cancelled"))
+		Eventually(status(queued)).Should(Equal("cancelled"))
+		Expect(queue.CancelledJobs()).To(Equal(uint32(2)))
+		Expect(queue.ProcessedJobs()).To(Equal(uint32(0)))
+		Expect(queue.Stats().ActiveJobs).To(Equal(uint32(0)))
+		Expect(queue.Backlog()).To(Equal(uint32(0)))
+
+		Expect(queue.Cancel(running)).ToNot(Succeed())
+		Expect(queue.Cancel(uuid.NewV4())).To(Equal(rift.ErrJobNotFound))
+
+		close(done)
+	}, 3)
+
+	It("should cancel a job straight after queueing it", func(done Done) {
+		running := queue.Later(BlockingJob{}, 0)
+		Eventually(status(running)).Should(Equal("started"))
+
+		queued := queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+		Expect(queue.Cancel(queued)).To(Succeed())
+		Expect(queue.Cancel(running)).To(Succeed())
+
+		Eventually(status(queued)).Should(Equal("cancelled"))
+		Expect(queue.ProcessedJobs()).To(Equal(uint32(0)))
+
+		close(done)
+	}, 3)
+
+	It("should cancel a queued batch job", func(done Done) {
+		running := queue.Later(BlockingJob{}, 0)
+		Eventually(status(running)).Should(Equal("started"))
+
+		batch := queue.NewBatch()
+		batch.Add(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+		cancelled := batch.Add(SampleJob{2, "Rift", "Running a Managed Goroutine"}, 0)
+		batch.Dispatch()
+
+		err := queue.Cancel(cancelled)
+		Expect(queue.Cancel(running)).To(Succeed())
+		Expect(err).ToNot(HaveOccurred())
+		batch.Wait()
+
+		Eventually(status(cancelled)).Should(Equal("cancelled"))
+		Eventually(func() uint32 { return queue.ProcessedJobs() }).Should(Equal(uint32(1)))
+		Expect(batch.Succeeded()).To(Equal(uint32(1)))
+
+		close(done)
+	}, 3)
+
+	It("should hold dead jobs up to the retention limit", func(done Done) {
+		limited := rift.New(&rift.Options{Tag: "Test", Workers: 1, Queues: 1, Retention: rift.Retention{MaxJobs: 2}}, nil)
+		defer limited.Close()
+
+		ids := make([]uuid.UUID, 0)
+		for i := 1; i <= 3; i++ {
+			ids = append(ids, limited.Later(BrokenJob{}, 0))
+			Eventually(func() uint32 { return limited.FailedJobs() }).Should(Equal(uint32(i)))
+		}
+
+		Expect(limited.Retry(ids[0])).To(Equal(rift.ErrJobNotFound))
+		Expect(limited.Retry(ids[2])).To(Succeed())
+		Eventually(func() uint32 { return limited.FailedJobs() }).Should(Equal(uint32(4)))
+
+		close(done)
+	}, 3)
+
+	It("should purge finished jobs from the history", func(done Done) {
+		failed := queue.Later(BrokenJob{}, 0)
+		processed := queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+		Eventually(status(failed)).Should(Equal("failed"))
+		Eventually(status(processed)).Should(Equal("processed"))
+
+		Expect(queue.Purge("failed")).To(ConsistOf(failed.String()))
+		Expect(queue.Stats().Jobs).ToNot(HaveKey(failed.String()))
+		Expect(queue.Retry(failed)).To(Equal(rift.ErrJobNotFound))
+
+		Expect(queue.PurgeJob(processed)).To(Succeed())
+		Expect(queue.Stats().Jobs).To(BeEmpty())
+		Expect(queue.PurgeJob(processed)).To(Equal(rift.ErrJobNotFound))
+
+		close(done)
+	}, 3)
+})
@@ -0,0 +1,76 @@
+package rift
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// NewStatsTLS creates the TLS configuration for connecting to the stats
+// server. The server certificate is verified against the CA file, or the
+// system roots when it is empty. A client certificate and key are presented
+// for mutual TLS when both are given.
+func NewStatsTLS(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates could be read from %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// tokenCredentials sends the stats token as a bearer token in the metadata of
+// every call to the stats server
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+// RequireTransportSecurity holds unless sending the token over a plain
+// connection was opted into with StatsInsecureToken
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return t.secure
+}
+
+// statsDialOptions are the options every connection to the stats server is
+// dialed with. Dialing doesn't wait for the connection, grpc connects in the
+// background and reconnects with the same backoff cap as the queue.
+func statsDialOptions(opts *Options) []grpc.DialOption {
+	dial := []grpc.DialOption{grpc.WithBackoffMaxDelay(opts.MaxReconnectBackoff)}
+
+	if opts.StatsTLS != nil {
+		dial = append(dial, grpc.WithTransportCredentials(credentials.NewTLS(opts.StatsTLS)))
+	} else {
+		dial = append(dial, grpc.WithInsecure())
+	}
+	if opts.StatsToken != "" {
+		dial = append(dial, grpc.WithPerRPCCredentials(tokenCredentials{opts.StatsToken, !opts.StatsInsecureToken}))
+	}
+	return dial
+}
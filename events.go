@@ -0,0 +1,238 @@
+package rift
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+	"github.com/satori/go.uuid"
+)
+
+// subscriberBuffer is the number of events held for a subscriber before new
+// events are dropped
+const subscriberBuffer = 256
+
+// subscriberCloseTimeout is how long closing the queue waits on a subscriber
+// still running a callback before leaving it behind
+const subscriberCloseTimeout = time.Second
+
+// EventType identifies a point in the lifecycle of a job
+type EventType string
+
+// Job lifecycle events
+const (
+	EventEnqueued  EventType = "enqueued"
+	EventStarted   EventType = "started"
+	EventSucceeded EventType = "succeeded"
+	EventFailed    EventType = "failed"
+	EventRetried   EventType = "retried"
+	EventDead      EventType = "dead"
+	EventCancelled EventType = "cancelled"
+)
+
+// eventStatus maps lifecycle events onto the job status reported in the stats
+var eventStatus = map[EventType]string{
+	EventEnqueued:  "queued",
+	EventStarted:   "started",
+	EventSucceeded: "processed",
+	EventFailed:    "failed",
+	EventRetried:   "requeued",
+	EventCancelled: "cancelled",
+}
+
+// Event describes a change in the lifecycle of a job
+type Event struct {
+	Type        EventType
+	App         string
+	QueueID     string
+	JobID       uuid.UUID
+	Tag         string
+	Worker      uuid.UUID
+	Attempt     uint8
+	Err         error
+	RequestedAt time.Time
+	QueuedAt    time.Time
+	StartedAt   time.Time
+	At          time.Time
+
+	payload  string
+	attempts []*summary.Attempt
+}
+
+func newEvent(eventType EventType, job ReservedJob, err error) Event {
+	return Event{
+		Type:        eventType,
+		JobID:       job.ID,
+		Tag:         job.Job.Tag(),
+		Attempt:     job.Requeued + 1,
+		Err:         err,
+		RequestedAt: job.RequestedAt,
+		QueuedAt:    job.QueuedAt,
+		StartedAt:   job.StartedAt,
+		At:          time.Now(),
+		payload:     job.payload,
+		attempts:    job.attempts,
+	}
+}
+
+// Subscription delivers lifecycle events to a subscriber on its own goroutine
+type Subscription struct {
+	id      uuid.UUID
+	queue   *Queue
+	fn      func(Event)
+	types   map[EventType]bool
+	events  chan Event
+	removed chan bool
+	stopped chan bool
+	once    sync.Once
+	stop    sync.Once
+	dropped uint64
+}
+
+// Subscribe registers fn to receive lifecycle events of the given types, or
+// every event when no types are given. Events are delivered asynchronously,
+// and dropped if the subscriber falls too far behind.
+func (q *Queue) Subscribe(fn func(Event), types ...EventType) *Subscription {
+	s := &Subscription{
+		id:      uuid.NewV4(),
+		queue:   q,
+		fn:      fn,
+		types:   make(map[EventType]bool, len(types)),
+		events:  make(chan Event, subscriberBuffer),
+		removed: make(chan bool),
+		stopped: make(chan bool),
+	}
+	for _, t := range types {
+		s.types[t] = true
+	}
+
+	q.subscriberMutex.Lock()
+	q.subscribers[s.id] = s
+	q.subscriberMutex.Unlock()
+
+	go s.listen()
+
+	return s
+}
+
+// Unsubscribe stops event delivery, waiting for any buffered events to be handled
+func (s *Subscription) Unsubscribe() {
+	s.queue.subscriberMutex.Lock()
+	delete(s.queue.subscribers, s.id)
+	s.queue.subscriberMutex.Unlock()
+
+	s.close()
+}
+
+// Dropped is the number of events discarded because the subscriber was too slow
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.events)
+		<-s.removed
+	})
+}
+
+// shutdown stops delivery, dropping any buffered events, and waits at most
+// until the deadline for the callback in progress to return
+func (s *Subscription) shutdown(deadline <-chan bool) {
+	s.stop.Do(func() {
+		close(s.stopped)
+	})
+	s.once.Do(func() {
+		close(s.events)
+	})
+	select {
+	case <-s.removed:
+	case <-deadline:
+	}
+}
+
+func (s *Subscription) listen() {
+	for e := range s.events {
+		select {
+		case <-s.stopped:
+			atomic.AddUint64(&s.dropped, 1)
+			continue
+		default:
+		}
+		s.fn(e)
+	}
+	close(s.removed)
+}
+
+func (s *Subscription) wants(e Event) bool {
+	return len(s.types) == 0 || s.types[e.Type]
+}
+
+// track records a lifecycle event in the queue metrics and hands it to any
+// subscribers
+func (q *Queue) track(e Event) {
+	e.App = q.stats.App
+	e.QueueID = q.id
+
+	if status, ok := eventStatus[e.Type]; ok {
+		worker := q.id
+		if e.Worker != uuid.Nil {
+			worker = e.Worker.String()
+		}
+		job := &summary.Job{
+			Id:          e.JobID.String(),
+			Tag:         e.Tag,
+			Status:      status,
+			Worker:      worker,
+			UpdatedAt:   e.At.UnixNano(),
+			RequestedAt: e.RequestedAt.UnixNano(),
+			Payload:     e.payload,
+			Attempts:    e.attempts,
+		}
+		if !e.QueuedAt.IsZero() {
+			job.QueuedAt = e.QueuedAt.UnixNano()
+		}
+		if !e.StartedAt.IsZero() {
+			job.StartedAt = e.StartedAt.UnixNano()
+		}
+		q.emit(job)
+	}
+
+	q.publish(e)
+}
+
+func (q *Queue) publish(e Event) {
+	q.subscriberMutex.RLock()
+	defer q.subscriberMutex.RUnlock()
+
+	for _, s := range q.subscribers {
+		if !s.wants(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+			q.logger.log(LogEventDropped, field("event", string(e.Type)), field("job", e.JobID.String()))
+		}
+	}
+}
+
+// closeSubscribers stops every subscription once the queue has shut down.
+// Undelivered events are dropped, and a subscriber stuck in its callback is
+// left behind rather than holding up the close.
+func (q *Queue) closeSubscribers() {
+	q.subscriberMutex.Lock()
+	subscribers := q.subscribers
+	q.subscribers = make(map[uuid.UUID]*Subscription, 0)
+	q.subscriberMutex.Unlock()
+
+	deadline := make(chan bool)
+	timer := time.AfterFunc(subscriberCloseTimeout, func() { close(deadline) })
+	defer timer.Stop()
+
+	for _, s := range subscribers {
+		s.shutdown(deadline)
+	}
+}
@@ -0,0 +1,77 @@
+package rift
+
+import (
+	"time"
+
+	"github.com/bmartel/rift/metrics"
+	"github.com/bmartel/rift/summary"
+)
+
+// latency tracks the durations of every job with the same tag. Wait is the
+// time from an attempt being queued until it started, run is the time the
+// attempt took and total is the time from being requested until the attempt
+// finished.
+type latency struct {
+	wait  *metrics.Histogram
+	run   *metrics.Histogram
+	total *metrics.Histogram
+}
+
+func newLatency() *latency {
+	return &latency{
+		wait:  metrics.NewHistogram(),
+		run:   metrics.NewHistogram(),
+		total: metrics.NewHistogram(),
+	}
+}
+
+// recordLatency adds the durations carried by a job update, must be called
+// holding the stats lock
+func (q *Queue) recordLatency(job *summary.Job) {
+	if job.Tag == "" || job.RequestedAt == 0 || job.StartedAt == 0 {
+		return
+	}
+
+	l, ok := q.latencies[job.Tag]
+	if !ok {
+		l = newLatency()
+		q.latencies[job.Tag] = l
+	}
+
+	switch job.Status {
+	case "started":
+		// the wait of this attempt, a retried job was queued again after
+		// its earlier attempts ran
+		queuedAt := job.QueuedAt
+		if queuedAt == 0 {
+			queuedAt = job.RequestedAt
+		}
+		l.wait.Observe(time.Duration(job.StartedAt - queuedAt))
+	case "processed", "failed":
+		l.run.Observe(time.Duration(job.UpdatedAt - job.StartedAt))
+		l.total.Observe(time.Duration(job.UpdatedAt - job.RequestedAt))
+	}
+}
+
+// latencyPercentiles summarises the latencies per tag, must be called holding
+// the stats lock
+func (q *Queue) latencyPercentiles() map[string]*summary.Latency {
+	latencies := make(map[string]*summary.Latency, len(q.latencies))
+	for tag, l := range q.latencies {
+		latencies[tag] = &summary.Latency{
+			Wait:  percentiles(l.wait),
+			Run:   percentiles(l.run),
+			Total: percentiles(l.total),
+		}
+	}
+	return latencies
+}
+
+func percentiles(h *metrics.Histogram) *summary.Percentiles {
+	return &summary.Percentiles{
+		Count: h.Count(),
+		P50:   h.Quantile(0.50).Seconds(),
+		P95:   h.Quantile(0.95).Seconds(),
+		P99:   h.Quantile(0.99).Seconds(),
+	}
+}
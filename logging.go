@@ -0,0 +1,217 @@
+package rift
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Level is the severity a log entry is written at
+type Level int8
+
+// Log levels, LevelOff silences an event entirely
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelOff Level = 127
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	case LevelOff:
+		return "OFF"
+	}
+	return fmt.Sprintf("LEVEL(%d)", l)
+}
+
+// LogEvent identifies something the queue logs, it is also the message the
+// entry is logged with
+type LogEvent string
+
+// Events logged by the queue
+const (
+	LogQueueStarted        LogEvent = "queue started"
+	LogQueueStopped        LogEvent = "queue stopped"
+	LogWorkersStarted      LogEvent = "workers started"
+	LogWorkerStarted       LogEvent = "worker started"
+	LogWorkerStopped       LogEvent = "worker stopped"
+	LogJobQueued           LogEvent = "job queued"
+	LogJobStarted          LogEvent = "job started"
+	LogJobProcessed        LogEvent = "job processed"
+	LogJobFailed           LogEvent = "job failed"
+	LogJobRequeued         LogEvent = "job requeued"
+	LogJobTiming           LogEvent = "job timing"
+	LogBatchQueued         LogEvent = "batch queued"
+	LogBatchFinished       LogEvent = "batch finished"
+	LogEventDropped        LogEvent = "event dropped for slow subscriber"
+	LogMonitoringConnected LogEvent = "monitoring connected"
+	LogMonitoringFailed    LogEvent = "monitoring failed"
+	LogCommandsConnected   LogEvent = "commands connected"
+	LogCommandsFailed      LogEvent = "commands failed"
+	LogCommandReceived     LogEvent = "command received"
+	LogInsecureToken       LogEvent = "stats token sent without tls"
+)
+
+// DefaultLogLevels are the levels each event is logged at unless overridden
+// in the queue options
+func DefaultLogLevels() map[LogEvent]Level {
+	return map[LogEvent]Level{
+		LogQueueStarted:        LevelDebug,
+		LogQueueStopped:        LevelDebug,
+		LogWorkersStarted:      LevelInfo,
+		LogWorkerStarted:       LevelDebug,
+		LogWorkerStopped:       LevelDebug,
+		LogJobQueued:           LevelInfo,
+		LogJobStarted:          LevelInfo,
+		LogJobProcessed:        LevelInfo,
+		LogJobFailed:           LevelError,
+		LogJobRequeued:         LevelInfo,
+		LogJobTiming:           LevelInfo,
+		LogBatchQueued:         LevelInfo,
+		LogBatchFinished:       LevelInfo,
+		LogEventDropped:        LevelWarn,
+		LogMonitoringConnected: LevelInfo,
+		LogMonitoringFailed:    LevelError,
+		LogCommandsConnected:   LevelInfo,
+		LogCommandsFailed:      LevelError,
+		LogCommandReceived:     LevelInfo,
+		LogInsecureToken:       LevelWarn,
+	}
+}
+
+// Field is a key value pair added to a log entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func field(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger receives the log entries of a queue
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// NewZapLogger adapts a zap logger
+func NewZapLogger(logger *zap.Logger) Logger {
+	return zapLogger{logger}
+}
+
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+var zapLevels = map[Level]zapcore.Level{
+	LevelDebug: zapcore.DebugLevel,
+	LevelInfo:  zapcore.InfoLevel,
+	LevelWarn:  zapcore.WarnLevel,
+	LevelError: zapcore.ErrorLevel,
+}
+
+func (l zapLogger) Log(level Level, msg string, fields ...Field) {
+	if entry := l.logger.Check(zapLevels[level], msg); entry != nil {
+		zapFields := make([]zapcore.Field, 0, len(fields))
+		for _, f := range fields {
+			zapFields = append(zapFields, zap.Any(f.Key, f.Value))
+		}
+		entry.Write(zapFields...)
+	}
+}
+
+// NewStdLogger adapts a standard library logger, writing entries at or above
+// the given level as text lines
+func NewStdLogger(logger *log.Logger, level Level) Logger {
+	return stdLogger{logger, level}
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	level  Level
+}
+
+func (l stdLogger) Log(level Level, msg string, fields ...Field) {
+	if level < l.level {
+		return
+	}
+	var line bytes.Buffer
+	line.WriteString(level.String())
+	line.WriteString(" ")
+	line.WriteString(msg)
+	for _, f := range fields {
+		fmt.Fprintf(&line, " %s=%v", f.Key, f.Value)
+	}
+	l.logger.Print(line.String())
+}
+
+// NopLogger discards every entry
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(level Level, msg string, fields ...Field) {}
+
+// defaultLogger is the production zap logger, falling back to the standard
+// library logger if it cannot be built
+func defaultLogger() Logger {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		return NewStdLogger(log.New(os.Stderr, "", log.LstdFlags), LevelInfo)
+	}
+	return NewZapLogger(logger)
+}
+
+// eventLogger writes the queue's log events at their configured levels
+type eventLogger struct {
+	logger Logger
+	levels map[LogEvent]Level
+	fields []Field
+}
+
+func newEventLogger(logger Logger, levels map[LogEvent]Level) *eventLogger {
+	return &eventLogger{
+		logger: logger,
+		levels: levels,
+		fields: make([]Field, 0),
+	}
+}
+
+// with returns a logger adding the fields to every entry
+func (l *eventLogger) with(fields ...Field) *eventLogger {
+	return &eventLogger{
+		logger: l.logger,
+		levels: l.levels,
+		fields: append(append(make([]Field, 0, len(l.fields)+len(fields)), l.fields...), fields...),
+	}
+}
+
+func (l *eventLogger) log(event LogEvent, fields ...Field) {
+	level, ok := l.levels[event]
+	if !ok {
+		level = LevelInfo
+	}
+	if level == LevelOff {
+		return
+	}
+	if len(l.fields) > 0 {
+		fields = append(append(make([]Field, 0, len(l.fields)+len(fields)), l.fields...), fields...)
+	}
+	l.logger.Log(level, string(event), fields...)
+}
@@ -0,0 +1,33 @@
+//go:build go1.21
+// +build go1.21
+
+package rift
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger adapts a structured logger from log/slog
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+var slogLevels = map[Level]slog.Level{
+	LevelDebug: slog.LevelDebug,
+	LevelInfo:  slog.LevelInfo,
+	LevelWarn:  slog.LevelWarn,
+	LevelError: slog.LevelError,
+}
+
+func (l slogLogger) Log(level Level, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	l.logger.LogAttrs(context.Background(), slogLevels[level], msg, attrs...)
+}
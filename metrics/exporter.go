@@ -0,0 +1,289 @@
+// Package metrics exposes rift job metrics in the Prometheus text exposition
+// format, derived from the same job and stats updates sent to the stats server.
+//
+// The text format is written directly rather than through client_golang. The
+// exporter only needs counters, gauges and fixed bucket histograms keyed by a
+// couple of labels, which the format covers in a few lines, while the client
+// library would pull its protobuf, procfs and common packages into every
+// application embedding a queue. The output is checked against the format in
+// the tests.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds, matching the
+// Prometheus client defaults
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// counted job statuses and the counter they increment
+var counterNames = map[string]string{
+	"queued":    "rift_jobs_queued_total",
+	"processed": "rift_jobs_processed_total",
+	"failed":    "rift_jobs_failed_total",
+	"requeued":  "rift_jobs_requeued_total",
+	"cancelled": "rift_jobs_cancelled_total",
+}
+
+var help = map[string]string{
+	"rift_jobs_queued_total":      "Number of jobs queued.",
+	"rift_jobs_processed_total":   "Number of jobs processed without error.",
+	"rift_jobs_failed_total":      "Number of failed job attempts.",
+	"rift_jobs_requeued_total":    "Number of failed job attempts queued again.",
+	"rift_jobs_cancelled_total":   "Number of jobs cancelled before finishing.",
+	"rift_active_jobs":            "Number of jobs currently being processed.",
+	"rift_idle_workers":           "Number of workers waiting for a job.",
+	"rift_job_wait_seconds":       "Time a job attempt waited in the queue before starting.",
+	"rift_job_processing_seconds": "Time a job attempt took to process.",
+}
+
+// tagKey identifies a series per app and job tag
+type tagKey struct {
+	app string
+	tag string
+}
+
+// queueKey identifies a series per app and queue instance
+type queueKey struct {
+	app     string
+	queueID string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	for i, upper := range buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Exporter accumulates job and stats updates and serves them to Prometheus
+type Exporter struct {
+	mutex      sync.Mutex
+	buckets    []float64
+	counters   map[string]map[tagKey]uint64
+	active     map[queueKey]uint32
+	idle       map[queueKey]uint32
+	wait       map[tagKey]*histogram
+	processing map[tagKey]*histogram
+}
+
+// NewExporter creates an exporter using the default histogram buckets
+func NewExporter() *Exporter {
+	return NewExporterWithBuckets(DefaultBuckets)
+}
+
+// NewExporterWithBuckets creates an exporter with custom histogram upper bounds in seconds
+func NewExporterWithBuckets(buckets []float64) *Exporter {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+
+	e := &Exporter{
+		buckets:    sorted,
+		counters:   make(map[string]map[tagKey]uint64, len(counterNames)),
+		active:     make(map[queueKey]uint32, 0),
+		idle:       make(map[queueKey]uint32, 0),
+		wait:       make(map[tagKey]*histogram, 0),
+		processing: make(map[tagKey]*histogram, 0),
+	}
+	for _, name := range counterNames {
+		e.counters[name] = make(map[tagKey]uint64, 0)
+	}
+	return e
+}
+
+// ObserveJob records a job status update for the given app
+func (e *Exporter) ObserveJob(app string, job *summary.Job) {
+	if job == nil {
+		return
+	}
+	key := tagKey{app, job.Tag}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if name, ok := counterNames[job.Status]; ok {
+		e.counters[name][key]++
+	}
+
+	switch job.Status {
+	case "started":
+		// the wait of this attempt, a retried job was queued again after
+		// it was first requested
+		queuedAt := job.QueuedAt
+		if queuedAt == 0 {
+			queuedAt = job.RequestedAt
+		}
+		if queuedAt > 0 && job.StartedAt > 0 {
+			e.observe(e.wait, key, seconds(job.StartedAt-queuedAt))
+		}
+	case "processed", "failed":
+		if job.StartedAt > 0 && job.UpdatedAt > 0 {
+			e.observe(e.processing, key, seconds(job.UpdatedAt-job.StartedAt))
+		}
+	}
+}
+
+// ObserveStats records the gauges reported by a queue instance
+func (e *Exporter) ObserveStats(stats *summary.Stats) {
+	if stats == nil {
+		return
+	}
+	key := queueKey{stats.App, stats.QueueId}
+
+	e.mutex.Lock()
+	e.active[key] = stats.ActiveJobs
+	e.idle[key] = stats.IdleWorkers
+	e.mutex.Unlock()
+}
+
+func (e *Exporter) observe(histograms map[tagKey]*histogram, key tagKey, v float64) {
+	h, ok := histograms[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(e.buckets))}
+		histograms[key] = h
+	}
+	h.observe(e.buckets, v)
+}
+
+// ServeHTTP writes every metric in the Prometheus text format
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+
+	e.mutex.Lock()
+	names := make([]string, 0, len(e.counters))
+	for name := range e.counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeHeader(&buf, name, "counter")
+		for _, key := range sortedTagKeys(e.counters[name]) {
+			fmt.Fprintf(&buf, "%s{%s} %d\n", name, labels("app", key.app, "tag", key.tag), e.counters[name][key])
+		}
+	}
+
+	writeGauges(&buf, "rift_active_jobs", e.active)
+	writeGauges(&buf, "rift_idle_workers", e.idle)
+
+	e.writeHistograms(&buf, "rift_job_wait_seconds", e.wait)
+	e.writeHistograms(&buf, "rift_job_processing_seconds", e.processing)
+	e.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func (e *Exporter) writeHistograms(buf *bytes.Buffer, name string, histograms map[tagKey]*histogram) {
+	writeHeader(buf, name, "histogram")
+
+	keys := make([]tagKey, 0, len(histograms))
+	for key := range histograms {
+		keys = append(keys, key)
+	}
+	sortTagKeys(keys)
+
+	for _, key := range keys {
+		h := histograms[key]
+		for i, upper := range e.buckets {
+			fmt.Fprintf(buf, "%s_bucket{%s} %d\n", name, labels("app", key.app, "tag", key.tag, "le", formatFloat(upper)), h.counts[i])
+		}
+		fmt.Fprintf(buf, "%s_bucket{%s} %d\n", name, labels("app", key.app, "tag", key.tag, "le", "+Inf"), h.count)
+		fmt.Fprintf(buf, "%s_sum{%s} %s\n", name, labels("app", key.app, "tag", key.tag), formatFloat(h.sum))
+		fmt.Fprintf(buf, "%s_count{%s} %d\n", name, labels("app", key.app, "tag", key.tag), h.count)
+	}
+}
+
+func writeGauges(buf *bytes.Buffer, name string, gauges map[queueKey]uint32) {
+	writeHeader(buf, name, "gauge")
+
+	keys := make([]queueKey, 0, len(gauges))
+	for key := range gauges {
+		keys = append(keys, key)
+	}
+	sort.Sort(byQueue(keys))
+
+	for _, key := range keys {
+		fmt.Fprintf(buf, "%s{%s} %d\n", name, labels("app", key.app, "queue_id", key.queueID), gauges[key])
+	}
+}
+
+func writeHeader(buf *bytes.Buffer, name, kind string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help[name])
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+}
+
+// labels formats label name and value pairs
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", pairs[i], escape(pairs[i+1])))
+	}
+	return strings.Join(parts, ",")
+}
+
+var escaper = strings.NewReplacer("\\", `\\`, "\n", `\n`, "\"", `\"`)
+
+func escape(v string) string {
+	return escaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
+
+func seconds(nanos int64) float64 {
+	return time.Duration(nanos).Seconds()
+}
+
+func sortedTagKeys(m map[tagKey]uint64) []tagKey {
+	keys := make([]tagKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sortTagKeys(keys)
+	return keys
+}
+
+func sortTagKeys(keys []tagKey) {
+	sort.Sort(byTag(keys))
+}
+
+type byTag []tagKey
+
+func (k byTag) Len() int      { return len(k) }
+func (k byTag) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k byTag) Less(i, j int) bool {
+	if k[i].app != k[j].app {
+		return k[i].app < k[j].app
+	}
+	return k[i].tag < k[j].tag
+}
+
+type byQueue []queueKey
+
+func (k byQueue) Len() int      { return len(k) }
+func (k byQueue) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k byQueue) Less(i, j int) bool {
+	if k[i].app != k[j].app {
+		return k[i].app < k[j].app
+	}
+	return k[i].queueID < k[j].queueID
+}
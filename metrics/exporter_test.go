@@ -0,0 +1,151 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/bmartel/rift/metrics"
+	"github.com/bmartel/rift/summary"
+)
+
+// exposition format 0.0.4 line grammar
+var (
+	commentLine = regexp.MustCompile(`^# (HELP|TYPE) ([a-zA-Z_:][a-zA-Z0-9_:]*) (.*)$`)
+	sampleLine  = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\[\\"n])*"(?:,[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\[\\"n])*")*\})? (\S+)$`)
+	metricTypes = map[string]bool{"counter": true, "gauge": true, "histogram": true, "summary": true, "untyped": true}
+)
+
+func scrape(e *metrics.Exporter) (string, string) {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	return rec.Header().Get("Content-Type"), rec.Body.String()
+}
+
+// family is the metric a sample belongs to, histogram samples are suffixed
+func family(name string, types map[string]string) string {
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		base := strings.TrimSuffix(name, suffix)
+		if base != name && types[base] == "histogram" {
+			return base
+		}
+	}
+	return name
+}
+
+func at(d time.Duration) int64 {
+	return time.Unix(1500000000, 0).Add(d).UnixNano()
+}
+
+var _ = Describe("Exporter", func() {
+	var exporter *metrics.Exporter
+
+	BeforeEach(func() {
+		exporter = metrics.NewExporterWithBuckets([]float64{1, 0.1})
+	})
+
+	observe := func(tag string) {
+		exporter.ObserveJob("App", &summary.Job{Tag: tag, Status: "queued", RequestedAt: at(0)})
+		exporter.ObserveJob("App", &summary.Job{Tag: tag, Status: "started", RequestedAt: at(0), QueuedAt: at(0), StartedAt: at(time.Millisecond * 500)})
+		exporter.ObserveJob("App", &summary.Job{Tag: tag, Status: "processed", RequestedAt: at(0), QueuedAt: at(0), StartedAt: at(time.Millisecond * 500), UpdatedAt: at(time.Millisecond * 550)})
+		exporter.ObserveStats(&summary.Stats{App: "App", QueueId: "q1", ActiveJobs: 2, IdleWorkers: 3})
+	}
+
+	It("should write the metrics in the text exposition format", func() {
+		observe("Email")
+
+		contentType, body := scrape(exporter)
+		Expect(contentType).To(Equal("text/plain; version=0.0.4; charset=utf-8"))
+		Expect(body).To(Equal(`# HELP rift_jobs_cancelled_total Number of jobs cancelled before finishing.
+# TYPE rift_jobs_cancelled_total counter
+# HELP rift_jobs_failed_total Number of failed job attempts.
+# TYPE rift_jobs_failed_total counter
+# HELP rift_jobs_processed_total Number of jobs processed without error.
+# TYPE rift_jobs_processed_total counter
+rift_jobs_processed_total{app="App",tag="Email"} 1
+# HELP rift_jobs_queued_total Number of jobs queued.
+# TYPE rift_jobs_queued_total counter
+rift_jobs_queued_total{app="App",tag="Email"} 1
+# HELP rift_jobs_requeued_total Number of failed job attempts queued again.
+# TYPE rift_jobs_requeued_total counter
+# HELP rift_active_jobs Number of jobs currently being processed.
+# TYPE rift_active_jobs gauge
+rift_active_jobs{app="App",queue_id="q1"} 2
+# HELP rift_idle_workers Number of workers waiting for a job.
+# TYPE rift_idle_workers gauge
+rift_idle_workers{app="App",queue_id="q1"} 3
+# HELP rift_job_wait_seconds Time a job attempt waited in the queue before starting.
+# TYPE rift_job_wait_seconds histogram
+rift_job_wait_seconds_bucket{app="App",tag="Email",le="0.1"} 0
+rift_job_wait_seconds_bucket{app="App",tag="Email",le="1"} 1
+rift_job_wait_seconds_bucket{app="App",tag="Email",le="+Inf"} 1
+rift_job_wait_seconds_sum{app="App",tag="Email"} 0.5
+rift_job_wait_seconds_count{app="App",tag="Email"} 1
+# HELP rift_job_processing_seconds Time a job attempt took to process.
+# TYPE rift_job_processing_seconds histogram
+rift_job_processing_seconds_bucket{app="App",tag="Email",le="0.1"} 1
+rift_job_processing_seconds_bucket{app="App",tag="Email",le="1"} 1
+rift_job_processing_seconds_bucket{app="App",tag="Email",le="+Inf"} 1
+rift_job_processing_seconds_sum{app="App",tag="Email"} 0.05
+rift_job_processing_seconds_count{app="App",tag="Email"} 1
+`))
+	})
+
+	It("should only write lines allowed by the exposition format", func() {
+		observe("Email")
+		observe("Say \"hi\"\\\nthere")
+
+		_, body := scrape(exporter)
+		Expect(body).To(HaveSuffix("\n"))
+
+		types := make(map[string]string, 0)
+		seen := make(map[string]bool, 0)
+		for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+			if m := commentLine.FindStringSubmatch(line); m != nil {
+				if m[1] == "TYPE" {
+					Expect(metricTypes).To(HaveKey(m[3]), line)
+					Expect(types).ToNot(HaveKey(m[2]), "a metric is declared once")
+					types[m[2]] = m[3]
+				}
+				continue
+			}
+
+			m := sampleLine.FindStringSubmatch(line)
+			Expect(m).ToNot(BeNil(), "malformed line %q", line)
+			Expect(types).To(HaveKey(family(m[1], types)), "sample before its type %q", line)
+			Expect(seen).ToNot(HaveKey(m[1]+m[2]), "duplicate series %q", line)
+			seen[m[1]+m[2]] = true
+
+			_, err := strconv.ParseFloat(m[3], 64)
+			Expect(err).ToNot(HaveOccurred(), line)
+		}
+
+		Expect(body).To(ContainSubstring(`rift_jobs_queued_total{app="App",tag="Say \"hi\"\\\nthere"} 1`))
+	})
+
+	It("should write cumulative histogram buckets ending with the total count", func() {
+		for _, wait := range []time.Duration{time.Millisecond * 50, time.Millisecond * 500, time.Second * 5} {
+			exporter.ObserveJob("App", &summary.Job{Tag: "Email", Status: "started", QueuedAt: at(0), StartedAt: at(wait)})
+		}
+
+		_, body := scrape(exporter)
+		Expect(body).To(ContainSubstring(`rift_job_wait_seconds_bucket{app="App",tag="Email",le="0.1"} 1` + "\n" +
+			`rift_job_wait_seconds_bucket{app="App",tag="Email",le="1"} 2` + "\n" +
+			`rift_job_wait_seconds_bucket{app="App",tag="Email",le="+Inf"} 3` + "\n" +
+			`rift_job_wait_seconds_sum{app="App",tag="Email"} 5.55` + "\n" +
+			`rift_job_wait_seconds_count{app="App",tag="Email"} 3` + "\n"))
+	})
+
+	It("should measure the wait of a retried job from when it was queued again", func() {
+		exporter.ObserveJob("App", &summary.Job{Tag: "Email", Status: "started", RequestedAt: at(0), QueuedAt: at(time.Second * 10), StartedAt: at(time.Second*10 + time.Millisecond*50)})
+
+		_, body := scrape(exporter)
+		Expect(body).To(ContainSubstring(`rift_job_wait_seconds_bucket{app="App",tag="Email",le="0.1"} 1`))
+		Expect(body).To(ContainSubstring(`rift_job_wait_seconds_sum{app="App",tag="Email"} 0.05`))
+	})
+})
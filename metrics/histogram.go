@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// histogramMin is the smallest duration told apart by a histogram
+	histogramMin = float64(time.Microsecond)
+	// histogramGrowth is the ratio between bucket bounds, bounding the
+	// relative error of a quantile to a few percent
+	histogramGrowth = 1.05
+)
+
+var logGrowth = math.Log(histogramGrowth)
+
+// Histogram is a streaming log-linear histogram of durations, estimating
+// quantiles in constant memory regardless of how many values are observed.
+// It is not safe for concurrent use.
+type Histogram struct {
+	counts []uint64
+	count  uint64
+}
+
+// NewHistogram creates an empty histogram
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]uint64, 0)}
+}
+
+// Observe records a duration
+func (h *Histogram) Observe(d time.Duration) {
+	i := bucket(float64(d))
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, i-len(h.counts)+1)...)
+	}
+	h.counts[i]++
+	h.count++
+}
+
+// Count is the number of observed durations
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// Quantile estimates the duration below which the fraction q of the observed
+// durations fall
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return time.Duration(midpoint(i))
+		}
+	}
+	return time.Duration(midpoint(len(h.counts) - 1))
+}
+
+func bucket(v float64) int {
+	if v <= histogramMin {
+		return 0
+	}
+	return int(math.Ceil(math.Log(v/histogramMin) / logGrowth))
+}
+
+// midpoint is the value halfway between a bucket's bounds
+func midpoint(i int) float64 {
+	if i == 0 {
+		return histogramMin
+	}
+	upper := histogramMin * math.Pow(histogramGrowth, float64(i))
+	return (upper + upper/histogramGrowth) / 2
+}
@@ -0,0 +1,94 @@
+package rift
+
+import (
+	"time"
+)
+
+// Handler executes a reserved job on a worker with the given service
+type Handler func(w *Worker, job ReservedJob, service Service) error
+
+// Middleware wraps a Handler to add behaviour around job processing
+type Middleware func(next Handler) Handler
+
+// DefaultMiddleware is installed when no middleware is given in the queue options
+func DefaultMiddleware() []Middleware {
+	return []Middleware{Logging(), Timing()}
+}
+
+// process is the innermost handler, running the job itself
+func process(w *Worker, job ReservedJob, service Service) error {
+	if j, ok := job.Job.(ContextJob); ok {
+		return j.ProcessContext(job.Context(), service)
+	}
+	return job.Job.Process(service)
+}
+
+// chain wraps the handler in the middleware so the first middleware is the
+// outermost one
+func chain(handler Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// Use installs middleware around every job processed by the queue
+func (q *Queue) Use(middleware ...Middleware) {
+	q.middlewareMutex.Lock()
+	q.middleware = append(q.middleware, middleware...)
+	q.middlewareMutex.Unlock()
+}
+
+// UseTag installs middleware around jobs with the given tag only. Tag
+// middleware runs inside of the queue wide middleware.
+func (q *Queue) UseTag(tag string, middleware ...Middleware) {
+	q.middlewareMutex.Lock()
+	q.tagMiddleware[tag] = append(q.tagMiddleware[tag], middleware...)
+	q.middlewareMutex.Unlock()
+}
+
+// handler builds the middleware chain for a job tag
+func (q *Queue) handler(tag string) Handler {
+	q.middlewareMutex.RLock()
+	middleware := make([]Middleware, 0, len(q.middleware)+len(q.tagMiddleware[tag]))
+	middleware = append(middleware, q.middleware...)
+	middleware = append(middleware, q.tagMiddleware[tag]...)
+	q.middlewareMutex.RUnlock()
+
+	return chain(process, middleware...)
+}
+
+// Logging logs the start and the outcome of every job
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(w *Worker, job ReservedJob, service Service) error {
+			w.logger.log(LogJobStarted, field("job", job.ID.String()))
+
+			err := next(w, job, service)
+			if err != nil {
+				w.logger.log(LogJobFailed, field("job", job.ID.String()), field("error", err.Error()))
+			} else {
+				w.logger.log(LogJobProcessed, field("job", job.ID.String()))
+			}
+			return err
+		}
+	}
+}
+
+// Timing logs how long the attempt waited in the queue, how long it ran for
+// and the total time from being requested until it finished
+func Timing() Middleware {
+	return func(next Handler) Handler {
+		return func(w *Worker, job ReservedJob, service Service) error {
+			start := time.Now()
+			err := next(w, job, service)
+			w.logger.log(LogJobTiming,
+				field("job", job.ID.String()),
+				field("wait", start.Sub(job.queuedAt()).Seconds()),
+				field("run", time.Since(start).Seconds()),
+				field("duration", time.Since(job.RequestedAt).Seconds()),
+			)
+			return err
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package rift
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultMonitoringBuffer    = 10000
+	defaultReconnectBackoff    = 500 * time.Millisecond
+	defaultMaxReconnectBackoff = 30 * time.Second
+	defaultHeartbeatInterval   = 10 * time.Second
+)
+
+// Connection states of the stats server stream
+const (
+	MonitoringDisconnected = "disconnected"
+	MonitoringConnecting   = "connecting"
+	MonitoringConnected    = "connected"
+)
+
+// monitor streams reports to the stats server, holding on to them while the
+// server can't be reached. Only the reporter uses it apart from status.
+//
+// Delivery is at most once: a report is let go as soon as it is written to
+// the stream, so reports in flight when the stream breaks are lost rather
+// than sent twice, which would count their jobs twice on the server.
+type monitor struct {
+	addr       string
+	dial       []grpc.DialOption
+	limit      int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	logger     *eventLogger
+	// heartbeat describes the queue instance, it is sent whenever a stream
+	// connects and on the heartbeat interval while connected
+	heartbeat func() *summary.Heartbeat
+
+	// cancels a stream being opened when the queue closes
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	conn      *grpc.ClientConn
+	stream    summary.Summary_ReportClient
+	connected bool
+	buffer    []report
+	buffered  int
+	attempts  int
+	retry     *time.Timer
+
+	mutex sync.Mutex
+	state *summary.Monitoring
+}
+
+func newMonitor(opts *Options, logger *eventLogger, heartbeat func() *summary.Heartbeat) *monitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &monitor{
+		addr:       opts.StatsAddr,
+		dial:       statsDialOptions(opts),
+		limit:      opts.MonitoringBuffer,
+		backoff:    opts.ReconnectBackoff,
+		maxBackoff: opts.MaxReconnectBackoff,
+		logger:     logger,
+		heartbeat:  heartbeat,
+		ctx:        ctx,
+		cancel:     cancel,
+		buffer:     make([]report, 0),
+		state:      &summary.Monitoring{State: MonitoringDisconnected},
+	}
+}
+
+// status is a copy of the connection state
+func (m *monitor) status() *summary.Monitoring {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return proto.Clone(m.state).(*summary.Monitoring)
+}
+
+func (m *monitor) update(fn func(state *summary.Monitoring)) {
+	m.mutex.Lock()
+	fn(m.state)
+	m.state.BufferedJobs = uint32(m.buffered)
+	m.mutex.Unlock()
+}
+
+// add buffers a report, discarding the oldest reports once more job updates
+// are held than the buffer limit
+func (m *monitor) add(r report) {
+	m.buffer = append(m.buffer, r)
+	m.buffered += len(r.jobs)
+
+	var dropped int
+	for m.buffered > m.limit && len(m.buffer) > 1 {
+		dropped += len(m.buffer[0].jobs)
+		m.buffered -= len(m.buffer[0].jobs)
+		m.buffer = m.buffer[1:]
+	}
+
+	m.update(func(state *summary.Monitoring) {
+		state.DroppedJobs += uint64(dropped)
+	})
+}
+
+// retrying fires when the next connection attempt is due, it is nil while no
+// attempt is scheduled
+func (m *monitor) retrying() <-chan time.Time {
+	if m.retry == nil {
+		return nil
+	}
+	return m.retry.C
+}
+
+// send streams every buffered report, connecting first if needed. Failures
+// leave the unsent reports buffered for the next attempt.
+func (m *monitor) send() {
+	if m.stream == nil {
+		if m.retry != nil {
+			return
+		}
+		if !m.connect() {
+			return
+		}
+		if !m.beat() {
+			return
+		}
+	}
+
+	for len(m.buffer) > 0 {
+		r := m.buffer[0]
+		if err := m.stream.Send(&summary.StatsReport{Jobs: r.jobs, Stats: r.stats}); err != nil {
+			m.disconnect(err)
+			return
+		}
+		m.buffered -= len(r.jobs)
+		m.buffer = m.buffer[1:]
+	}
+	m.update(func(state *summary.Monitoring) {})
+}
+
+// beat sends a heartbeat over an open stream, heartbeats are never buffered
+// as they are stale by the time the stream reconnects
+func (m *monitor) beat() bool {
+	if m.stream == nil {
+		return false
+	}
+	if err := m.stream.Send(&summary.StatsReport{Heartbeat: m.heartbeat()}); err != nil {
+		m.disconnect(err)
+		return false
+	}
+	return true
+}
+
+func (m *monitor) connect() bool {
+	m.update(func(state *summary.Monitoring) {
+		state.State = MonitoringConnecting
+	})
+
+	// the connection is dialed once and reconnected by grpc in the
+	// background, only the stream is opened again after a failure
+	if m.conn == nil {
+		conn, err := grpc.DialContext(m.ctx, m.addr, m.dial...)
+		if err != nil {
+			m.failed(err)
+			return false
+		}
+		m.conn = conn
+	}
+
+	stream, err := summary.NewSummaryClient(m.conn).Report(m.ctx)
+	if err != nil {
+		m.failed(err)
+		return false
+	}
+
+	m.stream = stream
+	m.attempts = 0
+	reconnected := m.connected
+	m.connected = true
+	m.update(func(state *summary.Monitoring) {
+		state.State = MonitoringConnected
+		state.ConnectedAt = time.Now().UnixNano()
+		if reconnected {
+			state.Reconnects++
+		}
+	})
+	m.logger.log(LogMonitoringConnected, field("addr", m.addr))
+	return true
+}
+
+// disconnect tears down a broken stream and schedules a new one
+func (m *monitor) disconnect(err error) {
+	if _, closeErr := m.stream.CloseAndRecv(); closeErr != nil {
+		err = closeErr
+	}
+	m.stream = nil
+	m.failed(err)
+}
+
+// failed records a connection failure and schedules the next attempt with an
+// exponential backoff
+func (m *monitor) failed(err error) {
+	m.attempts++
+
+	delay := backoffDelay(m.backoff, m.maxBackoff, m.attempts)
+	m.retry = time.NewTimer(delay)
+
+	m.update(func(state *summary.Monitoring) {
+		state.State = MonitoringDisconnected
+		state.LastError = err.Error()
+	})
+	m.logger.log(LogMonitoringFailed, field("addr", m.addr), field("error", err.Error()), field("retry", delay.Seconds()))
+}
+
+// backoffDelay is the delay before a reconnect, doubling with every failed
+// attempt up to max
+func backoffDelay(base, max time.Duration, attempts int) time.Duration {
+	delay := base << uint(attempts-1)
+	if delay > max || delay <= 0 {
+		delay = max
+	}
+	// spread reconnects of many queues after a stats server restart
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// close sends what it can over an open stream and releases the connection
+func (m *monitor) close() {
+	if m.retry != nil {
+		m.retry.Stop()
+	}
+	if m.stream != nil {
+		m.send()
+	}
+	if m.stream != nil {
+		m.stream.CloseAndRecv()
+		m.stream = nil
+	}
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+	m.update(func(state *summary.Monitoring) {
+		state.State = MonitoringDisconnected
+	})
+}
+
+// startReporter sends batches of job updates to the monitoring server, away
+// from the workers and the metrics capture
+func (q *Queue) startReporter() {
+	m := q.monitor
+	if m == nil {
+		for range q.reports {
+		}
+		close(q.reporterRemoved)
+		return
+	}
+
+	heartbeats := time.NewTicker(q.heartbeatInterval)
+	defer heartbeats.Stop()
+
+	// connect up front so the connection state is known before any jobs run
+	m.send()
+	for {
+		select {
+		case <-heartbeats.C:
+			m.beat()
+			continue
+		case r, ok := <-q.reports:
+			if !ok {
+				m.close()
+				close(q.reporterRemoved)
+				return
+			}
+			m.add(r)
+		case <-m.retrying():
+			m.retry = nil
+		}
+		m.send()
+	}
+}
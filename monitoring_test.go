@@ -0,0 +1,299 @@
+package rift_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bmartel/rift"
+	"github.com/bmartel/rift/summary"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// reportServer records the latest status of every job reported to it along
+// with the latest heartbeat and the credentials it was reported with, and relays commands to the queues subscribed to them
+type reportServer struct {
+	mutex     sync.Mutex
+	jobs      map[string]string
+	heartbeat *summary.Heartbeat
+	auth      string
+
+	commands chan *summary.Command
+	replies  chan *summary.CommandReply
+}
+
+func (s *reportServer) Report(stream summary.Summary_ReportServer) error {
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok && len(md["authorization"]) > 0 {
+		s.mutex.Lock()
+		s.auth = md["authorization"][0]
+		s.mutex.Unlock()
+	}
+
+	var reports uint64
+	for {
+		report, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&summary.ReportAck{Reports: reports})
+		}
+		if err != nil {
+			return err
+		}
+		reports++
+
+		s.mutex.Lock()
+		for _, job := range report.Jobs {
+			s.jobs[job.Id] = job.Status
+		}
+		if report.Heartbeat != nil {
+			s.heartbeat = report.Heartbeat
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func (s *reportServer) Enqueue(ctx context.Context, req *summary.EnqueueRequest) (*summary.EnqueueReply, error) {
+	return nil, status.Error(codes.Unimplemented, "enqueue through commands")
+}
+
+func (s *reportServer) Commands(stream summary.Summary_CommandsServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case cmd := <-s.commands:
+			if err := stream.Send(cmd); err != nil {
+				return err
+			}
+			reply, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			s.replies <- reply
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (s *reportServer) status(id string) func() string {
+	return func() string {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		return s.jobs[id]
+	}
+}
+
+func (s *reportServer) latestHeartbeat() *summary.Heartbeat {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.heartbeat
+}
+
+func (s *reportServer) authorization() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.auth
+}
+
+func serveReports(addr string) (*grpc.Server, *reportServer, string) {
+	lis, err := net.Listen("tcp", addr)
+	Expect(err).ToNot(HaveOccurred())
+
+	srv := &reportServer{
+		jobs:     make(map[string]string, 0),
+		commands: make(chan *summary.Command),
+		replies:  make(chan *summary.CommandReply, 1),
+	}
+	server := grpc.NewServer()
+	summary.RegisterSummaryServer(server, srv)
+	go server.Serve(lis)
+
+	return server, srv, lis.Addr().String()
+}
+
+var _ = Describe("Monitoring", func() {
+	monitoringState := func(queue *rift.Queue) func() string {
+		return func() string {
+			return queue.Stats().Monitoring.State
+		}
+	}
+
+	It("should stream job updates and resend those buffered while disconnected", func(done Done) {
+		server, srv, addr := serveReports("127.0.0.1:0")
+
+		queue := rift.New(&rift.Options{
+			Tag:                 "Test",
+			Workers:             2,
+			Queues:              2,
+			StatsAddr:           addr,
+			ReportInterval:      time.Millisecond * 10,
+			ReconnectBackoff:    time.Millisecond * 10,
+			MaxReconnectBackoff: time.Millisecond * 50,
+		}, nil)
+		defer queue.Close()
+
+		Eventually(monitoringState(queue)).Should(Equal(rift.MonitoringConnected))
+
+		id := queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+		Eventually(srv.status(id.String())).Should(Equal("processed"))
+
+		server.Stop()
+		time.Sleep(time.Millisecond * 50)
+
+		id = queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+		Eventually(monitoringState(queue)).Should(Equal(rift.MonitoringDisconnected))
+		Eventually(func() uint32 { return queue.Stats().Monitoring.BufferedJobs }).Should(BeNumerically(">", 0))
+		Expect(queue.Stats().Monitoring.LastError).ToNot(BeEmpty())
+
+		server, srv, _ = serveReports(addr)
+		defer server.Stop()
+
+		Eventually(srv.status(id.String())).Should(Equal("processed"))
+
+		stats := queue.Stats()
+		Expect(stats.Monitoring.State).To(Equal(rift.MonitoringConnected))
+		Expect(stats.Monitoring.Reconnects).To(Equal(uint32(1)))
+		Expect(stats.Monitoring.BufferedJobs).To(Equal(uint32(0)))
+
+		close(done)
+	}, 5)
+
+	It("should connect once a stats server which was down comes up", func(done Done) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		addr := lis.Addr().String()
+		lis.Close()
+
+		queue := rift.New(&rift.Options{
+			Tag:                 "Test",
+			Workers:             2,
+			Queues:              2,
+			StatsAddr:           addr,
+			ReportInterval:      time.Millisecond * 10,
+			ReconnectBackoff:    time.Millisecond * 10,
+			MaxReconnectBackoff: time.Millisecond * 50,
+		}, nil)
+		defer queue.Close()
+
+		id := queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+		Eventually(monitoringState(queue)).Should(Equal(rift.MonitoringDisconnected))
+
+		server, srv, _ := serveReports(addr)
+		defer server.Stop()
+
+		Eventually(srv.status(id.String())).Should(Equal("processed"))
+		Expect(queue.Stats().Monitoring.State).To(Equal(rift.MonitoringConnected))
+
+		close(done)
+	}, 5)
+
+	It("should send heartbeats while connected", func(done Done) {
+		server, srv, addr := serveReports("127.0.0.1:0")
+		defer server.Stop()
+
+		queue := rift.New(&rift.Options{
+			Tag:               "Test",
+			Workers:           2,
+			Queues:            2,
+			StatsAddr:         addr,
+			HeartbeatInterval: time.Millisecond * 20,
+		}, nil)
+		defer queue.Close()
+
+		// the first heartbeat is sent as soon as the stream connects
+		Eventually(srv.latestHeartbeat).ShouldNot(BeNil())
+		first := srv.latestHeartbeat()
+		Expect(first.App).To(Equal("Test"))
+		Expect(first.QueueId).To(Equal(queue.Stats().QueueId))
+		Expect(first.Workers).To(Equal(uint32(2)))
+		Expect(first.Version).To(Equal(rift.Version))
+
+		Eventually(func() int64 { return srv.latestHeartbeat().Uptime }).Should(BeNumerically(">", first.Uptime))
+		Expect(srv.latestHeartbeat().Backlog).To(Equal(uint32(0)))
+		Expect(queue.Uptime()).To(BeNumerically(">=", time.Duration(srv.latestHeartbeat().Uptime)))
+
+		close(done)
+	}, 3)
+
+	It("should present the stats token to the stats server", func(done Done) {
+		server, srv, addr := serveReports("127.0.0.1:0")
+		defer server.Stop()
+
+		logger := &recordingLogger{}
+		queue := rift.New(&rift.Options{
+			Tag:                "Test",
+			Workers:            2,
+			Queues:             2,
+			StatsAddr:          addr,
+			StatsToken:         "secret",
+			StatsInsecureToken: true,
+			Logger:             logger,
+		}, nil)
+		defer queue.Close()
+
+		Eventually(srv.authorization).Should(Equal("Bearer secret"))
+		Expect(logger.find(string(rift.LogInsecureToken))).To(HaveLen(1))
+
+		close(done)
+	}, 3)
+
+	It("should not send the stats token without tls unless opted into", func(done Done) {
+		server, srv, addr := serveReports("127.0.0.1:0")
+		defer server.Stop()
+
+		queue := rift.New(&rift.Options{
+			Tag:                 "Test",
+			Workers:             2,
+			Queues:              2,
+			StatsAddr:           addr,
+			StatsToken:          "secret",
+			ReconnectBackoff:    time.Millisecond * 10,
+			MaxReconnectBackoff: time.Millisecond * 50,
+		}, nil)
+		defer queue.Close()
+
+		Eventually(func() string { return queue.Stats().Monitoring.LastError }).Should(ContainSubstring("transport"))
+		Expect(queue.Stats().Monitoring.State).ToNot(Equal(rift.MonitoringConnected))
+		Consistently(srv.authorization, time.Millisecond*100).Should(BeEmpty())
+
+		close(done)
+	}, 3)
+
+	It("should discard the oldest updates past the buffer limit", func(done Done) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		addr := lis.Addr().String()
+		lis.Close()
+
+		queue := rift.New(&rift.Options{
+			Tag:              "Test",
+			Workers:          2,
+			Queues:           2,
+			StatsAddr:        addr,
+			ReportInterval:   time.Millisecond * 10,
+			MonitoringBuffer: 4,
+		}, nil)
+		defer queue.Close()
+
+		for i := 0; i < 5; i++ {
+			queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			time.Sleep(time.Millisecond * 20)
+		}
+
+		Eventually(func() uint64 { return queue.Stats().Monitoring.DroppedJobs }).Should(BeNumerically(">", 0))
+		stats := queue.Stats()
+		Expect(stats.Monitoring.State).To(Equal(rift.MonitoringDisconnected))
+		Expect(stats.Monitoring.BufferedJobs).To(BeNumerically("<=", 4))
+
+		close(done)
+	}, 3)
+})
@@ -0,0 +1,218 @@
+package rift
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	defaultMetricsBuffer  = 1024
+	defaultReportInterval = time.Second
+)
+
+// BackpressurePolicy decides what happens to a metrics event when the
+// metrics buffer is full. Workers never wait on the metrics pipeline.
+type BackpressurePolicy int
+
+const (
+	// DropNewest discards the incoming event
+	DropNewest BackpressurePolicy = iota
+	// DropOldest discards the oldest buffered event to make room for the incoming one
+	DropOldest
+	// Coalesce holds events back until the buffer drains, reporting only the
+	// latest event per job to the stats server
+	Coalesce
+)
+
+// report is a batch of job updates along with a snapshot of the stats at the
+// time it was taken
+type report struct {
+	jobs  []*summary.Job
+	stats *summary.Stats
+}
+
+// DroppedEvents is the number of metrics events discarded by the backpressure policy
+func (q *Queue) DroppedEvents() uint64 {
+	return atomic.LoadUint64(&q.droppedEvents)
+}
+
+func (q *Queue) dropEvent() {
+	atomic.AddUint64(&q.droppedEvents, 1)
+}
+
+// emit hands a job update to the metrics capture without ever blocking the caller
+func (q *Queue) emit(job *summary.Job) {
+	if q.metricsPolicy == Coalesce {
+		q.coalesce(job)
+		return
+	}
+
+	select {
+	case q.metrics <- job:
+		return
+	default:
+	}
+
+	switch q.metricsPolicy {
+	case DropOldest:
+		select {
+		case <-q.metrics:
+			q.dropEvent()
+		default:
+		}
+		select {
+		case q.metrics <- job:
+		default:
+			q.dropEvent()
+		}
+	default:
+		q.dropEvent()
+	}
+}
+
+// coalesce holds the update back once the buffer is full, and keeps holding
+// the updates of a job already held back so they are applied in order
+func (q *Queue) coalesce(job *summary.Job) {
+	q.coalesceMutex.Lock()
+	defer q.coalesceMutex.Unlock()
+
+	if held, ok := q.coalesced[job.Id]; ok {
+		q.coalesced[job.Id] = append(held, job)
+		return
+	}
+	select {
+	case q.metrics <- job:
+	default:
+		q.coalesced[job.Id] = []*summary.Job{job}
+	}
+}
+
+// drainCoalesced applies the updates held back by the Coalesce policy. Every
+// update is applied to the stats so the counters stay true, only the latest
+// update of each job is sent on to the stats server.
+func (q *Queue) drainCoalesced() {
+	// anything buffered was emitted before the held back updates
+	for n := len(q.metrics); n > 0; n-- {
+		q.capture(<-q.metrics)
+	}
+
+	q.coalesceMutex.Lock()
+	if len(q.coalesced) == 0 {
+		q.coalesceMutex.Unlock()
+		return
+	}
+	coalesced := q.coalesced
+	q.coalesced = make(map[string][]*summary.Job, len(coalesced))
+	q.coalesceMutex.Unlock()
+
+	for _, jobs := range coalesced {
+		for _, job := range jobs[:len(jobs)-1] {
+			q.apply(job)
+		}
+		q.capture(jobs[len(jobs)-1])
+	}
+}
+
+// capture applies a job update to the stats and holds it for the next report
+func (q *Queue) capture(job *summary.Job) {
+	q.apply(job)
+
+	if q.statsAddr == "" {
+		return
+	}
+	if len(q.pending) >= q.metricsBuffer {
+		q.pending = q.pending[1:]
+		q.dropEvent()
+	}
+	q.pending = append(q.pending, job)
+}
+
+// apply records a job update in the stats and the prometheus exporter
+func (q *Queue) apply(job *summary.Job) {
+	q.statsMutex.Lock()
+	updateJob(q.stats, job)
+	q.history.touch(q.stats, job)
+	q.recordLatency(job)
+	q.counters.store(q.stats)
+	q.statsMutex.Unlock()
+
+	q.exporter.ObserveJob(q.stats.App, job)
+}
+
+// observeGauges records the queue gauges with the prometheus exporter
+func (q *Queue) observeGauges() {
+	q.exporter.ObserveStats(&summary.Stats{
+		App:         q.stats.App,
+		QueueId:     q.id,
+		ActiveJobs:  q.ActiveJobs(),
+		Workers:     uint32(q.workerCount),
+		IdleWorkers: uint32(len(q.workers)),
+	})
+}
+
+// expire evicts the jobs which have outlived the retention policy
+func (q *Queue) expire() {
+	q.statsMutex.Lock()
+	q.history.expire(q.stats, time.Now())
+	evicted := q.history.drainEvicted()
+	q.statsMutex.Unlock()
+	q.forget(evicted)
+
+	if q.statsAddr != "" {
+		q.pendingEvicted = append(q.pendingEvicted, evicted...)
+	}
+}
+
+// flush hands the pending job updates to the reporter, unless it is still
+// busy with the previous report in which case they wait for the next flush
+func (q *Queue) flush() {
+	q.statsMutex.Lock()
+	evicted := q.history.drainEvicted()
+	q.statsMutex.Unlock()
+	q.forget(evicted)
+
+	if q.statsAddr == "" {
+		return
+	}
+
+	q.pendingEvicted = append(q.pendingEvicted, evicted...)
+	if len(q.pending) == 0 && len(q.pendingEvicted) == 0 {
+		return
+	}
+
+	select {
+	case q.reports <- report{jobs: q.pending, stats: q.delta(q.pending, q.pendingEvicted)}:
+		q.pending = make([]*summary.Job, 0, len(q.pending))
+		q.pendingEvicted = make([]string, 0)
+	default:
+	}
+}
+
+// delta snapshots the stats carrying only the given job changes rather than
+// the full job history
+func (q *Queue) delta(jobs []*summary.Job, evicted []string) *summary.Stats {
+	// the history is detached while cloning so it isn't needlessly copied,
+	// which is safe as the metrics capture is the only writer of the stats
+	q.statsMutex.Lock()
+	history := q.stats.Jobs
+	q.stats.Jobs = nil
+	stats := proto.Clone(q.stats).(*summary.Stats)
+	q.stats.Jobs = history
+	stats.Latencies = q.latencyPercentiles()
+	q.statsMutex.Unlock()
+
+	stats.DroppedEvents = q.DroppedEvents()
+	if q.monitor != nil {
+		stats.Monitoring = q.monitor.status()
+	}
+	stats.Jobs = make(map[string]*summary.Job, len(jobs))
+	for _, job := range jobs {
+		stats.Jobs[job.Id] = job
+	}
+	stats.EvictedJobs = evicted
+
+	return stats
+}
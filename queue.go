@@ -1,17 +1,21 @@
 package rift
 
 import (
+	"container/list"
 	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"net/http"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"google.golang.org/grpc"
-
+	"github.com/bmartel/rift/metrics"
 	"github.com/bmartel/rift/summary"
+	"github.com/bmartel/rift/trace"
+	"github.com/golang/protobuf/proto"
 	"github.com/satori/go.uuid"
-	"go.uber.org/zap"
 )
 
 // Queue provides the context and handling for jobs for one App instance
@@ -24,29 +28,76 @@ type Queue struct {
 	queueRemoved chan bool
 
 	// workers channel
-	workers chan *Worker
+	workers     chan *Worker
+	workerCount int
 
 	// metrics channel
 	metrics              chan *summary.Job
+	metricsBuffer        int
+	metricsPolicy        BackpressurePolicy
+	droppedEvents        uint64
+	coalesceMutex        sync.Mutex
+	coalesced            map[string][]*summary.Job
 	closeMetricsServer   chan bool
 	metricsServerRemoved chan bool
 
-	// metrics
-	stats *summary.Stats
+	// batched reporting to the monitoring server
+	reportInterval  time.Duration
+	pending         []*summary.Job
+	pendingEvicted  []string
+	reports         chan report
+	reporterRemoved chan bool
+
+	heartbeatInterval time.Duration
+	// backlog is the number of jobs queued and waiting for a worker
+	backlog int64
+
+	// metrics, stats are only modified by the metrics capture while holding
+	// the stats lock
+	statsMutex sync.RWMutex
+	stats      *summary.Stats
+	counters   counters
+	history    *history
+	latencies  map[string]*latency
 
 	createdAt time.Time
 
 	// logging
-	logger  *zap.Logger
-	verbose bool
+	logger *eventLogger
 
 	// serialization
 	registry *Registry
 
+	// jobs which can still be acted on by retry and cancel, the dead jobs
+	// are held in the order they were buried up to the retention limit
+	controlMutex sync.Mutex
+	dead         map[uuid.UUID]*list.Element
+	buried       *list.List
+	queued       map[uuid.UUID]bool
+	running      map[uuid.UUID]context.CancelFunc
+	cancelled    map[uuid.UUID]bool
+
+	// processing middleware
+	middlewareMutex sync.RWMutex
+	middleware      []Middleware
+	tagMiddleware   map[string][]Middleware
+
+	// lifecycle event subscribers
+	subscriberMutex sync.RWMutex
+	subscribers     map[uuid.UUID]*Subscription
+
+	// prometheus metrics
+	exporter *metrics.Exporter
+
+	// tracing, nil when no trace exporter is configured
+	tracer *trace.Tracer
+
 	// monitoring
-	statsAddr  string
-	rpcConn    *grpc.ClientConn
-	monitoring summary.SummaryClient
+	statsAddr string
+	monitor   *monitor
+
+	// remote commands, nil unless subscribed through the stats server
+	commands *commands
 }
 
 // Options provides a way to configure a rift queue
@@ -54,8 +105,68 @@ type Options struct {
 	Tag       string
 	Workers   int
 	Queues    int
-	Verbose   bool
 	StatsAddr string
+
+	// StatsTLS secures the connection to the stats server, which is plain
+	// TCP when left nil. Set client certificates on it for mutual TLS, see
+	// NewStatsTLS.
+	StatsTLS *tls.Config
+	// StatsToken authenticates the queue with the stats server. It is only
+	// sent over TLS unless StatsInsecureToken is set.
+	StatsToken string
+	// StatsInsecureToken allows the token to be sent over a plain
+	// connection, such as on a trusted network. A warning is logged when it
+	// is used.
+	StatsInsecureToken bool
+
+	// Logger receives the queue's log entries, a production zap logger is
+	// used when left nil
+	Logger Logger
+	// LogLevels overrides the level of individual events from
+	// DefaultLogLevels, LevelOff silences an event
+	LogLevels map[LogEvent]Level
+	// Verbose logs the queue and worker lifecycle events at info level.
+	//
+	// Deprecated: set the levels of those events in LogLevels instead.
+	Verbose bool
+
+	// Middleware wraps every job processed by the queue, DefaultMiddleware is
+	// used when left nil
+	Middleware []Middleware
+	// TagMiddleware wraps jobs of a specific tag, inside of Middleware
+	TagMiddleware map[string][]Middleware
+
+	// MetricsBuffer is the number of metrics events held before the
+	// MetricsPolicy applies
+	MetricsBuffer int
+	// MetricsPolicy decides which events are discarded when the buffer is full
+	MetricsPolicy BackpressurePolicy
+	// ReportInterval is how often batched updates are sent to the stats server
+	ReportInterval time.Duration
+	// MonitoringBuffer is the most job updates held for the stats server
+	// while it can't be reached, the oldest are discarded past it. Updates
+	// are delivered at most once, those in flight when the connection
+	// breaks are lost.
+	MonitoringBuffer int
+	// ReconnectBackoff is the delay before reconnecting to the stats server
+	// after the first failure, doubling with every failure after
+	ReconnectBackoff time.Duration
+	// MaxReconnectBackoff caps the delay between reconnects
+	MaxReconnectBackoff time.Duration
+	// HeartbeatInterval is how often the queue tells the stats server it is
+	// still running, with its uptime, workers and backlog
+	HeartbeatInterval time.Duration
+
+	// RemoteCommands subscribes to the commands sent through the stats
+	// server, such as jobs created from the dashboard
+	RemoteCommands bool
+
+	// Retention bounds the job history held in the stats
+	Retention Retention
+
+	// TraceExporter receives a span for queueing, waiting and processing each
+	// job, tracing is disabled when left nil
+	TraceExporter trace.Exporter
 }
 
 // New creates a rift queue, allowing options to be passed
@@ -73,7 +184,7 @@ func New(opts *Options, service Service) *Queue {
 	}
 
 	if opts == nil {
-		opts = &Options{"", 0, 0, false, ""}
+		opts = &Options{}
 	}
 
 	if opts.Tag == "" {
@@ -85,100 +196,259 @@ func New(opts *Options, service Service) *Queue {
 	if opts.Queues < 1 {
 		opts.Queues = maxQueues
 	}
+	if opts.Middleware == nil {
+		opts.Middleware = DefaultMiddleware()
+	}
+	if opts.MetricsBuffer < 1 {
+		opts.MetricsBuffer = defaultMetricsBuffer
+	}
+	if opts.ReportInterval <= 0 {
+		opts.ReportInterval = defaultReportInterval
+	}
+	if opts.MonitoringBuffer < 1 {
+		opts.MonitoringBuffer = defaultMonitoringBuffer
+	}
+	if opts.ReconnectBackoff <= 0 {
+		opts.ReconnectBackoff = defaultReconnectBackoff
+	}
+	if opts.MaxReconnectBackoff <= 0 {
+		opts.MaxReconnectBackoff = defaultMaxReconnectBackoff
+	}
+	if opts.HeartbeatInterval <= 0 {
+		opts.HeartbeatInterval = defaultHeartbeatInterval
+	}
 
 	var id uuid.UUID
 	id = uuid.NewV4()
 
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("can't initialize zap logger: %v", err)
+	if opts.Logger == nil {
+		opts.Logger = defaultLogger()
+	}
+	levels := DefaultLogLevels()
+	if opts.Verbose {
+		for _, event := range []LogEvent{LogQueueStarted, LogQueueStopped, LogWorkerStarted, LogWorkerStopped} {
+			levels[event] = LevelInfo
+		}
+	}
+	for event, level := range opts.LogLevels {
+		levels[event] = level
 	}
-	defer logger.Sync()
 
 	q := &Queue{
 		id:                   id.String(),
 		channel:              make(chan ReservedJob, opts.Queues),
 		workers:              make(chan *Worker, opts.Workers),
+		workerCount:          opts.Workers,
 		closeQueue:           make(chan bool),
 		queueRemoved:         make(chan bool),
 		closeMetricsServer:   make(chan bool),
 		metricsServerRemoved: make(chan bool),
-		metrics:              make(chan *summary.Job),
+		metrics:              make(chan *summary.Job, opts.MetricsBuffer),
+		metricsBuffer:        opts.MetricsBuffer,
+		metricsPolicy:        opts.MetricsPolicy,
+		coalesced:            make(map[string][]*summary.Job, 0),
+		reportInterval:       opts.ReportInterval,
+		pending:              make([]*summary.Job, 0),
+		pendingEvicted:       make([]string, 0),
+		reports:              make(chan report, 1),
+		reporterRemoved:      make(chan bool),
+		heartbeatInterval:    opts.HeartbeatInterval,
 		stats:                new(summary.Stats),
+		history:              newHistory(opts.Retention),
+		latencies:            make(map[string]*latency, 0),
+		exporter:             metrics.NewExporter(),
 		registry:             NewRegistry(),
+		dead:                 make(map[uuid.UUID]*list.Element, 0),
+		buried:               list.New(),
+		queued:               make(map[uuid.UUID]bool, 0),
+		running:              make(map[uuid.UUID]context.CancelFunc, 0),
+		cancelled:            make(map[uuid.UUID]bool, 0),
+		middleware:           opts.Middleware,
+		tagMiddleware:        make(map[string][]Middleware, 0),
+		subscribers:          make(map[uuid.UUID]*Subscription, 0),
 		statsAddr:            opts.StatsAddr,
-		logger:               logger,
-		verbose:              opts.Verbose,
+		logger:               newEventLogger(opts.Logger, levels),
 		createdAt:            time.Now(),
 	}
 
+	if opts.TraceExporter != nil {
+		q.tracer = trace.NewTracer(opts.TraceExporter)
+	}
+	if opts.StatsAddr != "" {
+		if opts.StatsToken != "" && opts.StatsTLS == nil && opts.StatsInsecureToken {
+			q.logger.log(LogInsecureToken, field("addr", opts.StatsAddr))
+		}
+		q.monitor = newMonitor(opts, q.logger, q.heartbeat)
+		if opts.RemoteCommands {
+			q.commands = newCommands(opts)
+		}
+	}
+
 	q.stats.App = opts.Tag
 	q.stats.QueueId = q.id
 	q.stats.Jobs = make(map[string]*summary.Job, 0)
 	q.stats.JobBlueprints = make([]*summary.JobBlueprint, 0)
 
-	if opts.Verbose {
-		q.logger.Info("queue started")
+	for tag, middleware := range opts.TagMiddleware {
+		q.UseTag(tag, middleware...)
 	}
 
+	q.logger.log(LogQueueStarted)
+
 	// starting n number of workers
 	for i := 0; i < opts.Workers; i++ {
 		dispatchWorker(q, service, opts)
 	}
 
-	q.logger.Info("workers started", zap.Int("count", opts.Workers))
+	q.logger.log(LogWorkersStarted, field("count", opts.Workers))
 
 	go q.startDispatcher()
 	go q.startMetricsCapture()
-
-	q.startMonitoringServer()
+	go q.startReporter()
+	if q.commands != nil {
+		go q.startCommands()
+	}
 
 	return q
 }
 
-// Stats of this queue's operation metrics
+// Stats returns a consistent snapshot of this queue's operation metrics. The
+// snapshot is a deep copy and is safe to read while jobs are running.
 func (q *Queue) Stats() *summary.Stats {
-	return q.stats
+	return q.snapshot()
 }
 
-// tries a connection to a monitoring server instance if available
-func (q *Queue) startMonitoringServer() {
-	if q.rpcConn == nil && q.statsAddr != "" {
-		conn, err := grpc.Dial(q.statsAddr, grpc.WithInsecure())
-		if err != nil {
-			q.logger.Error(err.Error())
-		} else {
-			q.rpcConn = conn
-			q.monitoring = summary.NewSummaryClient(q.rpcConn)
-			q.logger.Info("CONNECTED TO MONITORING SERVER")
-		}
+func (q *Queue) snapshot() *summary.Stats {
+	q.statsMutex.RLock()
+	stats := proto.Clone(q.stats).(*summary.Stats)
+	stats.Latencies = q.latencyPercentiles()
+	q.statsMutex.RUnlock()
+
+	stats.DroppedEvents = q.DroppedEvents()
+	if q.monitor != nil {
+		stats.Monitoring = q.monitor.status()
+	}
+	stats.Workers = uint32(q.workerCount)
+	stats.IdleWorkers = uint32(len(q.workers))
+	return stats
+}
+
+// MetricsHandler serves the queue metrics in the Prometheus text format
+func (q *Queue) MetricsHandler() http.Handler {
+	return q.exporter
+}
+
+// ActiveJobs is the number of jobs currently being processed
+func (q *Queue) ActiveJobs() uint32 {
+	return atomic.LoadUint32(&q.counters.active)
+}
+
+// Backlog is the number of jobs queued and waiting for a worker
+func (q *Queue) Backlog() uint32 {
+	if backlog := atomic.LoadInt64(&q.backlog); backlog > 0 {
+		return uint32(backlog)
+	}
+	return 0
+}
+
+// Uptime is how long the queue has been running
+func (q *Queue) Uptime() time.Duration {
+	return time.Since(q.createdAt)
+}
+
+// heartbeat describes the running queue instance to the stats server
+func (q *Queue) heartbeat() *summary.Heartbeat {
+	return &summary.Heartbeat{
+		App:         q.stats.App,
+		QueueId:     q.id,
+		SentAt:      time.Now().UnixNano(),
+		Uptime:      int64(q.Uptime()),
+		Workers:     uint32(q.workerCount),
+		IdleWorkers: uint32(len(q.workers)),
+		Backlog:     q.Backlog(),
+		Version:     Version,
 	}
 }
 
+// QueuedJobs is the number of jobs queued since the queue started
+func (q *Queue) QueuedJobs() uint32 {
+	return atomic.LoadUint32(&q.counters.queued)
+}
+
+// ProcessedJobs is the number of jobs processed without error
+func (q *Queue) ProcessedJobs() uint32 {
+	return atomic.LoadUint32(&q.counters.processed)
+}
+
+// DeferredJobs is the number of jobs which have been deferred
+func (q *Queue) DeferredJobs() uint32 {
+	return atomic.LoadUint32(&q.counters.deferred)
+}
+
+// FailedJobs is the number of failed job attempts
+func (q *Queue) FailedJobs() uint32 {
+	return atomic.LoadUint32(&q.counters.failed)
+}
+
+// RequeuedJobs is the number of failed job attempts which were queued again
+func (q *Queue) RequeuedJobs() uint32 {
+	return atomic.LoadUint32(&q.counters.requeued)
+}
+
+// CancelledJobs is the number of jobs cancelled before they finished
+func (q *Queue) CancelledJobs() uint32 {
+	return atomic.LoadUint32(&q.counters.cancelled)
+}
+
 // Later queues up a job for processing and returns the id of the job
 func (q *Queue) Later(job Job, retry uint8) uuid.UUID {
-	id := uuid.NewV4()
-	go func() {
-		q.channel <- ReservedJob{
-			ID:          id,
-			Job:         job,
-			RequestedAt: time.Now(),
-			Retry:       retry,
-		}
+	return q.LaterContext(context.Background(), job, retry)
+}
 
-		q.logger.Info("job queued", zap.String("job", id.String()))
+// LaterContext queues a job like Later, continuing the trace of the span
+// context carried by ctx
+func (q *Queue) LaterContext(ctx context.Context, job Job, retry uint8) uuid.UUID {
+	reserved := newReservedJob(job, retry)
+	reserved.Trace = trace.SpanContextFromContext(ctx)
+	q.submit(reserved)
+	return reserved.ID
+}
+
+// submit hands a job to the queue in the background, it can be cancelled as
+// soon as submit returns
+func (q *Queue) submit(job ReservedJob) {
+	q.markQueued(job.ID)
+	go q.enqueue(job)
+}
+
+// enqueue blocks until the reserved job has been accepted by the queue channel
+func (q *Queue) enqueue(job ReservedJob) {
+	atomic.AddInt64(&q.backlog, 1)
+	job.QueuedAt = time.Now()
+	span := q.startSpan("publish", job.Trace, job, job.RequestedAt)
+	if span != nil {
+		job.Trace = span.SpanContext
+	}
 
-		q.metrics <- &summary.Job{Id: id.String(), Status: "queued", Worker: q.id}
+	// the job is tracked before it is handed over, a worker could otherwise
+	// report it started before it was queued
+	q.logger.log(LogJobQueued, field("job", job.ID.String()))
+	q.track(newEvent(EventEnqueued, job, nil))
 
-	}()
-	return id
+	q.channel <- job
+
+	if span != nil {
+		span.End(time.Now())
+	}
 }
 
 // Register a job type so it can be looked up through deserialization
 func (q *Queue) Register(jobs ...Job) {
 	for _, job := range jobs {
 		// Capture the job imprint for serialization
+		q.statsMutex.Lock()
 		q.registry.SerializeJob(job, q.stats)
+		q.statsMutex.Unlock()
 	}
 }
 
@@ -194,32 +464,57 @@ func (q *Queue) CreateJob(jobType string, data map[string]interface{}, retry uin
 	return id.String(), nil
 }
 
+// SerializePayload deconstructs a reserved job for storing outside of the queue
+func (q *Queue) SerializePayload(job ReservedJob) Payload {
+	return q.registry.SerializePayload(job)
+}
+
+// DeserializePayload restores a reserved job from a stored payload
+func (q *Queue) DeserializePayload(payload Payload) (ReservedJob, error) {
+	return q.registry.DeserializePayload(payload)
+}
+
+// Restore queues a job from a stored payload, keeping its id, attempts and
+// trace context
+func (q *Queue) Restore(payload Payload) (uuid.UUID, error) {
+	job, err := q.DeserializePayload(payload)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	q.submit(job)
+
+	return job.ID, nil
+}
+
 // Close the queue, first draining any open workers and jobs in queue
 func (q *Queue) Close() {
+	if q.commands != nil {
+		// no more jobs are accepted from remote callers
+		q.commands.cancel()
+		<-q.commands.removed
+	}
 	q.closeQueue <- true
 	<-q.queueRemoved
 	q.drain()
 	close(q.queueRemoved)
 	close(q.workers)
 	close(q.channel)
+	if q.monitor != nil {
+		// don't wait on a stats server which can't be reached
+		q.monitor.cancel()
+	}
 	q.closeMetricsServer <- true
 	<-q.metricsServerRemoved
 	close(q.metricsServerRemoved)
-	q.monitoring = nil
-	if q.rpcConn != nil {
-		q.rpcConn.Close()
-		q.rpcConn = nil
-	}
-	if q.verbose {
-		q.logger.Info("queue stopped")
+	q.closeSubscribers()
+	if q.tracer != nil {
+		q.tracer.Close()
 	}
+	q.logger.log(LogQueueStopped)
 }
 
 func (q *Queue) startDispatcher() {
-	if q.verbose {
-		q.logger.Info("queue started")
-	}
-
 	for {
 		select {
 		case job := <-q.channel:
@@ -239,17 +534,27 @@ func (q *Queue) startDispatcher() {
 }
 
 func (q *Queue) startMetricsCapture() {
+	ticker := time.NewTicker(q.reportInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case job := <-q.metrics:
-			updateJob(q.stats, job)
-			q.startMonitoringServer()
-			if q.monitoring != nil {
-				q.monitoring.UpdateJob(context.Background(), &summary.JobUpdate{App: q.stats.App, QueueId: q.stats.QueueId, Job: job})
-				q.monitoring.UpdateStats(context.Background(), q.stats)
-			}
+			q.capture(job)
+		case <-ticker.C:
+			q.drainCoalesced()
+			q.expire()
+			q.observeGauges()
+			q.flush()
 		case <-q.closeMetricsServer:
-			close(q.metrics)
+			// apply whatever is still buffered before the final report
+			for len(q.metrics) > 0 {
+				q.capture(<-q.metrics)
+			}
+			q.drainCoalesced()
+			q.flush()
+			close(q.reports)
+			<-q.reporterRemoved
 			close(q.closeMetricsServer)
 			q.metricsServerRemoved <- true
 			return
@@ -1,13 +1,20 @@
 package rift_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"log"
+	"net/http/httptest"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bmartel/rift"
 	"github.com/bmartel/rift/summary"
+	"github.com/bmartel/rift/trace"
+	"github.com/satori/go.uuid"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -61,6 +68,27 @@ func (t FailedJob) Process(service rift.Service) error {
 	return nil
 }
 
+// SlowFailingJob runs slowly and fails on its first attempt only
+type SlowFailingJob struct {
+	Attempts *int32
+}
+
+func (t SlowFailingJob) Tag() string {
+	return "SlowFailingJob"
+}
+
+func (t SlowFailingJob) Deserialize(data map[string]interface{}) rift.Job {
+	return t
+}
+
+func (t SlowFailingJob) Process(service rift.Service) error {
+	if atomic.AddInt32(t.Attempts, 1) == 1 {
+		time.Sleep(time.Millisecond * 100)
+		return fmt.Errorf("slow failure")
+	}
+	return nil
+}
+
 type LongRunningJob struct{}
 
 func (t LongRunningJob) Tag() string {
@@ -80,6 +108,49 @@ func (t LongRunningJob) Process(service rift.Service) error {
 	return nil
 }
 
+// blockingExporter holds up every export until it is released
+type blockingExporter struct {
+	release chan bool
+}
+
+func (e blockingExporter) ExportSpans(spans []trace.Span) error {
+	<-e.release
+	return nil
+}
+
+type logEntry struct {
+	level  rift.Level
+	msg    string
+	fields map[string]interface{}
+}
+
+type recordingLogger struct {
+	mutex   sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) Log(level rift.Level, msg string, fields ...rift.Field) {
+	entry := logEntry{level, msg, make(map[string]interface{}, 0)}
+	for _, f := range fields {
+		entry.fields[f.Key] = f.Value
+	}
+	l.mutex.Lock()
+	l.entries = append(l.entries, entry)
+	l.mutex.Unlock()
+}
+
+func (l *recordingLogger) find(msg string) []logEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	found := make([]logEntry, 0)
+	for _, entry := range l.entries {
+		if entry.msg == msg {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
 var _ = Describe("Queue", func() {
 	var (
 		queue *rift.Queue
@@ -89,7 +160,7 @@ var _ = Describe("Queue", func() {
 		connectionRetry = 0
 
 		log.Println(runtime.NumGoroutine())
-		queue = rift.New(&rift.Options{"Test", 100, 100, false, "localhost:9147"}, nil)
+		queue = rift.New(&rift.Options{Tag: "Test", Workers: 100, Queues: 100, StatsAddr: "localhost:9147"}, nil)
 	})
 	AfterEach(func() {
 		queue.Close()
@@ -165,6 +236,30 @@ var _ = Describe("Queue", func() {
 			close(done)
 		}, 3)
 
+		It("should return stats snapshots which are not changed by later jobs", func(done Done) {
+			queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+
+			time.Sleep(time.Millisecond * 50)
+
+			stats := queue.Stats()
+			Expect(stats.Jobs).To(HaveLen(1))
+
+			queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+
+			time.Sleep(time.Millisecond * 50)
+
+			Expect(stats.ProcessedJobs).To(Equal(uint32(1)))
+			Expect(stats.Jobs).To(HaveLen(1))
+			Expect(queue.Stats().Jobs).To(HaveLen(2))
+
+			Expect(queue.QueuedJobs()).To(Equal(uint32(2)))
+			Expect(queue.ProcessedJobs()).To(Equal(uint32(2)))
+			Expect(queue.ActiveJobs()).To(Equal(uint32(0)))
+			Expect(queue.FailedJobs()).To(Equal(uint32(0)))
+
+			close(done)
+		}, 3)
+
 		It("should capture a queued jobs details and correctly produce a new job through serialization", func(done Done) {
 			queue.Register(SampleJob{})
 
@@ -209,7 +304,7 @@ var _ = Describe("Queue", func() {
 		}, 3)
 
 		It("should queue and process jobs even if the queue is saturated", func(done Done) {
-			queue2 := rift.New(&rift.Options{"Test", 2, 1, false, "localhost:9147"}, nil)
+			queue2 := rift.New(&rift.Options{Tag: "Test", Workers: 2, Queues: 1, StatsAddr: "localhost:9147"}, nil)
 			queue2.Later(LongRunningJob{}, 1)
 			queue2.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
 			queue2.Later(LongRunningJob{}, 1)
@@ -227,5 +322,514 @@ var _ = Describe("Queue", func() {
 			close(done)
 		}, 8)
 
+		It("should bound the job history to the retention limit", func(done Done) {
+			queue2 := rift.New(&rift.Options{Tag: "Test", Workers: 10, Queues: 10, Retention: rift.Retention{MaxJobs: 5}}, nil)
+
+			for i := 0; i < 20; i++ {
+				queue2.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			}
+
+			time.Sleep(time.Millisecond * 50)
+
+			stats := queue2.Stats()
+			Expect(stats.ProcessedJobs).To(Equal(uint32(20)))
+			Expect(stats.Jobs).To(HaveLen(5))
+
+			queue2.Close()
+			close(done)
+		}, 3)
+
+		It("should evict processed jobs before failed jobs at the retention limit", func(done Done) {
+			queue2 := rift.New(&rift.Options{Tag: "Test", Workers: 10, Queues: 10, Retention: rift.Retention{MaxJobs: 3}}, nil)
+
+			failed := queue2.Later(BrokenJob{}, 0)
+			Eventually(func() uint32 { return queue2.Stats().FailedJobs }).Should(Equal(uint32(1)))
+
+			// one at a time, so there is always a processed job to evict
+			for i := 1; i <= 10; i++ {
+				queue2.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+				Eventually(func() uint32 { return queue2.Stats().ProcessedJobs }).Should(Equal(uint32(i)))
+			}
+
+			stats := queue2.Stats()
+			Expect(stats.Jobs).To(HaveLen(3))
+			Expect(stats.Jobs).To(HaveKey(failed.String()))
+
+			queue2.Close()
+			close(done)
+		}, 3)
+
+		It("should keep failed jobs longer than processed jobs", func(done Done) {
+			queue2 := rift.New(&rift.Options{
+				Tag:            "Test",
+				Workers:        10,
+				Queues:         10,
+				ReportInterval: time.Millisecond * 10,
+				Retention:      rift.Retention{MaxAge: time.Millisecond * 20, FailedMaxAge: time.Hour},
+			}, nil)
+
+			queue2.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			failed := queue2.Later(BrokenJob{}, 0)
+
+			time.Sleep(time.Millisecond * 100)
+
+			stats := queue2.Stats()
+			Expect(stats.Jobs).To(HaveLen(1))
+			Expect(stats.Jobs).To(HaveKey(failed.String()))
+
+			queue2.Close()
+			close(done)
+		}, 3)
+
+		It("should return workers to the pool after failed jobs", func(done Done) {
+			queue2 := rift.New(&rift.Options{Tag: "Test", Workers: 3, Queues: 10}, nil)
+
+			for i := 0; i < 10; i++ {
+				queue2.Later(BrokenJob{}, 1)
+			}
+			Eventually(func() uint32 { return queue2.Stats().FailedJobs }).Should(Equal(uint32(20)))
+			Eventually(func() uint32 { return queue2.Stats().IdleWorkers }).Should(Equal(uint32(3)))
+
+			for i := 0; i < 10; i++ {
+				queue2.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			}
+			Eventually(func() uint32 { return queue2.Stats().ProcessedJobs }).Should(Equal(uint32(10)))
+			Eventually(func() uint32 { return queue2.Stats().IdleWorkers }).Should(Equal(uint32(3)))
+
+			queue2.Close()
+			close(done)
+		}, 3)
+
+		It("should never block workers when the metrics buffer is full", func(done Done) {
+			queue2 := rift.New(&rift.Options{Tag: "Test", Workers: 10, Queues: 10, MetricsBuffer: 1, MetricsPolicy: rift.DropNewest}, nil)
+
+			batch := queue2.NewBatch()
+			for i := 0; i < 200; i++ {
+				batch.Add(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			}
+			batch.Dispatch()
+			batch.Wait()
+
+			time.Sleep(time.Millisecond * 50)
+
+			stats := queue2.Stats()
+			dropped := queue2.DroppedEvents()
+			Expect(uint64(stats.QueuedJobs) + dropped).To(BeNumerically(">=", 200))
+			Expect(uint64(stats.ProcessedJobs) + dropped).To(BeNumerically(">=", 200))
+
+			queue2.Close()
+			close(done)
+		}, 3)
+
+		It("should keep the counters true after a burst under the coalesce policy", func(done Done) {
+			queue2 := rift.New(&rift.Options{Tag: "Test", Workers: 10, Queues: 10, MetricsBuffer: 1, MetricsPolicy: rift.Coalesce, ReportInterval: time.Millisecond * 10}, nil)
+
+			batch := queue2.NewBatch()
+			for i := 0; i < 200; i++ {
+				batch.Add(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			}
+			batch.Dispatch()
+			batch.Wait()
+
+			Eventually(func() uint32 { return queue2.Stats().ProcessedJobs }).Should(Equal(uint32(200)))
+
+			stats := queue2.Stats()
+			Expect(stats.QueuedJobs).To(Equal(uint32(200)))
+			Expect(stats.ActiveJobs).To(Equal(uint32(0)))
+			Expect(queue2.ActiveJobs()).To(Equal(uint32(0)))
+
+			queue2.Close()
+			close(done)
+		}, 3)
+
+	})
+
+	Describe("Latency percentiles", func() {
+		It("should summarise wait, run and total durations per tag", func(done Done) {
+			queue.Later(LongRunningJob{}, 0)
+			queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+
+			time.Sleep(time.Millisecond * 50)
+
+			stats := queue.Stats()
+			Expect(stats.Latencies).To(HaveKey("SampleJob"))
+			Expect(stats.Latencies).To(HaveKey("LongRunningJob"))
+
+			sample := stats.Latencies["SampleJob"]
+			Expect(sample.Wait.Count).To(Equal(uint64(1)))
+			Expect(sample.Run.Count).To(Equal(uint64(1)))
+			Expect(sample.Total.Count).To(Equal(uint64(1)))
+			Expect(sample.Total.P99).To(BeNumerically(">=", sample.Run.P99))
+			Expect(sample.Run.P50).To(BeNumerically("<", 0.05))
+
+			long := stats.Latencies["LongRunningJob"]
+			Expect(long.Wait.Count).To(Equal(uint64(1)))
+			Expect(long.Run.Count).To(Equal(uint64(0)))
+
+			close(done)
+		}, 3)
+
+		It("should measure the wait of a retried attempt from when it was queued again", func(done Done) {
+			logger := &recordingLogger{}
+			retried := rift.New(&rift.Options{Tag: "Test", Workers: 2, Queues: 2, Logger: logger}, nil)
+			defer retried.Close()
+
+			retried.Later(SlowFailingJob{new(int32)}, 1)
+			Eventually(func() uint32 { return retried.Stats().ProcessedJobs }).Should(Equal(uint32(1)))
+
+			latency := retried.Stats().Latencies["SlowFailingJob"]
+			Expect(latency.Wait.Count).To(Equal(uint64(2)))
+			Expect(latency.Wait.P99).To(BeNumerically("<", 0.05))
+			Expect(latency.Total.P99).To(BeNumerically(">=", 0.1))
+
+			timings := logger.find(string(rift.LogJobTiming))
+			Expect(timings).To(HaveLen(2))
+			for _, entry := range timings {
+				Expect(entry.fields["wait"]).To(BeNumerically("<", 0.05))
+			}
+			Expect(timings[1].fields["duration"]).To(BeNumerically(">=", 0.1))
+
+			close(done)
+		}, 3)
+	})
+
+	Describe("Prometheus metrics", func() {
+		It("should export job counters and timing histograms per tag", func(done Done) {
+			queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			queue.Later(BrokenJob{}, 1)
+
+			time.Sleep(time.Millisecond * 50)
+
+			rec := httptest.NewRecorder()
+			queue.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+			body := rec.Body.String()
+			Expect(body).To(ContainSubstring(`rift_jobs_queued_total{app="Test",tag="SampleJob"} 1`))
+			Expect(body).To(ContainSubstring(`rift_jobs_processed_total{app="Test",tag="SampleJob"} 1`))
+			Expect(body).To(ContainSubstring(`rift_jobs_failed_total{app="Test",tag="BrokenJob"} 2`))
+			Expect(body).To(ContainSubstring(`rift_jobs_requeued_total{app="Test",tag="BrokenJob"} 1`))
+			Expect(body).To(ContainSubstring(`rift_job_wait_seconds_count{app="Test",tag="SampleJob"} 1`))
+			Expect(body).To(ContainSubstring(`rift_job_processing_seconds_count{app="Test",tag="BrokenJob"} 2`))
+			Expect(body).To(ContainSubstring("# TYPE rift_idle_workers gauge"))
+
+			close(done)
+		}, 3)
+	})
+
+	Describe("Processing middleware", func() {
+		It("should run queue and tag middleware around each job in order", func(done Done) {
+			calls := make(chan string, 10)
+			record := func(name string) rift.Middleware {
+				return func(next rift.Handler) rift.Handler {
+					return func(w *rift.Worker, job rift.ReservedJob, service rift.Service) error {
+						calls <- name + ":" + job.Job.Tag()
+						return next(w, job, service)
+					}
+				}
+			}
+
+			queue.Use(record("queue"))
+			queue.UseTag("SampleJob", record("tag"))
+
+			queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			Expect(<-calls).To(Equal("queue:SampleJob"))
+			Expect(<-calls).To(Equal("tag:SampleJob"))
+
+			queue.Later(FailedJob{}, 0)
+			Expect(<-calls).To(Equal("queue:FailedJob"))
+			Consistently(calls, time.Millisecond*50).ShouldNot(Receive())
+
+			close(done)
+		}, 3)
+
+		It("should let middleware short circuit a job", func(done Done) {
+			queue.UseTag("SampleJob", func(next rift.Handler) rift.Handler {
+				return func(w *rift.Worker, job rift.ReservedJob, service rift.Service) error {
+					return fmt.Errorf("rejected by middleware")
+				}
+			})
+
+			queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+
+			time.Sleep(time.Millisecond * 50)
+
+			stats := queue.Stats()
+			Expect(stats.ProcessedJobs).To(Equal(uint32(0)))
+			Expect(stats.FailedJobs).To(Equal(uint32(1)))
+
+			close(done)
+		}, 3)
+	})
+
+	Describe("Lifecycle events", func() {
+		It("should deliver every event for a job that is retried until it succeeds", func(done Done) {
+			events := make(chan rift.Event, 20)
+			queue.Subscribe(func(e rift.Event) { events <- e })
+
+			id := queue.Later(FailedJob{}, 3)
+
+			expected := []rift.EventType{
+				rift.EventEnqueued,
+				rift.EventStarted, rift.EventFailed, rift.EventRetried,
+				rift.EventStarted, rift.EventFailed, rift.EventRetried,
+				rift.EventStarted, rift.EventSucceeded,
+			}
+			for i, eventType := range expected {
+				e := <-events
+				Expect(e.Type).To(Equal(eventType))
+				Expect(e.JobID).To(Equal(id))
+				Expect(e.Tag).To(Equal("FailedJob"))
+				Expect(e.App).To(Equal("Test"))
+				if i > 0 {
+					Expect(e.Worker).ToNot(Equal(uuid.Nil))
+				}
+			}
+
+			close(done)
+		}, 3)
+
+		It("should only deliver the subscribed event types", func(done Done) {
+			events := make(chan rift.Event, 20)
+			queue.Subscribe(func(e rift.Event) { events <- e }, rift.EventDead)
+
+			queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			queue.Later(BrokenJob{}, 1)
+
+			e := <-events
+			Expect(e.Type).To(Equal(rift.EventDead))
+			Expect(e.Tag).To(Equal("BrokenJob"))
+			Expect(e.Attempt).To(Equal(uint8(2)))
+			Expect(e.Err).To(HaveOccurred())
+			Consistently(events, time.Millisecond*50).ShouldNot(Receive())
+
+			close(done)
+		}, 3)
+
+		It("should not block workers on a slow subscriber", func(done Done) {
+			block := make(chan bool)
+			sub := queue.Subscribe(func(e rift.Event) { <-block })
+
+			for i := 0; i < 100; i++ {
+				queue.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			}
+
+			time.Sleep(time.Millisecond * 100)
+
+			Expect(queue.Stats().ProcessedJobs).To(Equal(uint32(100)))
+			Expect(sub.Dropped()).To(BeNumerically(">", 0))
+
+			close(block)
+			close(done)
+		}, 3)
+
+		It("should not hang closing the queue on a blocked subscriber", func(done Done) {
+			block := make(chan bool)
+			defer close(block)
+
+			blocked := rift.New(&rift.Options{Tag: "Test", Workers: 2, Queues: 2}, nil)
+			blocked.Subscribe(func(e rift.Event) { <-block })
+
+			for i := 0; i < 10; i++ {
+				blocked.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			}
+			Eventually(func() uint32 { return blocked.Stats().ProcessedJobs }).Should(Equal(uint32(10)))
+
+			closed := make(chan bool)
+			go func() {
+				blocked.Close()
+				close(closed)
+			}()
+			Eventually(closed, 2).Should(BeClosed())
+
+			close(done)
+		}, 4)
+	})
+
+	Describe("Tracing", func() {
+		var (
+			exporter *trace.InMemoryExporter
+			traced   *rift.Queue
+			parent   trace.SpanContext
+		)
+
+		BeforeEach(func() {
+			exporter = trace.NewInMemoryExporter()
+			traced = rift.New(&rift.Options{Tag: "Test", Workers: 10, Queues: 10, TraceExporter: exporter}, nil)
+
+			var err error
+			parent, err = trace.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			Expect(err).ToNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			traced.Close()
+		})
+
+		It("should record the publish, wait and process spans under the caller's trace", func(done Done) {
+			ctx := trace.ContextWithSpanContext(context.Background(), parent)
+			id := traced.LaterContext(ctx, SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+
+			time.Sleep(time.Millisecond * 50)
+
+			spans := make(map[string]trace.Span, 0)
+			for _, span := range exporter.Spans() {
+				spans[span.Name] = span
+			}
+			Expect(spans).To(HaveLen(3))
+
+			publish := spans["SampleJob publish"]
+			Expect(publish.SpanContext.TraceID).To(Equal(parent.TraceID))
+			Expect(publish.Parent).To(Equal(parent))
+			Expect(publish.Attributes["messaging.message.id"]).To(Equal(id.String()))
+
+			wait := spans["SampleJob wait"]
+			Expect(wait.Parent).To(Equal(publish.SpanContext))
+			Expect(wait.EndTime).ToNot(BeTemporally("<", wait.StartTime))
+
+			process := spans["SampleJob process"]
+			Expect(process.Parent).To(Equal(publish.SpanContext))
+			Expect(process.StartTime).To(Equal(wait.EndTime))
+			Expect(process.Error).To(BeEmpty())
+
+			close(done)
+		}, 3)
+
+		It("should start the wait of a retried job when it was queued again", func(done Done) {
+			traced.Later(BrokenJob{}, 1)
+
+			Eventually(exporter.Spans).Should(HaveLen(5))
+
+			spans := make(map[string]trace.Span, 0)
+			for _, span := range exporter.Spans() {
+				spans[span.Name+" "+span.Attributes["rift.job.attempt"]] = span
+			}
+			first := spans["BrokenJob process 1"]
+			retried := spans["BrokenJob wait 2"]
+			Expect(retried.StartTime).ToNot(BeTemporally("<", first.EndTime))
+			Expect(retried.EndTime).To(Equal(spans["BrokenJob process 2"].StartTime))
+
+			close(done)
+		}, 3)
+
+		It("should not hold up jobs on a slow trace exporter", func(done Done) {
+			release := make(chan bool)
+			slow := rift.New(&rift.Options{Tag: "Test", Workers: 10, Queues: 10, TraceExporter: blockingExporter{release}}, nil)
+
+			for i := 0; i < 20; i++ {
+				slow.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			}
+			Eventually(func() uint32 { return slow.Stats().ProcessedJobs }).Should(Equal(uint32(20)))
+
+			close(release)
+			slow.Close()
+			close(done)
+		}, 3)
+
+		It("should start a new trace for each job queued without a span context", func(done Done) {
+			traced.Later(SampleJob{}, 0)
+
+			time.Sleep(time.Millisecond * 50)
+
+			spans := exporter.Spans()
+			Expect(spans).To(HaveLen(3))
+			for _, span := range spans {
+				Expect(span.SpanContext.TraceID.IsValid()).To(BeTrue())
+				Expect(span.SpanContext.TraceID).To(Equal(spans[0].SpanContext.TraceID))
+				if span.Name == "SampleJob process" {
+					Expect(span.Error).To(Equal("missing data members"))
+				}
+			}
+
+			close(done)
+		}, 3)
+
+		It("should carry the trace context in serialized payloads", func() {
+			traced.Register(SampleJob{})
+
+			job := rift.ReservedJob{
+				ID:          uuid.NewV4(),
+				Job:         SampleJob{1, "Rift", "Running a Managed Goroutine"},
+				RequestedAt: time.Now(),
+				Retry:       2,
+				Trace:       parent,
+			}
+
+			payload := traced.SerializePayload(job)
+			Expect(payload.Traceparent).To(Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+			Expect(payload.Data).To(HaveKeyWithValue("title", "Rift"))
+
+			restored, err := traced.DeserializePayload(payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(restored.ID).To(Equal(job.ID))
+			Expect(restored.Job).To(Equal(job.Job))
+			Expect(restored.Retry).To(Equal(uint8(2)))
+			Expect(restored.Trace).To(Equal(parent))
+			Expect(restored.RequestedAt.UnixNano()).To(Equal(job.RequestedAt.UnixNano()))
+
+			id, err := traced.Restore(payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal(job.ID))
+
+			Eventually(exporter.Spans).Should(HaveLen(3))
+			for _, span := range exporter.Spans() {
+				Expect(span.SpanContext.TraceID).To(Equal(parent.TraceID))
+			}
+		})
+
+		It("should reject malformed traceparents", func() {
+			_, err := trace.ParseTraceparent("00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+			Expect(err).To(HaveOccurred())
+			_, err = trace.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Logging", func() {
+		It("should write entries to the configured logger at the configured levels", func(done Done) {
+			logger := &recordingLogger{}
+			queue2 := rift.New(&rift.Options{
+				Tag:     "Test",
+				Workers: 2,
+				Queues:  2,
+				Logger:  logger,
+				LogLevels: map[rift.LogEvent]rift.Level{
+					rift.LogJobProcessed: rift.LevelDebug,
+					rift.LogJobQueued:    rift.LevelOff,
+				},
+			}, nil)
+
+			id := queue2.Later(SampleJob{1, "Rift", "Running a Managed Goroutine"}, 0)
+			queue2.Later(SampleJob{}, 0)
+
+			time.Sleep(time.Millisecond * 50)
+			queue2.Close()
+
+			Expect(logger.find("job queued")).To(BeEmpty())
+
+			processed := logger.find("job processed")
+			Expect(processed).To(HaveLen(1))
+			Expect(processed[0].level).To(Equal(rift.LevelDebug))
+			Expect(processed[0].fields).To(HaveKeyWithValue("job", id.String()))
+			Expect(processed[0].fields).To(HaveKey("worker"))
+
+			failed := logger.find("job failed")
+			Expect(failed).To(HaveLen(1))
+			Expect(failed[0].level).To(Equal(rift.LevelError))
+			Expect(failed[0].fields).To(HaveKeyWithValue("error", "missing data members"))
+
+			Expect(logger.find("worker started")).To(HaveLen(2))
+			Expect(logger.find("worker started")[0].level).To(Equal(rift.LevelDebug))
+
+			close(done)
+		}, 3)
+
+		It("should adapt a standard library logger", func() {
+			var buf bytes.Buffer
+			logger := rift.NewStdLogger(log.New(&buf, "", 0), rift.LevelInfo)
+
+			logger.Log(rift.LevelDebug, "hidden")
+			logger.Log(rift.LevelWarn, "job failed", rift.Field{Key: "job", Value: "abc"})
+
+			Expect(buf.String()).To(Equal("WARN job failed job=abc\n"))
+		})
 	})
 })
@@ -1,6 +1,32 @@
 package rift
 
-import "github.com/bmartel/rift/summary"
+import (
+	"sync/atomic"
+
+	"github.com/bmartel/rift/summary"
+)
+
+// counters mirrors the job counts of the stats so they can be read without
+// taking the stats lock
+type counters struct {
+	active    uint32
+	queued    uint32
+	processed uint32
+	deferred  uint32
+	failed    uint32
+	requeued  uint32
+	cancelled uint32
+}
+
+func (c *counters) store(s *summary.Stats) {
+	atomic.StoreUint32(&c.active, s.ActiveJobs)
+	atomic.StoreUint32(&c.queued, s.QueuedJobs)
+	atomic.StoreUint32(&c.processed, s.ProcessedJobs)
+	atomic.StoreUint32(&c.deferred, s.DeferredJobs)
+	atomic.StoreUint32(&c.failed, s.FailedJobs)
+	atomic.StoreUint32(&c.requeued, s.RequeuedJobs)
+	atomic.StoreUint32(&c.cancelled, s.CancelledJobs)
+}
 
 func updateJob(s *summary.Stats, job *summary.Job) {
 	s.Jobs[job.Id] = job
@@ -30,5 +56,11 @@ func updateJob(s *summary.Stats, job *summary.Job) {
 			s.ActiveJobs--
 		}
 		s.RequeuedJobs++
+	case "cancelled":
+		// only jobs cancelled while running were active
+		if job.StartedAt > 0 && s.ActiveJobs > 0 {
+			s.ActiveJobs--
+		}
+		s.CancelledJobs++
 	}
 }
@@ -0,0 +1,153 @@
+package rift
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+)
+
+const (
+	defaultMaxJobs      = 10000
+	defaultMaxAge       = time.Hour
+	defaultFailedMaxAge = 24 * time.Hour
+)
+
+// Retention bounds how much job history is held in the stats
+type Retention struct {
+	// MaxJobs is the most jobs held, the least recently updated finished jobs
+	// are evicted first
+	MaxJobs int
+	// MaxAge is how long a finished job is held after its last update
+	MaxAge time.Duration
+	// FailedMaxAge is how long a failed job is held after its last update, so
+	// failures can be kept around longer than successes
+	FailedMaxAge time.Duration
+}
+
+// finished reports whether a job status is no longer going to change
+func finished(status string) bool {
+	switch status {
+	case "processed", "failed", "deferred", "cancelled":
+		return true
+	}
+	return false
+}
+
+// history tracks the order jobs were last updated in, evicting them from
+// the stats according to the retention policy. Jobs are kept in separate
+// lists by status so the next job to evict is always at the front of one.
+type history struct {
+	retention Retention
+	active    *list.List
+	succeeded *list.List
+	failed    *list.List
+	entries   map[string]*entry
+	evicted   []string
+}
+
+// entry is the position of a job in the list for its status
+type entry struct {
+	list    *list.List
+	element *list.Element
+}
+
+func newHistory(retention Retention) *history {
+	if retention.MaxJobs < 1 {
+		retention.MaxJobs = defaultMaxJobs
+	}
+	if retention.MaxAge <= 0 {
+		retention.MaxAge = defaultMaxAge
+	}
+	if retention.FailedMaxAge <= 0 {
+		retention.FailedMaxAge = defaultFailedMaxAge
+	}
+	return &history{
+		retention: retention,
+		active:    list.New(),
+		succeeded: list.New(),
+		failed:    list.New(),
+		entries:   make(map[string]*entry, 0),
+		evicted:   make([]string, 0),
+	}
+}
+
+// listFor is the list holding jobs of the given status
+func (h *history) listFor(status string) *list.List {
+	switch {
+	case status == "failed":
+		return h.failed
+	case finished(status):
+		return h.succeeded
+	}
+	return h.active
+}
+
+// touch marks the job as the most recently updated, evicting the oldest
+// jobs if the history has grown too large
+func (h *history) touch(s *summary.Stats, job *summary.Job) {
+	l := h.listFor(job.Status)
+	if e, ok := h.entries[job.Id]; ok {
+		e.list.Remove(e.element)
+		e.list = l
+		e.element = l.PushBack(job.Id)
+	} else {
+		h.entries[job.Id] = &entry{list: l, element: l.PushBack(job.Id)}
+	}
+
+	for len(h.entries) > h.retention.MaxJobs {
+		h.evictOldest(s)
+	}
+}
+
+// expire evicts every finished job which has outlived its retention. Each
+// list is in update order, so only the expired jobs are visited.
+func (h *history) expire(s *summary.Stats, now time.Time) {
+	h.expireList(s, h.succeeded, now.Add(-h.retention.MaxAge))
+	h.expireList(s, h.failed, now.Add(-h.retention.FailedMaxAge))
+}
+
+func (h *history) expireList(s *summary.Stats, l *list.List, before time.Time) {
+	for el := l.Front(); el != nil; el = l.Front() {
+		if job, ok := s.Jobs[el.Value.(string)]; ok && !time.Unix(0, job.UpdatedAt).Before(before) {
+			return
+		}
+		h.evict(s, el.Value.(string))
+	}
+}
+
+// evictOldest removes the least recently updated finished job, preferring
+// successes over failures, falling back to the oldest job still in progress
+func (h *history) evictOldest(s *summary.Stats) {
+	for _, l := range []*list.List{h.succeeded, h.failed, h.active} {
+		if el := l.Front(); el != nil {
+			h.evict(s, el.Value.(string))
+			return
+		}
+	}
+}
+
+func (h *history) evict(s *summary.Stats, id string) {
+	e := h.entries[id]
+	e.list.Remove(e.element)
+	delete(h.entries, id)
+	delete(s.Jobs, id)
+	h.evicted = append(h.evicted, id)
+}
+
+// remove evicts a job regardless of the retention policy, reporting whether
+// it was held
+func (h *history) remove(s *summary.Stats, id string) bool {
+	_, ok := h.entries[id]
+	if ok {
+		h.evict(s, id)
+	}
+	return ok
+}
+
+// drainEvicted returns the ids evicted since the last drain
+func (h *history) drainEvicted() []string {
+	evicted := h.evicted
+	h.evicted = make([]string, 0)
+	return evicted
+}
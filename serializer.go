@@ -1,16 +1,26 @@
 package rift
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bmartel/rift/summary"
+	"github.com/bmartel/rift/trace"
 	"github.com/fatih/structs"
+	"github.com/satori/go.uuid"
 )
 
 // Serializer deconstructs a job and its values
 type Serializer struct {
 	Job Job `json:"-"`
+	// Fields are the kinds of the job's fields by their serialized name
+	Fields map[string]string `json:"fields"`
 }
 
 // NewRegistry creates a registry to store serializers
@@ -46,18 +56,14 @@ func (r *Registry) SerializeJob(job Job, stats *summary.Stats) {
 	data := structs.New(job)
 
 	for _, field := range data.Fields() {
-		tags := strings.Split(field.Tag("json"), ",")
-		if tag := tags[0]; tag != "" {
-			blueprint.Fields[tag] = field.Kind().String()
-		} else {
-			blueprint.Fields[strings.ToLower(field.Name())] = field.Kind().String()
-		}
+		blueprint.Fields[fieldName(field)] = field.Kind().String()
 	}
 	stats.JobBlueprints = append(stats.JobBlueprints, blueprint)
 
 	r.mutex.Lock()
 	r.serializers[job.Tag()] = &Serializer{
-		Job: job,
+		Job:    job,
+		Fields: blueprint.Fields,
 	}
 	r.mutex.Unlock()
 }
@@ -66,9 +72,224 @@ func (r *Registry) SerializeJob(job Job, stats *summary.Stats) {
 func (r *Registry) DeserializeJob(jobType string, data map[string]interface{}) Job {
 
 	// lookup the incoming job type
-	if serializer, ok := r.serializers[jobType]; ok {
+	r.mutex.RLock()
+	serializer, ok := r.serializers[jobType]
+	r.mutex.RUnlock()
+
+	if ok {
 		return serializer.Job.Deserialize(data)
 	}
 
 	return nil
 }
+
+// DecodeJob creates a job from a JSON object of its field values, as sent by
+// remote callers. Values are converted to the kinds of the job's blueprint so
+// the job receives the integer or float type it declares, and fields left out
+// are given their zero value.
+func (r *Registry) DecodeJob(jobType string, data []byte) (job Job, err error) {
+	r.mutex.RLock()
+	serializer, ok := r.serializers[jobType]
+	r.mutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no job serializer could be found for %s", jobType)
+	}
+
+	values := make(map[string]interface{}, 0)
+	if len(bytes.TrimSpace(data)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, fmt.Errorf("invalid data for %s: %v", jobType, err)
+		}
+	}
+
+	for name, value := range values {
+		kind, ok := serializer.Fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%s has no field %s", jobType, name)
+		}
+		if values[name], err = convertField(kind, value); err != nil {
+			return nil, fmt.Errorf("invalid %s field %s: %v", jobType, name, err)
+		}
+	}
+	for name, kind := range serializer.Fields {
+		if _, ok := values[name]; !ok {
+			values[name], _ = convertField(kind, nil)
+		}
+	}
+
+	// the job's own deserialization is not trusted with remote values
+	defer func() {
+		if p := recover(); p != nil {
+			job, err = nil, fmt.Errorf("could not deserialize %s: %v", jobType, p)
+		}
+	}()
+
+	if job = serializer.Job.Deserialize(values); job == nil {
+		return nil, fmt.Errorf("could not deserialize %s", jobType)
+	}
+	return job, nil
+}
+
+// fieldKinds are the types of the field kinds values can be converted to
+var fieldKinds = map[string]reflect.Type{
+	"bool":    reflect.TypeOf(false),
+	"int":     reflect.TypeOf(int(0)),
+	"int8":    reflect.TypeOf(int8(0)),
+	"int16":   reflect.TypeOf(int16(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint":    reflect.TypeOf(uint(0)),
+	"uint8":   reflect.TypeOf(uint8(0)),
+	"uint16":  reflect.TypeOf(uint16(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+	"string":  reflect.TypeOf(""),
+}
+
+// convertField converts a decoded JSON value to a field kind, numbers and
+// booleans may also be given as strings. Values of other kinds are passed
+// through as decoded.
+func convertField(kind string, value interface{}) (interface{}, error) {
+	t, ok := fieldKinds[kind]
+	if !ok {
+		if n, isNumber := value.(json.Number); isNumber {
+			return n.Float64()
+		}
+		return value, nil
+	}
+	if value == nil {
+		return reflect.Zero(t).Interface(), nil
+	}
+
+	var text string
+	switch v := value.(type) {
+	case json.Number:
+		text = string(v)
+	case string:
+		text = v
+	case bool:
+		text = strconv.FormatBool(v)
+	default:
+		return nil, fmt.Errorf("expected %s", kind)
+	}
+
+	converted := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("expected bool, got %q", text)
+		}
+		converted.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected %s, got %q", kind, text)
+		}
+		converted.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected %s, got %q", kind, text)
+		}
+		converted.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected %s, got %q", kind, text)
+		}
+		converted.SetFloat(n)
+	case reflect.String:
+		converted.SetString(text)
+	}
+	return converted.Interface(), nil
+}
+
+// fieldName is the key a job field is serialized under, its json tag name or
+// otherwise its lowercased field name
+func fieldName(field *structs.Field) string {
+	tags := strings.Split(field.Tag("json"), ",")
+	if tag := tags[0]; tag != "" {
+		return tag
+	}
+	return strings.ToLower(field.Name())
+}
+
+// Payload is the serialized form of a reserved job, for storing jobs outside
+// of the queue and restoring them later
+type Payload struct {
+	ID          string                 `json:"id"`
+	Tag         string                 `json:"tag"`
+	Data        map[string]interface{} `json:"data"`
+	RequestedAt int64                  `json:"requested_at"`
+	Retry       uint8                  `json:"retry"`
+	Requeued    uint8                  `json:"requeued"`
+	Traceparent string                 `json:"traceparent,omitempty"`
+}
+
+// SerializePayload deconstructs a reserved job, keying its values the same way
+// as its blueprint
+func (r *Registry) SerializePayload(job ReservedJob) Payload {
+	data := make(map[string]interface{}, 0)
+	for _, field := range structs.New(job.Job).Fields() {
+		data[fieldName(field)] = field.Value()
+	}
+
+	return Payload{
+		ID:          job.ID.String(),
+		Tag:         job.Job.Tag(),
+		Data:        data,
+		RequestedAt: job.RequestedAt.UnixNano(),
+		Retry:       job.Retry,
+		Requeued:    job.Requeued,
+		Traceparent: job.Trace.Traceparent(),
+	}
+}
+
+// encodePayload is the JSON object of the values of a job, as reported in the
+// job details
+func (r *Registry) encodePayload(job ReservedJob) string {
+	data, err := json.Marshal(r.SerializePayload(job).Data)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// DeserializePayload restores a reserved job from its payload, the job tag
+// must have been registered
+func (r *Registry) DeserializePayload(payload Payload) (ReservedJob, error) {
+	var reserved ReservedJob
+
+	id, err := uuid.FromString(payload.ID)
+	if err != nil {
+		return reserved, fmt.Errorf("invalid job id %s: %v", payload.ID, err)
+	}
+
+	var sc trace.SpanContext
+	if payload.Traceparent != "" {
+		if sc, err = trace.ParseTraceparent(payload.Traceparent); err != nil {
+			return reserved, err
+		}
+	}
+
+	job := r.DeserializeJob(payload.Tag, payload.Data)
+	if job == nil {
+		return reserved, fmt.Errorf("no job serializer could be found for %s", payload.Tag)
+	}
+
+	reserved = ReservedJob{
+		ID:          id,
+		Job:         job,
+		RequestedAt: time.Unix(0, payload.RequestedAt),
+		Retry:       payload.Retry,
+		Requeued:    payload.Requeued,
+		Trace:       sc,
+	}
+	return reserved, nil
+}
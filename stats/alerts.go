@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// the kinds of alert rules
+const (
+	ruleFailureRate = "failure_rate"
+	ruleDepth       = "depth"
+	ruleLatency     = "latency"
+	ruleOffline     = "offline"
+)
+
+const (
+	alertFiring   = "firing"
+	alertResolved = "resolved"
+
+	// defaultRuleWindow is the window of a rule which doesn't set one, apart
+	// from offline rules which alert as soon as an instance goes offline
+	defaultRuleWindow = 5 * time.Minute
+	webhookTimeout    = 10 * time.Second
+
+	// webhookQueue is the number of alerts waiting for delivery before new
+	// alerts are dropped
+	webhookQueue = 256
+	// webhookAttempts is how many times an alert is posted before giving up
+	webhookAttempts = 5
+	// webhookBackoff is the delay before the first retry, doubling with
+	// every failed attempt after
+	webhookBackoff = time.Second
+)
+
+// alertRule raises an alert for the app it names, or every app, when the
+// measure of its kind goes past the threshold over the window back from now:
+//
+//	failure_rate  the share of finished attempts which failed, 0 to 1
+//	depth         the number of jobs waiting to run at the end of the window
+//	latency       the mean seconds from request to finish of a job
+//	offline       a queue instance has been offline for the window
+type alertRule struct {
+	Name      string  `json:"name"`
+	Kind      string  `json:"kind"`
+	App       string  `json:"app,omitempty"`
+	Tag       string  `json:"tag,omitempty"`
+	Threshold float64 `json:"threshold"`
+	// Window is a duration such as 5m
+	Window string `json:"window,omitempty"`
+	// MinJobs is the least attempts which have to finish within the window
+	// for a failure rate or latency to count
+	MinJobs uint64 `json:"min_jobs,omitempty"`
+
+	window time.Duration
+}
+
+// loadRules reads a JSON list of alert rules
+func loadRules(path string) ([]*alertRule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []*alertRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+func (r *alertRule) validate() error {
+	switch r.Kind {
+	case ruleFailureRate, ruleDepth, ruleLatency, ruleOffline:
+	default:
+		return fmt.Errorf("alert rule %q: unknown kind %q", r.Name, r.Kind)
+	}
+	if r.Name == "" {
+		r.Name = r.Kind
+	}
+	if r.Window != "" {
+		window, err := time.ParseDuration(r.Window)
+		if err != nil {
+			return fmt.Errorf("alert rule %q: %v", r.Name, err)
+		}
+		r.window = window
+	}
+	if r.window <= 0 && r.Kind != ruleOffline {
+		r.window = defaultRuleWindow
+	}
+	if r.MinJobs == 0 {
+		r.MinJobs = 1
+	}
+	return nil
+}
+
+// alert is a rule going past its threshold for an app, or coming back under
+// it. Offline alerts are raised per queue instance.
+type alert struct {
+	Message   string  `json:"message"`
+	ID        string  `json:"id"`
+	Rule      string  `json:"rule"`
+	Kind      string  `json:"kind"`
+	App       string  `json:"app"`
+	Tag       string  `json:"tag,omitempty"`
+	QueueID   string  `json:"queue_id,omitempty"`
+	State     string  `json:"state"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	// Since is when the alert started firing
+	Since int64 `json:"since"`
+	At    int64 `json:"at"`
+}
+
+// alerter evaluates the alert rules against the aggregated stats and history,
+// keeping the alerts which are firing
+type alerter struct {
+	rules   []*alertRule
+	state   *state
+	history *history
+	webhook string
+	client  *http.Client
+	now     func() time.Time
+	// deliveries are posted to the webhook away from the rule evaluation
+	deliveries chan *alert
+	backoff    time.Duration
+
+	mutex  sync.Mutex
+	firing map[string]*alert
+}
+
+func newAlerter(rules []*alertRule, st *state, hist *history, webhook string) *alerter {
+	return &alerter{
+		rules:   rules,
+		state:   st,
+		history: hist,
+		webhook: webhook,
+		client:  &http.Client{Timeout: webhookTimeout},
+		now:     time.Now,
+		firing:  make(map[string]*alert, 0),
+
+		deliveries: make(chan *alert, webhookQueue),
+		backoff:    webhookBackoff,
+	}
+}
+
+// evaluate checks every rule, returning the alerts which started firing or
+// resolved since the last evaluation
+func (a *alerter) evaluate() []*alert {
+	now := a.now()
+	current := make(map[string]*alert, 0)
+	for _, rule := range a.rules {
+		for _, app := range a.apps(rule) {
+			for _, al := range a.check(rule, app, now) {
+				current[al.ID] = al
+			}
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	changed := make([]*alert, 0)
+	for id, al := range current {
+		if _, ok := a.firing[id]; ok {
+			a.firing[id].Value = al.Value
+			a.firing[id].At = al.At
+			continue
+		}
+		a.firing[id] = al
+		fired := *al
+		changed = append(changed, &fired)
+	}
+	for id, al := range a.firing {
+		if _, ok := current[id]; ok {
+			continue
+		}
+		delete(a.firing, id)
+		resolved := *al
+		resolved.State = alertResolved
+		resolved.At = now.UnixNano()
+		changed = append(changed, &resolved)
+	}
+	sort.Sort(byAlertID(changed))
+	return changed
+}
+
+// apps are the apps a rule applies to
+func (a *alerter) apps(rule *alertRule) []string {
+	if rule.App != "" {
+		return []string{rule.App}
+	}
+	summaries := a.state.summaries()
+	apps := make([]string, 0, len(summaries))
+	for _, s := range summaries {
+		apps = append(apps, s.App)
+	}
+	return apps
+}
+
+// check returns the alerts a rule raises for an app
+func (a *alerter) check(rule *alertRule, app string, now time.Time) []*alert {
+	raise := func(queueID string, value float64) *alert {
+		id := rule.Name + "/" + app + "/" + rule.Tag
+		if queueID != "" {
+			id += "/" + queueID
+		}
+		return &alert{
+			Message:   "alert",
+			ID:        id,
+			Rule:      rule.Name,
+			Kind:      rule.Kind,
+			App:       app,
+			Tag:       rule.Tag,
+			QueueID:   queueID,
+			State:     alertFiring,
+			Value:     value,
+			Threshold: rule.Threshold,
+			Since:     now.UnixNano(),
+			At:        now.UnixNano(),
+		}
+	}
+
+	alerts := make([]*alert, 0)
+	if rule.Kind == ruleOffline {
+		update := a.state.update(app)
+		if update == nil {
+			return alerts
+		}
+		for id, instance := range update.Instances {
+			offline := now.Sub(time.Unix(0, instance.LastSeen))
+			if instance.Status == instanceOffline && offline >= rule.window {
+				alerts = append(alerts, raise(id, offline.Seconds()))
+			}
+		}
+		return alerts
+	}
+
+	p := a.history.window(historyFilter{App: app, Tag: rule.Tag}, now.Add(-rule.window), now)
+	finished := p.Processed + p.Failed
+	var value float64
+	switch rule.Kind {
+	case ruleFailureRate:
+		if finished < rule.MinJobs {
+			return alerts
+		}
+		value = p.FailureRate
+	case ruleLatency:
+		if finished < rule.MinJobs {
+			return alerts
+		}
+		value = p.Latency
+	case ruleDepth:
+		value = float64(p.Depth)
+	}
+	if value > rule.Threshold {
+		alerts = append(alerts, raise("", value))
+	}
+	return alerts
+}
+
+// active lists the alerts which are firing
+func (a *alerter) active() []*alert {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	alerts := make([]*alert, 0, len(a.firing))
+	for _, al := range a.firing {
+		copied := *al
+		alerts = append(alerts, &copied)
+	}
+	sort.Sort(byAlertID(alerts))
+	return alerts
+}
+
+// notify posts an alert to the webhook, if one is configured
+func (a *alerter) notify(al *alert) error {
+	if a.webhook == "" {
+		return nil
+	}
+	body, err := json.Marshal(al)
+	if err != nil {
+		return err
+	}
+	res, err := a.client.Post(a.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+// queue hands an alert to the webhook delivery, dropping it if too many
+// alerts are already waiting
+func (a *alerter) queue(al *alert) {
+	if a.webhook == "" {
+		return
+	}
+	select {
+	case a.deliveries <- al:
+	default:
+		log.Printf("Alert Error: delivery queue full, dropped %s %s\n", al.ID, al.State)
+	}
+}
+
+// deliver posts the queued alerts to the webhook in order, retrying each
+// with an exponential backoff, until the done channel closes
+func (a *alerter) deliver(done chan bool) {
+	for {
+		select {
+		case al := <-a.deliveries:
+			a.post(al, done)
+		case <-done:
+			return
+		}
+	}
+}
+
+// post sends an alert to the webhook, giving up after webhookAttempts or
+// once the done channel closes
+func (a *alerter) post(al *alert, done chan bool) {
+	delay := a.backoff
+	for attempt := 1; ; attempt++ {
+		err := a.notify(al)
+		if err == nil {
+			return
+		}
+		if attempt == webhookAttempts {
+			log.Printf("Alert Error: giving up on %s %s after %d attempts: %v\n", al.ID, al.State, attempt, err)
+			return
+		}
+		log.Printf("Alert Error: %v, retrying in %s\n", err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-done:
+			return
+		}
+		delay *= 2
+	}
+}
+
+// alertStream evaluates the rules on an interval, sending the alerts which
+// fired or resolved to the webhook and to dashboard clients until the done
+// channel closes. The webhook is posted to from its own goroutine so a slow
+// sink doesn't hold up the evaluation.
+func alertStream(a *alerter, clients *hub, events *events, interval time.Duration, done chan bool) {
+	go a.deliver(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, al := range a.evaluate() {
+				clients.broadcast(al)
+				events.publish(al)
+				a.queue(al)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+type byAlertID []*alert
+
+func (a byAlertID) Len() int           { return len(a) }
+func (a byAlertID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byAlertID) Less(i, j int) bool { return a[i].ID < a[j].ID }
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// webhook is a local stand-in for an alert sink, recording what is posted
+type webhook struct {
+	mutex  sync.Mutex
+	alerts []*alert
+}
+
+func (h *webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var al alert
+	if err := json.NewDecoder(r.Body).Decode(&al); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.mutex.Lock()
+	h.alerts = append(h.alerts, &al)
+	h.mutex.Unlock()
+}
+
+func (h *webhook) received() []*alert {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]*alert{}, h.alerts...)
+}
+
+var _ = Describe("Alerts", func() {
+	var (
+		st    *state
+		hist  *history
+		now   time.Time
+		sink  *webhook
+		hook  *httptest.Server
+		rules []*alertRule
+	)
+
+	job := func(id, status string, at time.Time) *summary.Job {
+		return &summary.Job{Id: id, Tag: "SampleJob", Status: status, RequestedAt: at.Add(-time.Second).UnixNano(), UpdatedAt: at.UnixNano()}
+	}
+
+	rule := func(r *alertRule) *alertRule {
+		Expect(r.validate()).To(Succeed())
+		return r
+	}
+
+	alerter := func() *alerter {
+		a := newAlerter(rules, st, hist, hook.URL)
+		a.now = func() time.Time { return now }
+		return a
+	}
+
+	BeforeEach(func() {
+		var err error
+		st = newState()
+		hist, err = openHistory(historyOptions{Raw: time.Hour, Retention: time.Hour, Resolution: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		now = time.Now()
+		sink = &webhook{}
+		hook = httptest.NewServer(sink)
+		rules = nil
+	})
+
+	AfterEach(func() {
+		hook.Close()
+	})
+
+	It("should fire and resolve on the failure rate over the window", func() {
+		rules = []*alertRule{rule(&alertRule{Name: "failures", Kind: ruleFailureRate, App: "Test", Threshold: 0.5, Window: "5m", MinJobs: 2})}
+		a := alerter()
+
+		hist.record([]*summary.Job{
+			job("1", "processed", now.Add(-4*time.Minute)),
+			job("2", "failed", now.Add(-3*time.Minute)),
+			job("3", "failed", now.Add(-2*time.Minute)),
+		}, queueStats("a", 1))
+
+		changed := a.evaluate()
+		Expect(changed).To(HaveLen(1))
+		Expect(changed[0].State).To(Equal(alertFiring))
+		Expect(changed[0].Value).To(BeNumerically("~", 2.0/3, 0.0001))
+		Expect(a.notify(changed[0])).To(Succeed())
+		Expect(sink.received()).To(HaveLen(1))
+		Expect(sink.received()[0].Rule).To(Equal("failures"))
+
+		// a firing alert is only reported once
+		Expect(a.evaluate()).To(BeEmpty())
+		Expect(a.active()).To(HaveLen(1))
+
+		now = now.Add(10 * time.Minute)
+		changed = a.evaluate()
+		Expect(changed).To(HaveLen(1))
+		Expect(changed[0].State).To(Equal(alertResolved))
+		Expect(a.active()).To(BeEmpty())
+	})
+
+	It("should wait for enough finished jobs before judging the failure rate", func() {
+		rules = []*alertRule{rule(&alertRule{Kind: ruleFailureRate, Threshold: 0.1, MinJobs: 5})}
+		st.apply(queueStats("a", 1))
+		hist.record([]*summary.Job{job("1", "failed", now.Add(-time.Minute))}, queueStats("a", 1))
+
+		Expect(alerter().evaluate()).To(BeEmpty())
+	})
+
+	It("should fire on queue depth and latency", func() {
+		rules = []*alertRule{
+			rule(&alertRule{Name: "backlog", Kind: ruleDepth, Threshold: 1}),
+			rule(&alertRule{Name: "slow", Kind: ruleLatency, Tag: "SampleJob", Threshold: 0.5}),
+		}
+		st.apply(queueStats("a", 1))
+		hist.record([]*summary.Job{
+			job("1", "queued", now.Add(-3*time.Minute)),
+			job("2", "queued", now.Add(-3*time.Minute)),
+			job("3", "queued", now.Add(-3*time.Minute)),
+			job("4", "processed", now.Add(-2*time.Minute)),
+		}, queueStats("a", 1))
+
+		changed := alerter().evaluate()
+		Expect(changed).To(HaveLen(2))
+		Expect(changed[0].Rule).To(Equal("backlog"))
+		Expect(changed[0].Value).To(Equal(3.0))
+		Expect(changed[1].Rule).To(Equal("slow"))
+		Expect(changed[1].Value).To(BeNumerically("~", 1.0, 0.0001))
+	})
+
+	It("should fire for instances offline longer than the window", func() {
+		rules = []*alertRule{rule(&alertRule{Name: "offline", Kind: ruleOffline, Window: "1m"})}
+		st.now = func() time.Time { return now }
+		st.apply(queueStats("a", 1))
+		st.apply(queueStats("b", 1))
+
+		now = now.Add(time.Minute)
+		st.heartbeat(&summary.Heartbeat{App: "Test", QueueId: "b"})
+		Expect(st.sweep(30 * time.Second)).To(ConsistOf("Test"))
+
+		changed := alerter().evaluate()
+		Expect(changed).To(HaveLen(1))
+		Expect(changed[0].QueueID).To(Equal("a"))
+		Expect(changed[0].Value).To(Equal(60.0))
+	})
+
+	It("should deliver alerts to the webhook and dashboard clients", func(done Done) {
+		rules = []*alertRule{rule(&alertRule{Kind: ruleDepth, Threshold: 0})}
+		st.apply(queueStats("a", 1))
+		hist.record([]*summary.Job{job("1", "queued", now.Add(-time.Minute))}, queueStats("a", 1))
+
+		ev := newEvents(st)
+		stream := ev.subscribe()
+		stop := make(chan bool)
+		defer close(stop)
+		// the stream may still be evaluating as the next spec starts
+		a, at := alerter(), now
+		a.now = func() time.Time { return at }
+		go alertStream(a, newHub(), ev, 10*time.Millisecond, stop)
+
+		var published interface{}
+		Eventually(stream).Should(Receive(&published))
+		Expect(published.(*alert).Kind).To(Equal(ruleDepth))
+		Eventually(sink.received).Should(HaveLen(1))
+
+		close(done)
+	}, 3)
+
+	It("should report webhook failures", func() {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer failing.Close()
+
+		a := newAlerter(nil, st, hist, failing.URL)
+		Expect(a.notify(&alert{ID: "test"})).ToNot(Succeed())
+		Expect(newAlerter(nil, st, hist, "").notify(&alert{ID: "test"})).To(Succeed())
+	})
+
+	It("should retry webhook deliveries with a backoff", func(done Done) {
+		var attempts int32
+		flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			sink.ServeHTTP(w, r)
+		}))
+		defer flaky.Close()
+
+		stop := make(chan bool)
+		defer close(stop)
+		a := newAlerter(nil, st, hist, flaky.URL)
+		a.backoff = 10 * time.Millisecond
+		go a.deliver(stop)
+
+		a.queue(&alert{ID: "test", State: alertFiring})
+		Eventually(sink.received).Should(HaveLen(1))
+		Expect(sink.received()[0].ID).To(Equal("test"))
+		Expect(atomic.LoadInt32(&attempts)).To(Equal(int32(3)))
+
+		close(done)
+	}, 3)
+
+	It("should drop alerts past the delivery queue rather than block", func() {
+		// nothing is delivering, as if the webhook was stuck on an alert
+		a := newAlerter(nil, st, hist, hook.URL)
+		for i := 0; i < webhookQueue*2; i++ {
+			a.queue(&alert{ID: "test", State: alertFiring})
+		}
+		Expect(a.deliveries).To(HaveLen(webhookQueue))
+		Expect(sink.received()).To(BeEmpty())
+	})
+
+	It("should list the firing alerts over http", func() {
+		rules = []*alertRule{rule(&alertRule{Kind: ruleDepth, Threshold: 0})}
+		st.apply(queueStats("a", 1))
+		hist.record([]*summary.Job{job("1", "queued", now.Add(-time.Minute))}, queueStats("a", 1))
+		a := alerter()
+		a.evaluate()
+
+		rec := httptest.NewRecorder()
+		newAPI(st, hist, nil, a).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/alerts", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var alerts []*alert
+		Expect(json.Unmarshal(rec.Body.Bytes(), &alerts)).To(Succeed())
+		Expect(alerts).To(HaveLen(1))
+		Expect(alerts[0].ID).To(Equal("depth/Test/"))
+	})
+
+	It("should load rules from a file", func() {
+		file, err := ioutil.TempFile("", "rift-rules")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.Remove(file.Name())
+
+		Expect(ioutil.WriteFile(file.Name(), []byte(`[{"kind": "latency", "threshold": 2, "window": "10m"}, {"kind": "offline"}]`), 0644)).To(Succeed())
+		loaded, err := loadRules(file.Name())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded).To(HaveLen(2))
+		Expect(loaded[0].Name).To(Equal(ruleLatency))
+		Expect(loaded[0].window).To(Equal(10 * time.Minute))
+		Expect(loaded[1].window).To(Equal(time.Duration(0)))
+
+		Expect(ioutil.WriteFile(file.Name(), []byte(`[{"kind": "cpu"}]`), 0644)).To(Succeed())
+		_, err = loadRules(file.Name())
+		Expect(err).To(MatchError(ContainSubstring("unknown kind")))
+	})
+})
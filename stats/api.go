@@ -0,0 +1,592 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiPrefix       = "/api/v1"
+	defaultJobLimit = 50
+	maxJobLimit     = 1000
+)
+
+// param is a query parameter accepted by a route
+type param struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// route is an endpoint of the api. The OpenAPI description is generated from
+// the routes so it can't drift from what is served.
+type route struct {
+	Method string
+	// Pattern is the path below the api prefix, segments in braces are path
+	// parameters
+	Pattern string
+	Summary string
+	Query   []param
+	// Request is a value of the type of the request body, if any
+	Request interface{}
+	// Response is a value of the type the route responds with
+	Response interface{}
+	// Status is the status of a successful response, 200 unless set
+	Status int
+	// Errors are the error statuses the route responds with, apart from
+	// invalid query parameters and missing path resources
+	Errors []int
+
+	handle func(w http.ResponseWriter, r *http.Request, params map[string]string)
+}
+
+// match reads the path parameters of a request path, ok is false if the path
+// doesn't match the pattern
+func (rt *route) match(path string) (map[string]string, bool) {
+	pattern := strings.Split(strings.Trim(rt.Pattern, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string, 0)
+	for i, segment := range pattern {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// jobPage is one page of the jobs matching a query
+type jobPage struct {
+	Total  int         `json:"total"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+	Jobs   []*queueJob `json:"jobs"`
+}
+
+// jobRequest creates a job on a queue of the app, the data holds the job's
+// field values keyed as in its blueprint
+type jobRequest struct {
+	Tag     string          `json:"tag"`
+	QueueID string          `json:"queue_id,omitempty"`
+	Data    json.RawMessage `json:"data"`
+	Retry   uint32          `json:"retry"`
+}
+
+// jobsReply lists the jobs of a queue instance a command applied to
+type jobsReply struct {
+	QueueID string   `json:"queue_id"`
+	JobIDs  []string `json:"job_ids"`
+}
+
+// api serves the versioned HTTP endpoints for querying the monitoring data
+// and creating jobs
+type api struct {
+	state    *state
+	history  *history
+	commands *router
+	alerts   *alerter
+	routes   []*route
+}
+
+var historyParams = []param{
+	{"app", "string", "Only the updates of this app"},
+	{"queue", "string", "Only the updates of this queue instance"},
+	{"tag", "string", "Only the updates of jobs with this tag"},
+	{"from", "date-time", "The start of the range, defaults to window before to"},
+	{"to", "date-time", "The end of the range, defaults to now"},
+	{"window", "duration", "The length of the range when from is not given, defaults to 1h"},
+}
+
+func newAPI(st *state, hist *history, commands *router, alerts *alerter) *api {
+	a := &api{state: st, history: hist, commands: commands, alerts: alerts}
+	a.routes = []*route{
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/apps",
+			Summary:  "List the apps reporting to the server",
+			Response: []*appSummary{},
+			handle:   a.listApps,
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/apps/{app}",
+			Summary:  "Get the state of an app",
+			Response: &appSnapshot{},
+			handle:   a.getApp,
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/apps/{app}/queues",
+			Summary:  "List the queue instances of an app, without their jobs",
+			Response: []*summary.Stats{},
+			handle:   a.listQueues,
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/apps/{app}/queues/{queue}",
+			Summary:  "Get the stats of a queue instance along with its jobs",
+			Response: &summary.Stats{},
+			handle:   a.getQueue,
+		},
+		{
+			Method:  http.MethodGet,
+			Pattern: "/apps/{app}/jobs",
+			Summary: "List the jobs of an app, the most recently updated first",
+			Query: []param{
+				{"queue", "string", "Only the jobs of this queue instance"},
+				{"status", "string", "Only the jobs with this status"},
+				{"tag", "string", "Only the jobs with this tag"},
+				{"offset", "integer", "The number of jobs to skip"},
+				{"limit", "integer", fmt.Sprintf("The most jobs to list, defaults to %d and at most %d", defaultJobLimit, maxJobLimit)},
+			},
+			Response: &jobPage{},
+			handle:   a.listJobs,
+		},
+		{
+			Method:   http.MethodPost,
+			Pattern:  "/apps/{app}/jobs",
+			Summary:  "Create a job on a queue of the app subscribed to commands",
+			Request:  &jobRequest{},
+			Response: &summary.EnqueueReply{},
+			Status:   http.StatusCreated,
+			Errors:   []int{http.StatusBadRequest, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+			handle:   a.createJob,
+		},
+		{
+			Method:   http.MethodDelete,
+			Pattern:  "/apps/{app}/jobs",
+			Summary:  "Purge the finished jobs from every queue of the app subscribed to commands",
+			Query:    []param{{"status", "string", "Only the finished jobs with this status"}},
+			Response: map[string][]string{},
+			Errors:   []int{http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+			handle:   a.purgeJobs,
+		},
+		{
+			Method:   http.MethodDelete,
+			Pattern:  "/apps/{app}/queues/{queue}/jobs",
+			Summary:  "Purge the finished jobs of a queue instance",
+			Query:    []param{{"status", "string", "Only the finished jobs with this status"}},
+			Response: &jobsReply{},
+			Errors:   []int{http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+			handle: a.jobCommand(func(params map[string]string, r *http.Request) *summary.Command {
+				return &summary.Command{Purge: &summary.JobCommand{Status: r.URL.Query().Get("status")}}
+			}),
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/apps/{app}/queues/{queue}/jobs/{job}",
+			Summary:  "Get a job of a queue instance with its payload and every attempt",
+			Response: &queueJob{},
+			handle:   a.getJob,
+		},
+		{
+			Method:   http.MethodDelete,
+			Pattern:  "/apps/{app}/queues/{queue}/jobs/{job}",
+			Summary:  "Purge a finished job of a queue instance",
+			Response: &jobsReply{},
+			Errors:   []int{http.StatusConflict, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+			handle: a.jobCommand(func(params map[string]string, r *http.Request) *summary.Command {
+				return &summary.Command{Purge: &summary.JobCommand{JobId: params["job"]}}
+			}),
+		},
+		{
+			Method:   http.MethodPost,
+			Pattern:  "/apps/{app}/queues/{queue}/jobs/{job}/retry",
+			Summary:  "Queue a job again which failed after running out of retries",
+			Response: &jobsReply{},
+			Errors:   []int{http.StatusConflict, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+			handle: a.jobCommand(func(params map[string]string, r *http.Request) *summary.Command {
+				return &summary.Command{Retry: &summary.JobCommand{JobId: params["job"]}}
+			}),
+		},
+		{
+			Method:   http.MethodPost,
+			Pattern:  "/apps/{app}/queues/{queue}/jobs/{job}/cancel",
+			Summary:  "Cancel a queued or running job",
+			Response: &jobsReply{},
+			Errors:   []int{http.StatusConflict, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+			handle: a.jobCommand(func(params map[string]string, r *http.Request) *summary.Command {
+				return &summary.Command{Cancel: &summary.JobCommand{JobId: params["job"]}}
+			}),
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/apps/{app}/blueprints",
+			Summary:  "List the job blueprints registered by the queues of an app",
+			Response: []*summary.JobBlueprint{},
+			handle:   a.listBlueprints,
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/state",
+			Summary:  "Get the state of every app, as sent to dashboard clients",
+			Response: &snapshot{},
+			handle:   a.getState,
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/alerts",
+			Summary:  "List the alerts which are firing",
+			Response: []*alert{},
+			handle:   a.listAlerts,
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/history/series",
+			Summary:  "Count the job updates by status in steps over time, with the throughput, failure rate, queue depth and latency of each step",
+			Query:    append(historyParams, param{"step", "duration", "The width of a step, at least the history resolution"}),
+			Response: []*point{},
+			handle:   a.getSeries,
+		},
+		{
+			Method:  http.MethodGet,
+			Pattern: "/history/jobs",
+			Summary: "List the most recently updated jobs and their updates",
+			Query: append(historyParams,
+				param{"status", "string", "Only the jobs whose latest status is this"},
+				param{"limit", "integer", "The most jobs to list, defaults to 100"},
+			),
+			Response: []*jobHistory{},
+			handle:   a.getJobHistory,
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/history/samples",
+			Summary:  "List the stats samples of the matching queue instances",
+			Query:    historyParams,
+			Response: []*sample{},
+			handle:   a.getSamples,
+		},
+		{
+			Method:   http.MethodGet,
+			Pattern:  "/openapi.json",
+			Summary:  "Get the OpenAPI description of the api",
+			Response: map[string]interface{}{},
+			handle:   a.getOpenAPI,
+		},
+	}
+	return a
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		http.NotFound(w, r)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+
+	allowed := make([]string, 0)
+	for _, rt := range a.routes {
+		params, ok := rt.match(path)
+		if !ok {
+			continue
+		}
+		if rt.Method == r.Method {
+			rt.handle(w, r, params)
+			return
+		}
+		allowed = append(allowed, rt.Method)
+	}
+
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (a *api) listApps(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	writeJSON(w, a.state.summaries())
+}
+
+func (a *api) getApp(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	app := a.state.app(params["app"])
+	if app == nil {
+		http.Error(w, "app not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, app)
+}
+
+func (a *api) listQueues(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	queues, ok := a.state.queues(params["app"])
+	if !ok {
+		http.Error(w, "app not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, queues)
+}
+
+func (a *api) getQueue(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	queue, ok := a.state.queue(params["app"], params["queue"])
+	if !ok {
+		http.Error(w, "queue not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, queue)
+}
+
+func (a *api) getJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	job, ok := a.state.job(params["app"], params["queue"], params["job"])
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, job)
+}
+
+func (a *api) listJobs(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	q := r.URL.Query()
+	offset, err := intQuery(r, "offset", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := intQuery(r, "limit", defaultJobLimit)
+	if err != nil || limit < 1 {
+		http.Error(w, fmt.Sprintf("invalid limit %q", q.Get("limit")), http.StatusBadRequest)
+		return
+	}
+	if limit > maxJobLimit {
+		limit = maxJobLimit
+	}
+
+	jobs, ok := a.state.jobs(params["app"], historyFilter{
+		QueueID: q.Get("queue"),
+		Tag:     q.Get("tag"),
+		Status:  q.Get("status"),
+	})
+	if !ok {
+		http.Error(w, "app not found", http.StatusNotFound)
+		return
+	}
+
+	page := &jobPage{Total: len(jobs), Offset: offset, Limit: limit, Jobs: make([]*queueJob, 0)}
+	if offset < len(jobs) {
+		end := offset + limit
+		if end > len(jobs) {
+			end = len(jobs)
+		}
+		page.Jobs = jobs[offset:end]
+	}
+	writeJSON(w, page)
+}
+
+func (a *api) createJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var req jobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid job request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	reply, err := a.commands.enqueue(r.Context(), a.state, &summary.EnqueueRequest{
+		App:     params["app"],
+		QueueId: req.QueueID,
+		Tag:     req.Tag,
+		Data:    string(req.Data),
+		Retry:   req.Retry,
+	})
+	if err != nil {
+		s := status.Convert(err)
+		http.Error(w, s.Message(), httpStatus(s.Code()))
+		return
+	}
+	writeJSONStatus(w, http.StatusCreated, reply)
+}
+
+// jobCommand handles a route sending a command to the queue instance in its path
+func (a *api) jobCommand(command func(params map[string]string, r *http.Request) *summary.Command) func(http.ResponseWriter, *http.Request, map[string]string) {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		ids, err := a.commands.jobs(r.Context(), params["app"], params["queue"], command(params, r))
+		if err != nil {
+			s := status.Convert(err)
+			http.Error(w, s.Message(), httpStatus(s.Code()))
+			return
+		}
+		writeJSON(w, &jobsReply{QueueID: params["queue"], JobIDs: ids})
+	}
+}
+
+func (a *api) purgeJobs(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	purged, err := a.commands.purge(r.Context(), params["app"], r.URL.Query().Get("status"))
+	if err != nil {
+		s := status.Convert(err)
+		http.Error(w, s.Message(), httpStatus(s.Code()))
+		return
+	}
+	writeJSON(w, purged)
+}
+
+// httpStatus is the HTTP status of a failed command
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.FailedPrecondition:
+		return http.StatusConflict
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+func (a *api) listBlueprints(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	app := a.state.app(params["app"])
+	if app == nil {
+		http.Error(w, "app not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, app.Blueprints)
+}
+
+func (a *api) getState(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	writeJSON(w, a.state.snapshot())
+}
+
+func (a *api) listAlerts(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if a.alerts == nil {
+		writeJSON(w, []*alert{})
+		return
+	}
+	writeJSON(w, a.alerts.active())
+}
+
+// getSeries responds with the job updates counted per step, as throughput
+// and failure rate over time
+func (a *api) getSeries(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	filter, from, to, err := historyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	step := a.history.opts.Resolution
+	if v := r.URL.Query().Get("step"); v != "" {
+		if step, err = time.ParseDuration(v); err != nil || step <= 0 {
+			http.Error(w, fmt.Sprintf("invalid step %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	writeJSON(w, a.history.series(filter, from, to, step))
+}
+
+// getJobHistory responds with the most recently updated jobs and their updates
+func (a *api) getJobHistory(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	filter, from, to, err := historyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, err := intQuery(r, "limit", 100)
+	if err != nil || limit < 1 {
+		http.Error(w, fmt.Sprintf("invalid limit %q", r.URL.Query().Get("limit")), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, a.history.jobs(filter, from, to, limit))
+}
+
+// getSamples responds with the stats samples of the matching queues
+func (a *api) getSamples(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	filter, from, to, err := historyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, a.history.sampled(filter, from, to))
+}
+
+func (a *api) getOpenAPI(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	writeJSON(w, describe(a.routes))
+}
+
+// intQuery reads a non-negative integer query parameter
+func intQuery(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return def, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return n, nil
+}
+
+// historyQuery reads the filter and time range of a history request. The range
+// is given by from and to as RFC 3339 times, or as a window back from now.
+func historyQuery(r *http.Request) (historyFilter, time.Time, time.Time, error) {
+	q := r.URL.Query()
+	filter := historyFilter{
+		App:     q.Get("app"),
+		QueueID: q.Get("queue"),
+		Tag:     q.Get("tag"),
+		Status:  q.Get("status"),
+	}
+
+	to := time.Now()
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, to, to, fmt.Errorf("invalid to: %v", err)
+		}
+		to = t
+	}
+
+	window := time.Hour
+	if v := q.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return filter, to, to, fmt.Errorf("invalid window %q", v)
+		}
+		window = d
+	}
+	from := to.Add(-window)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, to, to, fmt.Errorf("invalid from: %v", err)
+		}
+		from = t
+	}
+	return filter, from, to, nil
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	writeJSONStatus(w, http.StatusOK, body)
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Encoding Error: %v\n", err)
+	}
+}
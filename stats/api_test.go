@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("API", func() {
+	var (
+		handler  *api
+		commands *router
+	)
+
+	get := func(path string, body interface{}) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code == http.StatusOK && body != nil {
+			Expect(json.Unmarshal(rec.Body.Bytes(), body)).To(Succeed())
+		}
+		return rec.Code
+	}
+
+	BeforeEach(func() {
+		st := newState()
+		jobs := make([]*summary.Job, 0)
+		for i := 0; i < 5; i++ {
+			jobs = append(jobs, &summary.Job{Id: strconv.Itoa(i), Tag: "SampleJob", Status: "processed", UpdatedAt: int64(i)})
+		}
+		jobs = append(jobs, &summary.Job{Id: "5", Tag: "OtherJob", Status: "failed", UpdatedAt: 5, Payload: `{"id":5}`, Attempts: []*summary.Attempt{
+			{Worker: "w", StartedAt: 1, FinishedAt: 2, Duration: 1, Error: "always broken"},
+		}})
+		st.apply(queueStats("a", 5, jobs...))
+		st.apply(queueStats("b", 0, &summary.Job{Id: "6", Tag: "SampleJob", Status: "queued", UpdatedAt: 6}))
+
+		other := queueStats("c", 1)
+		other.App = "Other"
+		st.apply(other)
+
+		commands = newRouter(time.Millisecond * 100)
+		handler = newAPI(st, nil, commands, nil)
+	})
+
+	It("should list the apps and their queues", func() {
+		var apps []*appSummary
+		Expect(get("/api/v1/apps", &apps)).To(Equal(http.StatusOK))
+		Expect(apps).To(HaveLen(2))
+		Expect(apps[0].App).To(Equal("Other"))
+		Expect(apps[1].App).To(Equal("Test"))
+		Expect(apps[1].Queues).To(Equal(2))
+		Expect(apps[1].Totals.ProcessedJobs).To(Equal(uint32(5)))
+
+		var queues []*summary.Stats
+		Expect(get("/api/v1/apps/Test/queues", &queues)).To(Equal(http.StatusOK))
+		Expect(queues).To(HaveLen(2))
+		Expect(queues[0].QueueId).To(Equal("a"))
+		Expect(queues[0].Jobs).To(BeEmpty())
+
+		Expect(get("/api/v1/apps/Missing/queues", nil)).To(Equal(http.StatusNotFound))
+	})
+
+	It("should get the stats of a queue along with its jobs", func() {
+		var stats summary.Stats
+		Expect(get("/api/v1/apps/Test/queues/a", &stats)).To(Equal(http.StatusOK))
+		Expect(stats.ProcessedJobs).To(Equal(uint32(5)))
+		Expect(stats.Jobs).To(HaveLen(6))
+
+		Expect(get("/api/v1/apps/Test/queues/missing", nil)).To(Equal(http.StatusNotFound))
+	})
+
+	It("should get a job with its payload and attempts", func() {
+		var job queueJob
+		Expect(get("/api/v1/apps/Test/queues/a/jobs/5", &job)).To(Equal(http.StatusOK))
+		Expect(job.QueueID).To(Equal("a"))
+		Expect(job.Payload).To(MatchJSON(`{"id": 5}`))
+		Expect(job.Attempts).To(HaveLen(1))
+		Expect(job.Attempts[0].Error).To(Equal("always broken"))
+
+		Expect(get("/api/v1/apps/Test/queues/a/jobs/6", nil)).To(Equal(http.StatusNotFound))
+		Expect(get("/api/v1/apps/Test/queues/missing/jobs/5", nil)).To(Equal(http.StatusNotFound))
+	})
+
+	It("should filter and paginate the jobs of an app", func() {
+		var page jobPage
+		Expect(get("/api/v1/apps/Test/jobs?status=processed&tag=SampleJob&offset=1&limit=2", &page)).To(Equal(http.StatusOK))
+		Expect(page.Total).To(Equal(5))
+		Expect(page.Jobs).To(HaveLen(2))
+		Expect(page.Jobs[0].Id).To(Equal("3"))
+		Expect(page.Jobs[1].Id).To(Equal("2"))
+
+		Expect(get("/api/v1/apps/Test/jobs?queue=b", &page)).To(Equal(http.StatusOK))
+		Expect(page.Total).To(Equal(1))
+		Expect(page.Jobs[0].QueueID).To(Equal("b"))
+
+		Expect(get("/api/v1/apps/Test/jobs?offset=10", &page)).To(Equal(http.StatusOK))
+		Expect(page.Total).To(Equal(7))
+		Expect(page.Jobs).To(BeEmpty())
+
+		Expect(get("/api/v1/apps/Test/jobs?limit=none", nil)).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should list the blueprints of an app", func() {
+		var blueprints []*summary.JobBlueprint
+		Expect(get("/api/v1/apps/Test/blueprints", &blueprints)).To(Equal(http.StatusOK))
+		Expect(blueprints).To(HaveLen(1))
+		Expect(blueprints[0].JobName).To(Equal("SampleJob"))
+	})
+
+	It("should create jobs on a queue subscribed to commands", func() {
+		post := func(body string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/apps/Test/jobs", strings.NewReader(body)))
+			return rec
+		}
+
+		Expect(post(`{"tag": "SampleJob", "data": {"id": 1}}`).Code).To(Equal(http.StatusServiceUnavailable))
+
+		sub := commands.subscribe("Test", "b")
+		defer close(sub.commands)
+		serveCommands(commands, sub)
+
+		rec := post(`{"tag": "SampleJob", "data": {"id": 1}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		var reply summary.EnqueueReply
+		Expect(json.Unmarshal(rec.Body.Bytes(), &reply)).To(Succeed())
+		Expect(reply.QueueId).To(Equal("b"))
+
+		Expect(post(`{"tag": "UnknownJob"}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(post(`{"tag": `).Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should route job commands to the owning queue", func() {
+		do := func(method, path string) (int, map[string]interface{}) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+			var body map[string]interface{}
+			if rec.Code == http.StatusOK {
+				Expect(json.Unmarshal(rec.Body.Bytes(), &body)).To(Succeed())
+			}
+			return rec.Code, body
+		}
+
+		code, _ := do(http.MethodPost, "/api/v1/apps/Test/queues/a/jobs/job/retry")
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+
+		sub := commands.subscribe("Test", "a")
+		defer close(sub.commands)
+		serveCommands(commands, sub)
+
+		code, body := do(http.MethodPost, "/api/v1/apps/Test/queues/a/jobs/job/retry")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body["queue_id"]).To(Equal("a"))
+		Expect(body["job_ids"]).To(ConsistOf("job"))
+
+		code, _ = do(http.MethodPost, "/api/v1/apps/Test/queues/a/jobs/job/cancel")
+		Expect(code).To(Equal(http.StatusConflict))
+		code, _ = do(http.MethodPost, "/api/v1/apps/Test/queues/a/jobs/missing/cancel")
+		Expect(code).To(Equal(http.StatusNotFound))
+
+		code, body = do(http.MethodDelete, "/api/v1/apps/Test/queues/a/jobs/job")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body["job_ids"]).To(ConsistOf("job"))
+
+		code, body = do(http.MethodDelete, "/api/v1/apps/Test/queues/a/jobs?status=processed")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body["job_ids"]).To(BeEmpty())
+
+		code, body = do(http.MethodDelete, "/api/v1/apps/Test/jobs")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(HaveKey("a"))
+		Expect(body["a"]).To(ConsistOf("job"))
+	})
+
+	It("should reject unknown paths and methods", func() {
+		Expect(get("/api/v1/unknown", nil)).To(Equal(http.StatusNotFound))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/apps", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(rec.Header().Get("Allow")).To(Equal(http.MethodGet))
+	})
+
+	It("should describe every route in the OpenAPI description", func() {
+		var doc struct {
+			Paths      map[string]map[string]interface{} `json:"paths"`
+			Components struct {
+				Schemas map[string]struct {
+					Properties map[string]interface{} `json:"properties"`
+				} `json:"schemas"`
+			} `json:"components"`
+		}
+		Expect(get("/api/v1/openapi.json", &doc)).To(Equal(http.StatusOK))
+
+		for _, rt := range handler.routes {
+			Expect(doc.Paths).To(HaveKey(apiPrefix + rt.Pattern))
+			Expect(doc.Paths[apiPrefix+rt.Pattern]).To(HaveKey(strings.ToLower(rt.Method)))
+		}
+		Expect(doc.Components.Schemas).To(HaveKey("Stats"))
+		Expect(doc.Components.Schemas["queueJob"].Properties).To(HaveKey("queue_id"))
+		Expect(doc.Components.Schemas["queueJob"].Properties).To(HaveKey("status"))
+		Expect(doc.Components.Schemas["Stats"].Properties).ToNot(HaveKey("XXX_unrecognized"))
+	})
+})
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authenticator checks the credentials of queues calling the gRPC service and
+// of dashboard and API clients. Queues present the token as a bearer token,
+// HTTP clients may present it the same way or log in with the dashboard user.
+type authenticator struct {
+	token    string
+	user     string
+	password string
+}
+
+// grpcEnabled reports whether queues have to present a token
+func (a *authenticator) grpcEnabled() bool {
+	return a.token != ""
+}
+
+// httpEnabled reports whether the dashboard and API require credentials
+func (a *authenticator) httpEnabled() bool {
+	return a.token != "" || a.user != ""
+}
+
+// bearer reports whether an authorization header carries the token
+func (a *authenticator) bearer(authorization string) bool {
+	const prefix = "Bearer "
+	if a.token == "" || !strings.HasPrefix(authorization, prefix) {
+		return false
+	}
+	return equal(strings.TrimPrefix(authorization, prefix), a.token)
+}
+
+// check verifies the token in the metadata of a call
+func (a *authenticator) check(ctx context.Context) error {
+	if !a.grpcEnabled() {
+		return nil
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing token")
+	}
+	for _, authorization := range md["authorization"] {
+		if a.bearer(authorization) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid token")
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.check(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.check(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// serverOptions installs the token check on the gRPC server
+func (a *authenticator) serverOptions() []grpc.ServerOption {
+	if !a.grpcEnabled() {
+		return nil
+	}
+	return []grpc.ServerOption{grpc.UnaryInterceptor(a.unary), grpc.StreamInterceptor(a.stream)}
+}
+
+// wrap requires credentials for every request to the handler, the browser
+// prompts for the dashboard user and sends it along with the websocket
+// handshake
+func (a *authenticator) wrap(h http.Handler) http.Handler {
+	if !a.httpEnabled() {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.bearer(r.Header.Get("Authorization")) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if user, password, ok := r.BasicAuth(); ok && a.user != "" && equal(user, a.user) && equal(password, a.password) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if a.user != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="rift"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// equal compares credentials in constant time
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
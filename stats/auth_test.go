@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/bmartel/rift/metrics"
+	"github.com/bmartel/rift/summary"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Auth", func() {
+	var auth *authenticator
+
+	BeforeEach(func() {
+		auth = &authenticator{token: "secret", user: "admin", password: "hunter2"}
+	})
+
+	It("should only accept reports carrying the token", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		server := grpc.NewServer(auth.serverOptions()...)
+		defer server.Stop()
+		hist, err := openHistory(historyOptions{Raw: time.Hour, Retention: time.Hour, Resolution: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		summary.RegisterSummaryServer(server, &statsServer{
+			statsStream: make(chan *summary.Stats, 10),
+			jobStream:   make(chan *summary.JobUpdate, 10),
+			appStream:   make(chan *appUpdate, 10),
+			exporter:    metrics.NewExporter(),
+			state:       newState(),
+			history:     hist,
+		})
+		go server.Serve(lis)
+
+		conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		client := summary.NewSummaryClient(conn)
+
+		report := func(authorization string) error {
+			ctx := context.Background()
+			if authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", authorization)
+			}
+			stream, err := client.Report(ctx)
+			if err != nil {
+				return err
+			}
+			_, err = stream.CloseAndRecv()
+			return err
+		}
+
+		Expect(status.Code(report(""))).To(Equal(codes.Unauthenticated))
+		Expect(status.Code(report("Bearer wrong"))).To(Equal(codes.Unauthenticated))
+		Expect(report("Bearer secret")).To(Succeed())
+	})
+
+	It("should ask for credentials over http", func() {
+		handler := auth.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		serve := func(r *http.Request) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			return rec
+		}
+
+		rec := serve(httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rec.Header().Get("WWW-Authenticate")).To(ContainSubstring("Basic"))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetBasicAuth("admin", "wrong")
+		Expect(serve(r).Code).To(Equal(http.StatusUnauthorized))
+
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetBasicAuth("admin", "hunter2")
+		Expect(serve(r).Code).To(Equal(http.StatusOK))
+
+		r = httptest.NewRequest(http.MethodGet, "/api/v1/state", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		Expect(serve(r).Code).To(Equal(http.StatusOK))
+	})
+
+	It("should leave everything open without credentials", func() {
+		open := &authenticator{}
+		Expect(open.serverOptions()).To(BeEmpty())
+		Expect(open.check(context.Background())).To(Succeed())
+
+		rec := httptest.NewRecorder()
+		open.wrap(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// subscriber is the commands stream of a queue instance
+type subscriber struct {
+	app      string
+	queueID  string
+	commands chan *summary.Command
+}
+
+// router sends commands to the queue instances subscribed to them and hands
+// their replies back to the callers waiting on them
+type router struct {
+	timeout time.Duration
+
+	mutex       sync.Mutex
+	next        uint64
+	subscribers map[string]map[string]*subscriber
+	pending     map[string]chan *summary.CommandReply
+}
+
+func newRouter(timeout time.Duration) *router {
+	return &router{
+		timeout:     timeout,
+		subscribers: make(map[string]map[string]*subscriber, 0),
+		pending:     make(map[string]chan *summary.CommandReply, 0),
+	}
+}
+
+// subscribe registers the commands stream of a queue instance, replacing any
+// previous stream of the same instance
+func (r *router) subscribe(app, queueID string) *subscriber {
+	sub := &subscriber{app: app, queueID: queueID, commands: make(chan *summary.Command)}
+
+	r.mutex.Lock()
+	queues, ok := r.subscribers[app]
+	if !ok {
+		queues = make(map[string]*subscriber, 0)
+		r.subscribers[app] = queues
+	}
+	queues[queueID] = sub
+	r.mutex.Unlock()
+	return sub
+}
+
+func (r *router) unsubscribe(sub *subscriber) {
+	r.mutex.Lock()
+	if r.subscribers[sub.app][sub.queueID] == sub {
+		delete(r.subscribers[sub.app], sub.queueID)
+		if len(r.subscribers[sub.app]) == 0 {
+			delete(r.subscribers, sub.app)
+		}
+	}
+	r.mutex.Unlock()
+}
+
+// subscribed are the instances of an app with an open commands stream
+func (r *router) subscribed(app string) map[string]bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	queues := make(map[string]bool, len(r.subscribers[app]))
+	for id := range r.subscribers[app] {
+		queues[id] = true
+	}
+	return queues
+}
+
+// reply hands a queue's reply to the caller waiting on the command
+func (r *router) reply(reply *summary.CommandReply) {
+	r.mutex.Lock()
+	replies, ok := r.pending[reply.Id]
+	delete(r.pending, reply.Id)
+	r.mutex.Unlock()
+
+	if ok {
+		replies <- reply
+	}
+}
+
+// send routes a command to a queue instance and waits for its reply
+func (r *router) send(ctx context.Context, app, queueID string, cmd *summary.Command) (*summary.CommandReply, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	replies := make(chan *summary.CommandReply, 1)
+
+	r.mutex.Lock()
+	sub, ok := r.subscribers[app][queueID]
+	r.next++
+	cmd.Id = strconv.FormatUint(r.next, 10)
+	if ok {
+		r.pending[cmd.Id] = replies
+	}
+	r.mutex.Unlock()
+
+	if !ok {
+		return nil, status.Errorf(codes.Unavailable, "queue %s of %s is not subscribed to commands", queueID, app)
+	}
+	defer func() {
+		r.mutex.Lock()
+		delete(r.pending, cmd.Id)
+		r.mutex.Unlock()
+	}()
+
+	select {
+	case sub.commands <- cmd:
+	case <-ctx.Done():
+		return nil, contextError(ctx.Err())
+	}
+
+	select {
+	case reply := <-replies:
+		if reply.Error != "" {
+			code := codes.Code(reply.Code)
+			if code == codes.OK {
+				code = codes.Unknown
+			}
+			return nil, status.Error(code, reply.Error)
+		}
+		return reply, nil
+	case <-ctx.Done():
+		return nil, contextError(ctx.Err())
+	}
+}
+
+// enqueue creates a job on a queue instance of the app subscribed to
+// commands, the instance is picked from the state unless given
+func (r *router) enqueue(ctx context.Context, st *state, req *summary.EnqueueRequest) (*summary.EnqueueReply, error) {
+	if req.App == "" || req.Tag == "" {
+		return nil, status.Error(codes.InvalidArgument, "app and tag are required")
+	}
+
+	queueID := req.QueueId
+	if queueID == "" {
+		if queueID = st.owner(req.App, req.Tag, r.subscribed(req.App)); queueID == "" {
+			return nil, status.Errorf(codes.Unavailable, "no queue of %s is subscribed to commands", req.App)
+		}
+	}
+
+	reply, err := r.send(ctx, req.App, queueID, &summary.Command{Enqueue: req})
+	if err != nil {
+		return nil, err
+	}
+	return reply.Enqueue, nil
+}
+
+// jobs runs a command on the jobs of a queue instance, returning the ids of
+// the jobs it applied to
+func (r *router) jobs(ctx context.Context, app, queueID string, cmd *summary.Command) ([]string, error) {
+	reply, err := r.send(ctx, app, queueID, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if reply.JobIds == nil {
+		return make([]string, 0), nil
+	}
+	return reply.JobIds, nil
+}
+
+// purge removes the finished jobs with the status from every queue instance
+// of the app subscribed to commands, returning the removed ids by queue
+func (r *router) purge(ctx context.Context, app, status string) (map[string][]string, error) {
+	purged := make(map[string][]string, 0)
+	for queueID := range r.subscribed(app) {
+		ids, err := r.jobs(ctx, app, queueID, &summary.Command{Purge: &summary.JobCommand{Status: status}})
+		if err != nil {
+			return purged, err
+		}
+		purged[queueID] = ids
+	}
+	return purged, nil
+}
+
+func contextError(err error) error {
+	if err == context.DeadlineExceeded {
+		return status.Error(codes.DeadlineExceeded, "queue did not reply in time")
+	}
+	return status.Error(codes.Canceled, err.Error())
+}
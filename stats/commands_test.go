@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// serveCommands replies to the commands of a subscriber the way a queue would,
+// creating SampleJobs only and holding a single failed job with the id "job"
+func serveCommands(r *router, sub *subscriber) {
+	go func() {
+		for c := range sub.commands {
+			reply := &summary.CommandReply{Id: c.Id, App: sub.app, QueueId: sub.queueID}
+			switch {
+			case c.Enqueue != nil && c.Enqueue.Tag == "SampleJob":
+				reply.Enqueue = &summary.EnqueueReply{Id: "job", QueueId: sub.queueID}
+			case c.Enqueue != nil:
+				reply.Code = uint32(codes.InvalidArgument)
+				reply.Error = "no job serializer could be found for " + c.Enqueue.Tag
+			case c.Retry != nil && c.Retry.JobId == "job", c.Purge != nil && c.Purge.JobId == "job":
+				reply.JobIds = []string{"job"}
+			case c.Purge != nil && c.Purge.JobId == "":
+				if c.Purge.Status == "" || c.Purge.Status == "failed" {
+					reply.JobIds = []string{"job"}
+				}
+			case c.Cancel != nil && c.Cancel.JobId == "job":
+				reply.Code = uint32(codes.FailedPrecondition)
+				reply.Error = "job job is failed and can't be cancelled"
+			default:
+				reply.Code = uint32(codes.NotFound)
+				reply.Error = "job not found"
+			}
+			r.reply(reply)
+		}
+	}()
+}
+
+var _ = Describe("Commands", func() {
+	var (
+		st  *state
+		cmd *router
+		srv *statsServer
+	)
+
+	BeforeEach(func() {
+		st = newState()
+		cmd = newRouter(time.Millisecond * 100)
+		srv = &statsServer{state: st, commands: cmd}
+	})
+
+	It("should route jobs to a subscribed queue which registered the tag", func() {
+		st.apply(queueStats("a", 0))
+		unregistered := queueStats("b", 0)
+		unregistered.JobBlueprints = nil
+		st.apply(unregistered)
+
+		a := cmd.subscribe("Test", "a")
+		defer close(a.commands)
+		serveCommands(cmd, a)
+		b := cmd.subscribe("Test", "b")
+		defer close(b.commands)
+		serveCommands(cmd, b)
+
+		reply, err := srv.Enqueue(context.Background(), &summary.EnqueueRequest{App: "Test", Tag: "SampleJob"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reply.QueueId).To(Equal("a"))
+
+		reply, err = srv.Enqueue(context.Background(), &summary.EnqueueRequest{App: "Test", QueueId: "b", Tag: "SampleJob"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reply.QueueId).To(Equal("b"))
+
+		_, err = srv.Enqueue(context.Background(), &summary.EnqueueRequest{App: "Test", Tag: "UnknownJob"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should fail when no queue can take the command", func() {
+		_, err := srv.Enqueue(context.Background(), &summary.EnqueueRequest{App: "Test", Tag: "SampleJob"})
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+
+		// subscribed but never replying
+		sub := cmd.subscribe("Test", "a")
+		go func() {
+			for range sub.commands {
+			}
+		}()
+		defer close(sub.commands)
+
+		_, err = srv.Enqueue(context.Background(), &summary.EnqueueRequest{App: "Test", Tag: "SampleJob"})
+		Expect(status.Code(err)).To(Equal(codes.DeadlineExceeded))
+
+		cmd.unsubscribe(sub)
+		Expect(cmd.subscribed("Test")).To(BeEmpty())
+	})
+})
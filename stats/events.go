@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+)
+
+const (
+	// eventBuffer is the number of updates held for a slow event stream
+	// before further updates are dropped for it
+	eventBuffer = 64
+	// eventKeepAlive is how often an idle event stream is sent a comment so
+	// proxies keep the connection open
+	eventKeepAlive = 15 * time.Second
+)
+
+// eventFilter narrows the updates sent to an event stream or dashboard socket
+// to an app, a queue instance, a job tag and a job status, empty fields match
+// everything
+type eventFilter struct {
+	App    string
+	Queue  string
+	Tag    string
+	Status string
+}
+
+func (f eventFilter) matches(app, queueID string) bool {
+	return (f.App == "" || f.App == app) && (f.Queue == "" || f.Queue == queueID)
+}
+
+func (f eventFilter) job(job *summary.Job) bool {
+	return (f.Tag == "" || job.GetTag() == f.Tag) && (f.Status == "" || job.GetStatus() == f.Status)
+}
+
+// apply returns the event name and the part of an update matching the
+// filter, or false if none of it does. App updates carry the totals of the
+// whole app and pass for any queue, tag and status, as do alerts.
+func (f eventFilter) apply(data interface{}) (string, interface{}, bool) {
+	switch update := data.(type) {
+	case *summary.JobUpdate:
+		if !f.matches(update.App, update.QueueId) || !f.job(update.Job) {
+			return "", nil, false
+		}
+		return "job", update, true
+	case *summary.Stats:
+		if !f.matches(update.App, update.QueueId) {
+			return "", nil, false
+		}
+		if f.Tag == "" && f.Status == "" {
+			return "stats", update, true
+		}
+		stats := *update
+		stats.Jobs = make(map[string]*summary.Job, 0)
+		for id, job := range update.Jobs {
+			if f.job(job) {
+				stats.Jobs[id] = job
+			}
+		}
+		if f.Tag != "" {
+			stats.Latencies = make(map[string]*summary.Latency, 1)
+			if latency, ok := update.Latencies[f.Tag]; ok {
+				stats.Latencies[f.Tag] = latency
+			}
+		}
+		return "stats", &stats, true
+	case *appUpdate:
+		if f.App != "" && f.App != update.App {
+			return "", nil, false
+		}
+		return "app", update, true
+	case *snapshot:
+		snap := &snapshot{Message: update.Message, Apps: make(map[string]*appSnapshot, len(update.Apps))}
+		for name, app := range update.Apps {
+			if f.App != "" && f.App != name {
+				continue
+			}
+			narrowed := *app
+			narrowed.Jobs = make(map[string]*queueJob, 0)
+			for id, job := range app.Jobs {
+				if (f.Queue == "" || f.Queue == job.QueueID) && f.job(job.Job) {
+					narrowed.Jobs[id] = job
+				}
+			}
+			snap.Apps[name] = &narrowed
+		}
+		return "snapshot", snap, true
+	case *alert:
+		// alerts on a whole app pass for any queue
+		if (f.App != "" && f.App != update.App) || (f.Queue != "" && update.QueueID != "" && f.Queue != update.QueueID) {
+			return "", nil, false
+		}
+		return "alert", update, true
+	case *seriesUpdate:
+		if f.App == "" {
+			return "series", update, true
+		}
+		series := *update
+		series.Points = make(map[string]map[string]*point, 1)
+		if points, ok := update.Points[f.App]; ok {
+			series.Points[f.App] = points
+		}
+		return "series", &series, true
+	}
+	return "", data, true
+}
+
+// events streams the updates sent to dashboard sockets as Server-Sent Events
+// to clients which can't speak websockets
+type events struct {
+	state   *state
+	mtx     sync.Mutex
+	streams map[chan interface{}]bool
+}
+
+func newEvents(st *state) *events {
+	return &events{
+		state:   st,
+		streams: make(map[chan interface{}]bool, 0),
+	}
+}
+
+func (e *events) subscribe() chan interface{} {
+	ch := make(chan interface{}, eventBuffer)
+	e.mtx.Lock()
+	e.streams[ch] = true
+	e.mtx.Unlock()
+	return ch
+}
+
+func (e *events) unsubscribe(ch chan interface{}) {
+	e.mtx.Lock()
+	delete(e.streams, ch)
+	e.mtx.Unlock()
+}
+
+// publish hands an update to every stream without waiting on them, a stream
+// whose buffer is full misses the update
+func (e *events) publish(data interface{}) {
+	e.mtx.Lock()
+	for ch := range e.streams {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+	e.mtx.Unlock()
+}
+
+// ServeHTTP streams updates until the client goes away, filtered by the app,
+// queue, tag and status query parameters. The stream opens with a snapshot of
+// the state narrowed by the same filters.
+func (e *events) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query()
+	filter := eventFilter{App: query.Get("app"), Queue: query.Get("queue"), Tag: query.Get("tag"), Status: query.Get("status")}
+
+	ch := e.subscribe()
+	defer e.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	_, snap, _ := filter.apply(e.state.snapshot())
+	if err := writeEvent(w, "snapshot", snap); err != nil {
+		log.Printf("Event Error: %v\n", err)
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case data := <-ch:
+			name, update, ok := filter.apply(data)
+			if !ok {
+				continue
+			}
+			if err := writeEvent(w, name, update); err != nil {
+				log.Printf("Event Error: %v\n", err)
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, name string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, body)
+	return err
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/bmartel/rift/summary"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// sentEvent is a single Server-Sent Event read off a stream
+type sentEvent struct {
+	name string
+	data string
+}
+
+func readEvent(r *bufio.Reader) sentEvent {
+	var ev sentEvent
+	for {
+		line, err := r.ReadString('\n')
+		Expect(err).ToNot(HaveOccurred())
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && ev.name != "":
+			return ev
+		case strings.HasPrefix(line, "event: "):
+			ev.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+var _ = Describe("Events", func() {
+	var (
+		st     *state
+		ev     *events
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		st = newState()
+		ev = newEvents(st)
+		server = httptest.NewServer(ev)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	open := func(query string) (*bufio.Reader, func()) {
+		res, err := http.Get(server.URL + "/events" + query)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(res.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+		return bufio.NewReader(res.Body), func() { res.Body.Close() }
+	}
+
+	It("should open with a snapshot narrowed to the app", func() {
+		st.apply(queueStats("a", 1))
+		other := queueStats("b", 1)
+		other.App = "Other"
+		st.apply(other)
+
+		r, closeStream := open("?app=Test")
+		defer closeStream()
+
+		first := readEvent(r)
+		Expect(first.name).To(Equal("snapshot"))
+		var snap snapshot
+		Expect(json.Unmarshal([]byte(first.data), &snap)).To(Succeed())
+		Expect(snap.Apps).To(HaveKey("Test"))
+		Expect(snap.Apps).ToNot(HaveKey("Other"))
+	})
+
+	It("should only stream updates matching the filters", func(done Done) {
+		r, closeStream := open("?app=Test&queue=a&tag=Mail")
+		defer closeStream()
+		Expect(readEvent(r).name).To(Equal("snapshot"))
+
+		ev.publish(&summary.JobUpdate{App: "Other", QueueId: "a", Job: &summary.Job{Id: "1", Tag: "Mail"}})
+		ev.publish(&summary.JobUpdate{App: "Test", QueueId: "b", Job: &summary.Job{Id: "2", Tag: "Mail"}})
+		ev.publish(&summary.JobUpdate{App: "Test", QueueId: "a", Job: &summary.Job{Id: "3", Tag: "Report"}})
+		ev.publish(&summary.JobUpdate{App: "Test", QueueId: "a", Job: &summary.Job{Id: "4", Tag: "Mail"}})
+		ev.publish(queueStats("a", 2,
+			&summary.Job{Id: "4", Tag: "Mail", Status: "processed"},
+			&summary.Job{Id: "5", Tag: "Report", Status: "processed"},
+		))
+		ev.publish(&appUpdate{Message: "app", App: "Test"})
+
+		job := readEvent(r)
+		Expect(job.name).To(Equal("job"))
+		var update summary.JobUpdate
+		Expect(json.Unmarshal([]byte(job.data), &update)).To(Succeed())
+		Expect(update.Job.Id).To(Equal("4"))
+
+		stats := readEvent(r)
+		Expect(stats.name).To(Equal("stats"))
+		var reported summary.Stats
+		Expect(json.Unmarshal([]byte(stats.data), &reported)).To(Succeed())
+		Expect(reported.Jobs).To(HaveLen(1))
+		Expect(reported.Jobs).To(HaveKey("4"))
+
+		Expect(readEvent(r).name).To(Equal("app"))
+
+		close(done)
+	}, 3)
+
+	It("should stop publishing to closed streams", func() {
+		r, closeStream := open("")
+		Expect(readEvent(r).name).To(Equal("snapshot"))
+		closeStream()
+
+		Eventually(func() int {
+			ev.publish(&appUpdate{Message: "app", App: "Test"})
+			ev.mtx.Lock()
+			defer ev.mtx.Unlock()
+			return len(ev.streams)
+		}).Should(Equal(0))
+	})
+})
@@ -0,0 +1,1011 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+)
+
+const (
+	segmentPrefix = "segment-"
+	segmentSuffix = ".log"
+	// defaultSegment is the width of a segment when the options leave it out
+	defaultSegment = time.Hour
+)
+
+// event is a single job update as reported by a queue
+type event struct {
+	App     string       `json:"app"`
+	QueueID string       `json:"queue_id"`
+	Job     *summary.Job `json:"job"`
+}
+
+// rollup counts the job updates with the same status in one bucket, replacing
+// the individual events once they are older than the raw window
+type rollup struct {
+	App     string `json:"app"`
+	QueueID string `json:"queue_id"`
+	Tag     string `json:"tag"`
+	Status  string `json:"status"`
+	At      int64  `json:"at"`
+	Count   uint64 `json:"count"`
+	// Depth is the change in the number of jobs waiting to run
+	Depth int64 `json:"depth,omitempty"`
+	// Latency is the total time from request to finish of the updates, in nanoseconds
+	Latency int64 `json:"latency,omitempty"`
+}
+
+type rollupKey struct {
+	app     string
+	queueID string
+	tag     string
+	status  string
+	at      int64
+}
+
+// sample is the state of a queue instance at a point in time, the counters
+// are cumulative so downsampling keeps the last sample of each bucket
+type sample struct {
+	App           string `json:"app"`
+	QueueID       string `json:"queue_id"`
+	At            int64  `json:"at"`
+	ActiveJobs    uint32 `json:"active_jobs"`
+	QueuedJobs    uint32 `json:"queued_jobs"`
+	ProcessedJobs uint32 `json:"processed_jobs"`
+	FailedJobs    uint32 `json:"failed_jobs"`
+	RequeuedJobs  uint32 `json:"requeued_jobs"`
+	Workers       uint32 `json:"workers"`
+	IdleWorkers   uint32 `json:"idle_workers"`
+}
+
+type sampleKey struct {
+	app     string
+	queueID string
+	at      int64
+}
+
+// record is one line of a segment log
+type record struct {
+	Event  *event  `json:"event,omitempty"`
+	Rollup *rollup `json:"rollup,omitempty"`
+	Sample *sample `json:"sample,omitempty"`
+	// Downsampled marks the segment starting at this time as downsampled
+	Downsampled int64 `json:"downsampled,omitempty"`
+}
+
+// historyOptions configures how long history is held and at what detail
+type historyOptions struct {
+	// Dir holds the segment logs, history is only kept in memory when empty
+	Dir string
+	// Raw is how long individual job events and samples are kept before
+	// being downsampled
+	Raw time.Duration
+	// Retention is how long downsampled history is kept
+	Retention time.Duration
+	// Resolution is the width of a downsampled bucket
+	Resolution time.Duration
+	// SampleInterval is the least time between two samples of a queue
+	SampleInterval time.Duration
+	// Segment is the width of the time window each segment log holds,
+	// rounded up to whole buckets. History is downsampled and expires a
+	// segment at a time, so both happen up to a segment late.
+	Segment time.Duration
+}
+
+// segment is the history of one segment window along with its log. Leaving
+// the raw window rewrites only the segment's own log, and expired history is
+// dropped by deleting whole logs.
+type segment struct {
+	start int64
+	// events are ordered by the time of the update
+	events  []*event
+	rollups map[rollupKey]*rollup
+	// samples are ordered by the time they were taken
+	samples []*sample
+	// downsampled is set once the events are rolled up and the samples thinned
+	downsampled bool
+
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func newSegment(start int64) *segment {
+	return &segment{
+		start:   start,
+		events:  make([]*event, 0),
+		rollups: make(map[rollupKey]*rollup, 0),
+		samples: make([]*sample, 0),
+	}
+}
+
+// insert adds an event at its place in time, reports from different queues
+// interleave so it is usually close to the end
+func (s *segment) insert(e *event) {
+	at := e.Job.UpdatedAt
+	n := len(s.events)
+	i := n
+	if n > 0 && s.events[n-1].Job.UpdatedAt > at {
+		i = sort.Search(n, func(i int) bool { return s.events[i].Job.UpdatedAt > at })
+	}
+	s.events = append(s.events, nil)
+	copy(s.events[i+1:], s.events[i:])
+	s.events[i] = e
+}
+
+func (s *segment) addSample(sm *sample) {
+	n := len(s.samples)
+	i := n
+	if n > 0 && s.samples[n-1].At > sm.At {
+		i = sort.Search(n, func(i int) bool { return s.samples[i].At > sm.At })
+	}
+	s.samples = append(s.samples, nil)
+	copy(s.samples[i+1:], s.samples[i:])
+	s.samples[i] = sm
+}
+
+func (s *segment) addRollup(r *rollup) {
+	key := rollupKey{r.App, r.QueueID, r.Tag, r.Status, r.At}
+	if existing, ok := s.rollups[key]; ok {
+		existing.Count += r.Count
+		existing.Depth += r.Depth
+		existing.Latency += r.Latency
+		return
+	}
+	copied := *r
+	s.rollups[key] = &copied
+}
+
+func (s *segment) sortedRollups() []*rollup {
+	rollups := make([]*rollup, 0, len(s.rollups))
+	for _, r := range s.rollups {
+		rollups = append(rollups, r)
+	}
+	sort.Sort(byRollupTime(rollups))
+	return rollups
+}
+
+func (s *segment) close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	s.encoder = nil
+	return err
+}
+
+// history records job events and stats samples, persisting them to append
+// only segment logs which are rewritten once as they are downsampled and
+// deleted as they expire
+type history struct {
+	mutex sync.RWMutex
+	opts  historyOptions
+	now   func() time.Time
+
+	segments    map[int64]*segment
+	lastSampled map[string]int64
+
+	// live holds running tallies of the open bucket by app and tag, the
+	// empty tag holding the whole app, so the latest chart points don't
+	// walk the history
+	live      map[string]map[string]*tally
+	liveStart int64
+	ahead     []liveUpdate
+}
+
+// validate rejects the durations history can't be kept with, a segment left
+// out is given the default width
+func (opts historyOptions) validate() error {
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"raw", opts.Raw},
+		{"retention", opts.Retention},
+		{"resolution", opts.Resolution},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			return fmt.Errorf("history %s must be positive, got %s", d.name, d.value)
+		}
+	}
+	if opts.SampleInterval < 0 {
+		return fmt.Errorf("history sample interval can't be negative, got %s", opts.SampleInterval)
+	}
+	if opts.Segment < 0 {
+		return fmt.Errorf("history segment can't be negative, got %s", opts.Segment)
+	}
+	return nil
+}
+
+// openHistory loads any history held in the options directory
+func openHistory(opts historyOptions) (*history, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Segment == 0 {
+		opts.Segment = defaultSegment
+	}
+	if rem := opts.Segment % opts.Resolution; rem != 0 {
+		opts.Segment += opts.Resolution - rem
+	}
+	h := &history{
+		opts:        opts,
+		now:         time.Now,
+		segments:    make(map[int64]*segment, 0),
+		lastSampled: make(map[string]int64, 0),
+		live:        make(map[string]map[string]*tally, 0),
+	}
+
+	if opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+			return nil, err
+		}
+		if err := h.load(); err != nil {
+			return nil, err
+		}
+	}
+	if err := h.compact(); err != nil {
+		return nil, err
+	}
+
+	h.mutex.Lock()
+	h.resetLive()
+	h.mutex.Unlock()
+	return h, nil
+}
+
+func (h *history) load() error {
+	paths, err := filepath.Glob(filepath.Join(h.opts.Dir, segmentPrefix+"*"+segmentSuffix))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := h.loadFile(path); err != nil {
+			return err
+		}
+	}
+
+	for _, seg := range h.segments {
+		sort.Stable(byEventTime(seg.events))
+		sort.Stable(bySampleTime(seg.samples))
+	}
+	return nil
+}
+
+// loadFile reads the records of a log into their segments, leaving them to be
+// sorted once every log is read
+func (h *history) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var r record
+		// a line cut short by a crash is skipped rather than losing the log
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		switch {
+		case r.Event != nil && r.Event.Job != nil:
+			seg := h.segment(r.Event.Job.UpdatedAt)
+			seg.events = append(seg.events, r.Event)
+		case r.Rollup != nil:
+			h.segment(r.Rollup.At).addRollup(r.Rollup)
+		case r.Sample != nil:
+			seg := h.segment(r.Sample.At)
+			seg.samples = append(seg.samples, r.Sample)
+			key := r.Sample.App + "/" + r.Sample.QueueID
+			if r.Sample.At > h.lastSampled[key] {
+				h.lastSampled[key] = r.Sample.At
+			}
+		case r.Downsampled != 0:
+			h.segment(r.Downsampled).downsampled = true
+		}
+	}
+	return scanner.Err()
+}
+
+// segment returns the segment holding the time, creating it if needed. Must
+// be called holding the lock.
+func (h *history) segment(at int64) *segment {
+	start := at - at%int64(h.opts.Segment)
+	seg, ok := h.segments[start]
+	if !ok {
+		seg = newSegment(start)
+		h.segments[start] = seg
+	}
+	return seg
+}
+
+// ordered returns the segments overlapping the range from start to end in
+// time order, must be called holding the lock
+func (h *history) ordered(start, end int64) []*segment {
+	width := int64(h.opts.Segment)
+	segments := make([]*segment, 0)
+	for at, seg := range h.segments {
+		if at < end && at+width > start {
+			segments = append(segments, seg)
+		}
+	}
+	sort.Sort(bySegmentStart(segments))
+	return segments
+}
+
+func (h *history) path(seg *segment) string {
+	return filepath.Join(h.opts.Dir, fmt.Sprintf("%s%d%s", segmentPrefix, seg.start, segmentSuffix))
+}
+
+// record adds the job updates and a sample of a queue's reported stats
+func (h *history) record(jobs []*summary.Job, stats *summary.Stats) {
+	if stats == nil {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := h.now().UnixNano()
+	h.advance(h.bucket(now))
+
+	for _, job := range jobs {
+		h.add(&event{App: stats.App, QueueID: stats.QueueId, Job: job})
+	}
+
+	key := stats.App + "/" + stats.QueueId
+	if now-h.lastSampled[key] < int64(h.opts.SampleInterval) {
+		return
+	}
+	h.lastSampled[key] = now
+
+	s := &sample{
+		App:           stats.App,
+		QueueID:       stats.QueueId,
+		At:            now,
+		ActiveJobs:    stats.ActiveJobs,
+		QueuedJobs:    stats.QueuedJobs,
+		ProcessedJobs: stats.ProcessedJobs,
+		FailedJobs:    stats.FailedJobs,
+		RequeuedJobs:  stats.RequeuedJobs,
+		Workers:       stats.Workers,
+		IdleWorkers:   stats.IdleWorkers,
+	}
+	seg := h.segment(now)
+	seg.addSample(s)
+	h.write(seg, record{Sample: s})
+}
+
+// add records a job update, an update arriving for a segment which was
+// already downsampled is rolled up right away. Must be called holding the lock.
+func (h *history) add(e *event) {
+	at := e.Job.UpdatedAt
+	h.liveAdd(e.App, e.Job.Tag, at, e.Job.Status, 1, depthChange(e.Job), latency(e.Job))
+
+	seg := h.segment(at)
+	if seg.downsampled {
+		r := h.rollup(e)
+		seg.addRollup(r)
+		h.write(seg, record{Rollup: r})
+		return
+	}
+	seg.insert(e)
+	h.write(seg, record{Event: e})
+}
+
+func (h *history) rollup(e *event) *rollup {
+	return &rollup{
+		App:     e.App,
+		QueueID: e.QueueID,
+		Tag:     e.Job.Tag,
+		Status:  e.Job.Status,
+		At:      h.bucket(e.Job.UpdatedAt),
+		Count:   1,
+		Depth:   depthChange(e.Job),
+		Latency: latency(e.Job),
+	}
+}
+
+// write appends a record to the log of the segment, must be called holding
+// the lock. Only segments still in the raw window keep their log open.
+func (h *history) write(seg *segment, r record) {
+	if h.opts.Dir == "" {
+		return
+	}
+	if seg.encoder == nil {
+		file, err := os.OpenFile(h.path(seg), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Printf("History Error: %v\n", err)
+			return
+		}
+		seg.file = file
+		seg.encoder = json.NewEncoder(file)
+	}
+	if err := seg.encoder.Encode(r); err != nil {
+		log.Printf("History Error: %v\n", err)
+	}
+	if seg.downsampled {
+		seg.close()
+	}
+}
+
+// bucket is the start of the resolution bucket holding the time
+func (h *history) bucket(at int64) int64 {
+	resolution := int64(h.opts.Resolution)
+	return at - at%resolution
+}
+
+// compact downsamples the segments which left the raw window, rewriting
+// only their logs, and deletes the segments which outlived the retention
+func (h *history) compact() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := h.now()
+	raw := now.Add(-h.opts.Raw).UnixNano()
+	expired := now.Add(-h.opts.Retention).UnixNano()
+	width := int64(h.opts.Segment)
+
+	var failed error
+	for start, seg := range h.segments {
+		switch {
+		case start+width <= expired:
+			h.expire(seg)
+			delete(h.segments, start)
+			if err := h.remove(seg); err != nil {
+				failed = err
+			}
+		case !seg.downsampled && start+width <= raw:
+			h.downsample(seg)
+			if err := h.rewrite(seg); err != nil {
+				failed = err
+			}
+		}
+	}
+	return failed
+}
+
+// downsample rolls the events of a segment up into counts per bucket and
+// keeps only the last sample of each queue per bucket
+func (h *history) downsample(seg *segment) {
+	for _, e := range seg.events {
+		seg.addRollup(h.rollup(e))
+	}
+	seg.events = make([]*event, 0)
+
+	samples := make([]*sample, 0, len(seg.samples))
+	last := make(map[sampleKey]int, 0)
+	for _, s := range seg.samples {
+		key := sampleKey{s.App, s.QueueID, h.bucket(s.At)}
+		if i, ok := last[key]; ok {
+			samples[i] = s
+			continue
+		}
+		last[key] = len(samples)
+		samples = append(samples, s)
+	}
+	seg.samples = samples
+	seg.downsampled = true
+}
+
+// rewrite replaces the log of a segment with its current history, must be
+// called holding the lock
+func (h *history) rewrite(seg *segment) error {
+	if h.opts.Dir == "" {
+		return nil
+	}
+	path := h.path(seg)
+	tmp, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+	if seg.downsampled {
+		encoder.Encode(record{Downsampled: seg.start})
+	}
+	for _, r := range seg.sortedRollups() {
+		encoder.Encode(record{Rollup: r})
+	}
+	for _, s := range seg.samples {
+		encoder.Encode(record{Sample: s})
+	}
+	for _, e := range seg.events {
+		encoder.Encode(record{Event: e})
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	seg.close()
+	return os.Rename(path+".tmp", path)
+}
+
+// remove deletes the log of an expired segment
+func (h *history) remove(seg *segment) error {
+	seg.close()
+	if h.opts.Dir == "" {
+		return nil
+	}
+	if err := os.Remove(h.path(seg)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// compactEvery compacts the history on an interval until the done channel closes
+func (h *history) compactEvery(interval time.Duration, done chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := h.compact(); err != nil {
+				log.Printf("History Error: %v\n", err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// Close releases the segment logs
+func (h *history) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var failed error
+	for _, seg := range h.segments {
+		if err := seg.close(); err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// liveUpdate is an update held back from the running tallies until the open
+// bucket reaches it
+type liveUpdate struct {
+	app, tag, status string
+	at               int64
+	count            uint64
+	depth, latency   int64
+}
+
+// liveAdd adds an update to the running tallies of the open bucket, updates
+// from before it only count towards the depth and updates ahead of the
+// server's clock wait for their bucket. Must be called holding the lock.
+func (h *history) liveAdd(app, tag string, at int64, status string, count uint64, depth, latency int64) {
+	tags, ok := h.live[app]
+	if !ok {
+		tags = make(map[string]*tally, 0)
+		h.live[app] = tags
+	}
+	ahead := at >= h.liveStart+int64(h.opts.Resolution)
+	for _, key := range []string{"", tag} {
+		t, ok := tags[key]
+		if !ok {
+			t = newTally(h.liveStart, 1, h.opts.Resolution)
+			tags[key] = t
+		}
+		if !ahead {
+			t.add(at, status, count, depth, latency)
+		}
+	}
+	if ahead {
+		h.ahead = append(h.ahead, liveUpdate{app, tag, status, at, count, depth, latency})
+	}
+}
+
+// advance moves the open bucket forward, carrying the depth of the closed
+// bucket over. Must be called holding the lock.
+func (h *history) advance(start int64) {
+	if start <= h.liveStart {
+		return
+	}
+	for _, tags := range h.live {
+		for tag, t := range tags {
+			next := newTally(start, 1, h.opts.Resolution)
+			next.depth = t.depth + t.points[0].depth
+			tags[tag] = next
+		}
+	}
+	h.liveStart = start
+
+	ahead := h.ahead
+	h.ahead = nil
+	for _, u := range ahead {
+		h.liveAdd(u.app, u.tag, u.at, u.status, u.count, u.depth, u.latency)
+	}
+}
+
+// expire takes the depth changes of an expired segment back out of the
+// running tallies, matching what a series over the remaining history gives.
+// Must be called holding the lock.
+func (h *history) expire(seg *segment) {
+	unlive := func(app, tag string, depth int64) {
+		for _, key := range []string{"", tag} {
+			if t, ok := h.live[app][key]; ok {
+				t.depth -= depth
+			}
+		}
+	}
+	for _, r := range seg.rollups {
+		unlive(r.App, r.Tag, r.Depth)
+	}
+	for _, e := range seg.events {
+		unlive(e.App, e.Job.Tag, depthChange(e.Job))
+	}
+}
+
+// resetLive rebuilds the running tallies from the whole history, must be
+// called holding the lock
+func (h *history) resetLive() {
+	h.live = make(map[string]map[string]*tally, 0)
+	h.liveStart = h.bucket(h.now().UnixNano())
+	h.ahead = nil
+	for _, seg := range h.segments {
+		for _, r := range seg.rollups {
+			h.liveAdd(r.App, r.Tag, r.At, r.Status, r.Count, r.Depth, r.Latency)
+		}
+		for _, e := range seg.events {
+			h.liveAdd(e.App, e.Job.Tag, e.Job.UpdatedAt, e.Job.Status, 1, depthChange(e.Job), latency(e.Job))
+		}
+	}
+}
+
+// historyFilter narrows history queries, empty fields match everything
+type historyFilter struct {
+	App     string
+	QueueID string
+	Tag     string
+	Status  string
+}
+
+func (f historyFilter) matches(app, queueID, tag, status string) bool {
+	return (f.App == "" || f.App == app) &&
+		(f.QueueID == "" || f.QueueID == queueID) &&
+		(f.Tag == "" || f.Tag == tag) &&
+		(f.Status == "" || f.Status == status)
+}
+
+// depthChange is how a job update changes the number of jobs waiting to run
+func depthChange(job *summary.Job) int64 {
+	switch job.Status {
+	case "queued", "requeued":
+		return 1
+	case "started":
+		return -1
+	case "cancelled":
+		// jobs cancelled while running already left the queue when they started
+		if job.StartedAt == 0 {
+			return -1
+		}
+	}
+	return 0
+}
+
+// latency is the time from the request of a job to its finished update, zero
+// for any other update
+func latency(job *summary.Job) int64 {
+	if (job.Status == "processed" || job.Status == "failed") && job.RequestedAt > 0 && job.UpdatedAt > job.RequestedAt {
+		return job.UpdatedAt - job.RequestedAt
+	}
+	return 0
+}
+
+// point counts the job updates by status within one step of a series
+type point struct {
+	At        int64  `json:"at"`
+	Queued    uint64 `json:"queued"`
+	Started   uint64 `json:"started"`
+	Processed uint64 `json:"processed"`
+	Failed    uint64 `json:"failed"`
+	Requeued  uint64 `json:"requeued"`
+	Cancelled uint64 `json:"cancelled"`
+	// Throughput is the jobs processed per second
+	Throughput float64 `json:"throughput"`
+	// FailureRate is the share of finished attempts which failed
+	FailureRate float64 `json:"failure_rate"`
+	// Depth is the number of jobs waiting to run at the end of the step
+	Depth int64 `json:"depth"`
+	// Latency is the mean time in seconds from request to finish of the
+	// attempts finished in the step
+	Latency float64 `json:"latency"`
+
+	depth   int64
+	latency int64
+}
+
+func (p *point) add(status string, count uint64) {
+	switch status {
+	case "queued":
+		p.Queued += count
+	case "started":
+		p.Started += count
+	case "processed":
+		p.Processed += count
+	case "failed":
+		p.Failed += count
+	case "requeued":
+		p.Requeued += count
+	case "cancelled":
+		p.Cancelled += count
+	}
+}
+
+// maxPoints bounds the size of a series
+const maxPoints = 10000
+
+// tally adds job updates up into the points of a series. Updates before the
+// first point only count towards the queue depth.
+type tally struct {
+	start  int64
+	step   time.Duration
+	points []*point
+	depth  int64
+}
+
+func newTally(start int64, n int, step time.Duration) *tally {
+	t := &tally{start: start, step: step, points: make([]*point, n)}
+	for i := range t.points {
+		t.points[i] = &point{At: start + int64(i)*int64(step)}
+	}
+	return t
+}
+
+func (t *tally) add(at int64, status string, count uint64, depth, latency int64) {
+	if at < t.start {
+		t.depth += depth
+		return
+	}
+	i := int((at - t.start) / int64(t.step))
+	if i >= len(t.points) {
+		return
+	}
+	p := t.points[i]
+	p.add(status, count)
+	p.depth += depth
+	p.latency += latency
+}
+
+// finish derives the rates, depth and latency of every point
+func (t *tally) finish() []*point {
+	depth := t.depth
+	for _, p := range t.points {
+		depth += p.depth
+		// depth is approximate once history expires, so it never goes negative
+		if depth < 0 {
+			depth = 0
+		}
+		p.Depth = depth
+		p.Throughput = float64(p.Processed) / t.step.Seconds()
+		if finished := p.Processed + p.Failed; finished > 0 {
+			p.FailureRate = float64(p.Failed) / float64(finished)
+			p.Latency = time.Duration(p.latency / int64(finished)).Seconds()
+		}
+	}
+	return t.points
+}
+
+// series counts the job updates matching the filter in steps between from
+// and to, the status of the filter is ignored as every status is counted
+func (h *history) series(f historyFilter, from, to time.Time, step time.Duration) []*point {
+	if step < h.opts.Resolution {
+		step = h.opts.Resolution
+	}
+	start := from.UnixNano() - from.UnixNano()%int64(step)
+	end := to.UnixNano()
+	if end <= start {
+		return make([]*point, 0)
+	}
+	n := int((end-start)/int64(step)) + 1
+	if n > maxPoints {
+		n = maxPoints
+		start = end - int64(n-1)*int64(step)
+		start -= start % int64(step)
+	}
+
+	t := newTally(start, n, step)
+	h.tally(t, f, end)
+	return t.finish()
+}
+
+// window adds the job updates matching the filter between from and to up
+// into a single point, downsampled updates count towards it when their bucket
+// starts within the window
+func (h *history) window(f historyFilter, from, to time.Time) *point {
+	start, end := from.UnixNano(), to.UnixNano()
+	if end <= start {
+		return &point{At: start}
+	}
+	t := newTally(start, 1, to.Sub(from))
+	h.tally(t, f, end)
+	return t.finish()[0]
+}
+
+// tally adds the job updates matching the filter before end to the tally,
+// the status of the filter is ignored as every status is counted
+func (h *history) tally(t *tally, f historyFilter, end int64) {
+	f.Status = ""
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, seg := range h.segments {
+		if seg.start >= end {
+			continue
+		}
+		for key, r := range seg.rollups {
+			if key.at < end && f.matches(key.app, key.queueID, key.tag, key.status) {
+				t.add(key.at, key.status, r.Count, r.Depth, r.Latency)
+			}
+		}
+		for _, e := range seg.events {
+			at := e.Job.UpdatedAt
+			if at >= end {
+				break
+			}
+			if f.matches(e.App, e.QueueID, e.Job.Tag, e.Job.Status) {
+				t.add(at, e.Job.Status, 1, depthChange(e.Job), latency(e.Job))
+			}
+		}
+	}
+}
+
+// latest is the point of the current resolution step of every app, keyed by
+// app and tag with the empty tag holding the point of the whole app. It is
+// read from the running tallies kept up by record.
+func (h *history) latest() map[string]map[string]*point {
+	start := h.bucket(h.now().UnixNano())
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// the tallies only move forward, a clock set back rebuilds them
+	if start < h.liveStart {
+		h.resetLive()
+	}
+	h.advance(start)
+
+	points := make(map[string]map[string]*point, len(h.live))
+	for app, tags := range h.live {
+		points[app] = make(map[string]*point, len(tags))
+		for tag, t := range tags {
+			p := *t.finish()[0]
+			points[app][tag] = &p
+		}
+	}
+	return points
+}
+
+// jobUpdate is one status change in the history of a job
+type jobUpdate struct {
+	Status string `json:"status"`
+	Worker string `json:"worker,omitempty"`
+	At     int64  `json:"at"`
+}
+
+// jobHistory is the latest state of a job along with every update recorded
+type jobHistory struct {
+	*summary.Job
+	App     string      `json:"app"`
+	QueueID string      `json:"queue_id"`
+	Updates []jobUpdate `json:"updates"`
+}
+
+// jobs returns the most recently updated jobs matching the filter, the
+// status matches the latest status of the job. Only jobs within the raw
+// window have a history.
+func (h *history) jobs(f historyFilter, from, to time.Time, limit int) []*jobHistory {
+	start, end := from.UnixNano(), to.UnixNano()
+	byID := make(map[string]*jobHistory, 0)
+	status := f.Status
+	f.Status = ""
+
+	h.mutex.RLock()
+	for _, seg := range h.ordered(start, end) {
+		for _, e := range seg.events {
+			at := e.Job.UpdatedAt
+			if at < start || at >= end || !f.matches(e.App, e.QueueID, e.Job.Tag, e.Job.Status) {
+				continue
+			}
+			job, ok := byID[e.Job.Id]
+			if !ok {
+				job = &jobHistory{App: e.App, QueueID: e.QueueID, Updates: make([]jobUpdate, 0)}
+				byID[e.Job.Id] = job
+			}
+			job.Job = e.Job
+			job.QueueID = e.QueueID
+			job.Updates = append(job.Updates, jobUpdate{e.Job.Status, e.Job.Worker, at})
+		}
+	}
+	h.mutex.RUnlock()
+
+	jobs := make([]*jobHistory, 0, len(byID))
+	for _, job := range byID {
+		if status == "" || job.Status == status {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Sort(byLatestUpdate(jobs))
+	if limit > 0 && len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs
+}
+
+// sampled returns the samples of the queues matching the filter between from and to
+func (h *history) sampled(f historyFilter, from, to time.Time) []*sample {
+	start, end := from.UnixNano(), to.UnixNano()
+	samples := make([]*sample, 0)
+
+	h.mutex.RLock()
+	for _, seg := range h.ordered(start, end) {
+		for _, s := range seg.samples {
+			if s.At >= start && s.At < end && f.matches(s.App, s.QueueID, "", "") {
+				copied := *s
+				samples = append(samples, &copied)
+			}
+		}
+	}
+	h.mutex.RUnlock()
+	return samples
+}
+
+type byLatestUpdate []*jobHistory
+
+func (j byLatestUpdate) Len() int      { return len(j) }
+func (j byLatestUpdate) Swap(i, k int) { j[i], j[k] = j[k], j[i] }
+func (j byLatestUpdate) Less(i, k int) bool {
+	if j[i].UpdatedAt != j[k].UpdatedAt {
+		return j[i].UpdatedAt > j[k].UpdatedAt
+	}
+	return j[i].Id < j[k].Id
+}
+
+type byEventTime []*event
+
+func (e byEventTime) Len() int           { return len(e) }
+func (e byEventTime) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byEventTime) Less(i, j int) bool { return e[i].Job.UpdatedAt < e[j].Job.UpdatedAt }
+
+type bySampleTime []*sample
+
+func (s bySampleTime) Len() int           { return len(s) }
+func (s bySampleTime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s bySampleTime) Less(i, j int) bool { return s[i].At < s[j].At }
+
+type bySegmentStart []*segment
+
+func (s bySegmentStart) Len() int           { return len(s) }
+func (s bySegmentStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s bySegmentStart) Less(i, j int) bool { return s[i].start < s[j].start }
+
+type byRollupTime []*rollup
+
+func (r byRollupTime) Len() int      { return len(r) }
+func (r byRollupTime) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r byRollupTime) Less(i, j int) bool {
+	if r[i].At != r[j].At {
+		return r[i].At < r[j].At
+	}
+	return r[i].App+r[i].QueueID+r[i].Tag+r[i].Status < r[j].App+r[j].QueueID+r[j].Tag+r[j].Status
+}
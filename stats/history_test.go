@@ -0,0 +1,299 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("History", func() {
+	var (
+		dir  string
+		opts historyOptions
+		hist *history
+		base time.Time
+	)
+
+	job := func(id, tag, status string, at time.Time) *summary.Job {
+		return &summary.Job{Id: id, Tag: tag, Status: status, UpdatedAt: at.UnixNano()}
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "rift-history")
+		Expect(err).ToNot(HaveOccurred())
+
+		opts = historyOptions{
+			Dir:            dir,
+			Raw:            time.Hour,
+			Retention:      24 * time.Hour,
+			Resolution:     time.Minute,
+			SampleInterval: time.Second,
+			Segment:        5 * time.Minute,
+		}
+		hist, err = openHistory(opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		base = time.Now().Truncate(5 * time.Minute).Add(-10 * time.Minute)
+		hist.record([]*summary.Job{
+			job("1", "SampleJob", "queued", base),
+			job("1", "SampleJob", "processed", base.Add(time.Second)),
+			job("2", "FailedJob", "queued", base.Add(time.Minute)),
+			job("2", "FailedJob", "failed", base.Add(time.Minute+time.Second)),
+		}, &summary.Stats{App: "Test", QueueId: "a", ProcessedJobs: 1, FailedJobs: 1})
+		hist.record([]*summary.Job{
+			job("3", "SampleJob", "processed", base.Add(2*time.Minute)),
+		}, &summary.Stats{App: "Other", QueueId: "b", ProcessedJobs: 1})
+	})
+
+	AfterEach(func() {
+		hist.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("should count job updates per step", func() {
+		points := hist.series(historyFilter{App: "Test"}, base, base.Add(3*time.Minute), time.Minute)
+		Expect(points).To(HaveLen(4))
+		Expect(points[0].Processed).To(Equal(uint64(1)))
+		Expect(points[0].Throughput).To(BeNumerically("~", 1.0/60, 0.0001))
+		Expect(points[1].Failed).To(Equal(uint64(1)))
+		Expect(points[1].FailureRate).To(Equal(1.0))
+		Expect(points[2].Processed).To(Equal(uint64(0)))
+
+		points = hist.series(historyFilter{Tag: "SampleJob"}, base, base.Add(3*time.Minute), 5*time.Minute)
+		Expect(points).To(HaveLen(1))
+		Expect(points[0].Processed).To(Equal(uint64(2)))
+	})
+
+	It("should track the queue depth and latency of each step", func() {
+		requested := base.Add(-time.Minute)
+		update := func(id, status string, at time.Time, started bool) *summary.Job {
+			j := job(id, "SlowJob", status, at)
+			j.RequestedAt = requested.UnixNano()
+			if started {
+				j.StartedAt = at.UnixNano()
+			}
+			return j
+		}
+		hist.record([]*summary.Job{
+			update("4", "queued", requested, false),
+			update("5", "queued", requested, false),
+			update("6", "queued", requested, false),
+			update("4", "started", base, true),
+			update("4", "processed", base.Add(time.Second), true),
+			update("5", "started", base.Add(time.Minute), true),
+			update("5", "processed", base.Add(time.Minute+time.Second), true),
+			update("6", "cancelled", base.Add(2*time.Minute), false),
+		}, &summary.Stats{App: "Test", QueueId: "a"})
+
+		points := hist.series(historyFilter{Tag: "SlowJob"}, base, base.Add(3*time.Minute), time.Minute)
+		Expect(points).To(HaveLen(4))
+		Expect(points[0].Depth).To(Equal(int64(2)))
+		Expect(points[0].Latency).To(BeNumerically("~", 61, 0.001))
+		Expect(points[1].Depth).To(Equal(int64(1)))
+		Expect(points[1].Latency).To(BeNumerically("~", 121, 0.001))
+		Expect(points[2].Depth).To(Equal(int64(0)))
+		Expect(points[2].Cancelled).To(Equal(uint64(1)))
+
+		// rolled up updates keep their depth and latency
+		hist.now = func() time.Time { return base.Add(2 * time.Hour) }
+		Expect(hist.compact()).To(Succeed())
+		Expect(hist.series(historyFilter{Tag: "SlowJob"}, base, base.Add(3*time.Minute), time.Minute)).To(Equal(points))
+	})
+
+	It("should give the point of the current step for every app and tag", func() {
+		hist.now = func() time.Time { return base.Add(time.Minute + 30*time.Second) }
+
+		points := hist.latest()
+		Expect(points).To(HaveKey("Test"))
+		Expect(points["Test"]).To(HaveKey(""))
+		Expect(points["Test"]).To(HaveKey("FailedJob"))
+		Expect(points["Test"][""].At).To(Equal(base.Add(time.Minute).UnixNano()))
+		Expect(points["Test"][""].Failed).To(Equal(uint64(1)))
+		Expect(points["Test"]["FailedJob"].Failed).To(Equal(uint64(1)))
+		Expect(points["Test"]["SampleJob"].Processed).To(BeZero())
+		Expect(points["Other"]["SampleJob"].Processed).To(BeZero())
+	})
+
+	It("should keep the point of the current step up to date as updates arrive", func() {
+		now := base.Add(5 * time.Minute)
+		hist.now = func() time.Time { return now }
+		queued := func(id string, at time.Time) *summary.Job {
+			j := job(id, "SampleJob", "queued", at)
+			j.RequestedAt = at.UnixNano()
+			return j
+		}
+
+		baseline := hist.latest()["Test"]["SampleJob"].Depth
+		hist.record([]*summary.Job{queued("4", now), queued("5", now)}, &summary.Stats{App: "Test", QueueId: "a"})
+		Expect(hist.latest()["Test"]["SampleJob"].Queued).To(Equal(uint64(2)))
+		Expect(hist.latest()["Test"]["SampleJob"].Depth).To(Equal(baseline + 2))
+
+		// the depth carries over into the next step, an update ahead of the
+		// clock waits for its step
+		finished := job("4", "SampleJob", "processed", now.Add(time.Minute))
+		finished.RequestedAt = now.UnixNano()
+		hist.record([]*summary.Job{finished}, &summary.Stats{App: "Test", QueueId: "a"})
+		Expect(hist.latest()["Test"]["SampleJob"].Processed).To(BeZero())
+
+		now = now.Add(time.Minute)
+		latest := hist.latest()["Test"]["SampleJob"]
+		Expect(latest.Queued).To(BeZero())
+		Expect(latest.Processed).To(Equal(uint64(1)))
+		Expect(latest.Depth).To(Equal(baseline + 2))
+
+		series := hist.series(historyFilter{App: "Test", Tag: "SampleJob"}, now, now.Add(time.Second), time.Minute)
+		Expect(latest).To(Equal(series[0]))
+
+		// expired history leaves the running tallies the same as a series
+		now = base.Add(25 * time.Hour)
+		Expect(hist.compact()).To(Succeed())
+		Expect(hist.latest()["Test"][""].Depth).To(Equal(hist.series(historyFilter{App: "Test"}, now, now.Add(time.Second), time.Minute)[0].Depth))
+	})
+
+	It("should filter job histories by their latest status", func() {
+		jobs := hist.jobs(historyFilter{App: "Test", Status: "failed"}, base, time.Now(), 10)
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].Id).To(Equal("2"))
+		Expect(jobs[0].QueueID).To(Equal("a"))
+		Expect(jobs[0].Updates).To(HaveLen(2))
+		Expect(jobs[0].Updates[0].Status).To(Equal("queued"))
+
+		jobs = hist.jobs(historyFilter{}, base, time.Now(), 2)
+		Expect(jobs).To(HaveLen(2))
+		Expect(jobs[0].Id).To(Equal("3"))
+	})
+
+	It("should sample the stats of each queue at most once per interval", func() {
+		hist.record(nil, &summary.Stats{App: "Test", QueueId: "a", ProcessedJobs: 2})
+
+		samples := hist.sampled(historyFilter{App: "Test"}, base, time.Now().Add(time.Second))
+		Expect(samples).To(HaveLen(1))
+		Expect(samples[0].ProcessedJobs).To(Equal(uint32(1)))
+	})
+
+	It("should restore the history after reopening", func() {
+		Expect(hist.Close()).To(Succeed())
+
+		var err error
+		hist, err = openHistory(opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(hist.jobs(historyFilter{}, base, time.Now(), 10)).To(HaveLen(3))
+		Expect(hist.sampled(historyFilter{}, base, time.Now().Add(time.Second))).To(HaveLen(2))
+	})
+
+	It("should roll up events outside the raw window and expire them after the retention", func() {
+		hist.now = func() time.Time { return base.Add(2 * time.Hour) }
+		Expect(hist.compact()).To(Succeed())
+
+		Expect(hist.jobs(historyFilter{}, base, time.Now(), 10)).To(BeEmpty())
+		points := hist.series(historyFilter{App: "Test"}, base, base.Add(3*time.Minute), time.Minute)
+		Expect(points[0].Processed).To(Equal(uint64(1)))
+		Expect(points[1].Failed).To(Equal(uint64(1)))
+
+		Expect(hist.Close()).To(Succeed())
+		var err error
+		hist, err = openHistory(opts)
+		Expect(err).ToNot(HaveOccurred())
+		hist.now = func() time.Time { return base.Add(2 * time.Hour) }
+		points = hist.series(historyFilter{App: "Test"}, base, base.Add(3*time.Minute), time.Minute)
+		Expect(points[0].Processed).To(Equal(uint64(1)))
+
+		hist.now = func() time.Time { return base.Add(48 * time.Hour) }
+		Expect(hist.compact()).To(Succeed())
+		points = hist.series(historyFilter{}, base, base.Add(3*time.Minute), time.Minute)
+		for _, p := range points {
+			Expect(p.Processed + p.Failed).To(BeZero())
+		}
+	})
+	segments := func() []string {
+		paths, err := filepath.Glob(filepath.Join(dir, segmentPrefix+"*"+segmentSuffix))
+		Expect(err).ToNot(HaveOccurred())
+		return paths
+	}
+
+	It("should keep a log per segment and delete expired segments as whole files", func() {
+		Expect(segments()).ToNot(BeEmpty())
+
+		hist.now = func() time.Time { return base.Add(48 * time.Hour) }
+		Expect(hist.compact()).To(Succeed())
+		Expect(segments()).To(BeEmpty())
+		Expect(hist.segments).To(BeEmpty())
+	})
+
+	It("should only rewrite the segments leaving the raw window", func() {
+		recent := base.Add(20 * time.Minute)
+		hist.record([]*summary.Job{job("4", "SampleJob", "queued", recent)}, &summary.Stats{App: "Test", QueueId: "a"})
+		before := len(segments())
+
+		hist.now = func() time.Time { return base.Add(time.Hour + 10*time.Minute) }
+		Expect(hist.compact()).To(Succeed())
+		Expect(segments()).To(HaveLen(before))
+
+		// the old segment holds only rollups now, the recent one its events
+		Expect(hist.jobs(historyFilter{}, base, recent.Add(time.Second), 10)).To(HaveLen(1))
+		old, err := ioutil.ReadFile(hist.path(hist.segments[base.UnixNano()]))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(old)).To(ContainSubstring(`"downsampled"`))
+		Expect(string(old)).ToNot(ContainSubstring(`"event"`))
+		fresh, err := ioutil.ReadFile(hist.path(hist.segment(recent.UnixNano())))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(fresh)).To(ContainSubstring(`"event"`))
+
+		// an update arriving late for a downsampled segment is rolled up
+		hist.record([]*summary.Job{job("5", "SampleJob", "processed", base.Add(30*time.Second))}, &summary.Stats{App: "Test", QueueId: "a"})
+		points := hist.series(historyFilter{App: "Test"}, base, base.Add(time.Minute), time.Minute)
+		Expect(points[0].Processed).To(Equal(uint64(2)))
+
+		Expect(hist.Close()).To(Succeed())
+		hist, err = openHistory(opts)
+		Expect(err).ToNot(HaveOccurred())
+		hist.now = func() time.Time { return base.Add(time.Hour + 10*time.Minute) }
+		points = hist.series(historyFilter{App: "Test"}, base, base.Add(time.Minute), time.Minute)
+		Expect(points[0].Processed).To(Equal(uint64(2)))
+	})
+
+	It("should insert updates arriving out of order at their place in time", func() {
+		later := base.Add(3 * time.Minute)
+		hist.record([]*summary.Job{
+			job("4", "SampleJob", "processed", later.Add(2*time.Second)),
+			job("4", "SampleJob", "started", later.Add(time.Second)),
+			job("4", "SampleJob", "queued", later),
+		}, &summary.Stats{App: "Test", QueueId: "a"})
+
+		for _, seg := range hist.segments {
+			for i := 1; i < len(seg.events); i++ {
+				Expect(seg.events[i].Job.UpdatedAt).To(BeNumerically(">=", seg.events[i-1].Job.UpdatedAt))
+			}
+		}
+		jobs := hist.jobs(historyFilter{App: "Test", Status: "processed"}, later, later.Add(time.Minute), 10)
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].Updates[0].Status).To(Equal("queued"))
+		Expect(jobs[0].Updates[2].Status).To(Equal("processed"))
+	})
+
+	It("should refuse durations history can't be kept with", func() {
+		for _, invalid := range []historyOptions{
+			{Raw: time.Hour, Retention: time.Hour},
+			{Raw: time.Hour, Retention: time.Hour, Resolution: -time.Minute},
+			{Raw: 0, Retention: time.Hour, Resolution: time.Minute},
+			{Raw: time.Hour, Retention: 0, Resolution: time.Minute},
+			{Raw: time.Hour, Retention: time.Hour, Resolution: time.Minute, Segment: -time.Hour},
+			{Raw: time.Hour, Retention: time.Hour, Resolution: time.Minute, SampleInterval: -time.Second},
+		} {
+			_, err := openHistory(invalid)
+			Expect(err).To(HaveOccurred(), "%+v", invalid)
+		}
+
+		valid, err := openHistory(historyOptions{Raw: time.Hour, Retention: time.Hour, Resolution: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(valid.opts.Segment).To(Equal(defaultSegment))
+	})
+})
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// socketBuffer is the number of updates queued for a dashboard socket, a
+	// socket falling further behind is dropped
+	socketBuffer = 256
+	// socketWriteTimeout is how long writing a single update to a dashboard
+	// socket may take
+	socketWriteTimeout = 10 * time.Second
+)
+
+// hub fans updates out to the dashboard sockets. Every socket is written by
+// its own goroutine from its own buffer, so a slow socket can't hold up the
+// others and is dropped once its buffer fills up.
+type hub struct {
+	connections map[*websocket.Conn]*connection
+	mtx         sync.RWMutex
+}
+
+func newHub() *hub {
+	return &hub{
+		connections: make(map[*websocket.Conn]*connection, 0),
+	}
+}
+
+// connection is a dashboard socket along with the updates waiting to be
+// written to it and the updates it subscribed to
+type connection struct {
+	ws     *websocket.Conn
+	send   chan interface{}
+	closed chan struct{}
+	once   sync.Once
+
+	mtx    sync.RWMutex
+	filter eventFilter
+}
+
+// add registers a socket, the initial data is queued ahead of any broadcast
+// which can reach it
+func (h *hub) add(ws *websocket.Conn, initial interface{}) *connection {
+	c := &connection{
+		ws:     ws,
+		send:   make(chan interface{}, socketBuffer),
+		closed: make(chan struct{}),
+	}
+	c.send <- initial
+
+	h.mtx.Lock()
+	h.connections[ws] = c
+	h.mtx.Unlock()
+
+	go c.write()
+	return c
+}
+
+func (h *hub) count() int {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	return len(h.connections)
+}
+
+func (h *hub) exists(ws *websocket.Conn) bool {
+	h.mtx.RLock()
+	_, ok := h.connections[ws]
+	h.mtx.RUnlock()
+	return ok
+}
+
+// remove unregisters a socket and stops its writer, which closes the socket
+func (h *hub) remove(ws *websocket.Conn) {
+	h.mtx.Lock()
+	c, ok := h.connections[ws]
+	delete(h.connections, ws)
+	h.mtx.Unlock()
+
+	if ok {
+		c.close()
+	}
+}
+
+// broadcast queues the data for every socket without waiting on any of them
+func (h *hub) broadcast(data interface{}) {
+	var slow []*websocket.Conn
+
+	h.mtx.RLock()
+	for ws, c := range h.connections {
+		if !c.push(data) {
+			slow = append(slow, ws)
+		}
+	}
+	h.mtx.RUnlock()
+
+	for _, ws := range slow {
+		log.Println("Dropping slow socket connection")
+		h.remove(ws)
+	}
+}
+
+// push queues the data, false if the socket's buffer is full
+func (c *connection) push(data interface{}) bool {
+	select {
+	case c.send <- data:
+		return true
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *connection) subscribe(filter eventFilter) {
+	c.mtx.Lock()
+	c.filter = filter
+	c.mtx.Unlock()
+}
+
+func (c *connection) subscription() eventFilter {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.filter
+}
+
+// close stops the writer, the deadline is expired as well so a write or read
+// blocked on a slow socket fails right away
+func (c *connection) close() {
+	c.once.Do(func() {
+		close(c.closed)
+		c.ws.SetDeadline(time.Now())
+	})
+}
+
+// write sends the queued updates matching the subscription until the
+// connection is closed or a write fails, the socket is closed on the way out
+// so its reader stops as well
+func (c *connection) write() {
+	defer c.ws.Close()
+
+	for {
+		select {
+		case data := <-c.send:
+			_, update, ok := c.subscription().apply(data)
+			if !ok {
+				continue
+			}
+			c.ws.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+			if err := websocket.JSON.Send(c.ws, update); err != nil {
+				log.Printf("Socket Error: %v\n", err)
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
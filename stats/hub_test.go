@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+	"golang.org/x/net/websocket"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hub", func() {
+	var (
+		st      *state
+		clients *hub
+		server  *httptest.Server
+	)
+
+	BeforeEach(func() {
+		st = newState()
+		clients = newHub()
+		server = httptest.NewServer(websocket.Handler(socket(clients, st)))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	dial := func() *websocket.Conn {
+		ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		return ws
+	}
+
+	receive := func(ws *websocket.Conn) map[string]interface{} {
+		var msg map[string]interface{}
+		ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+		Expect(websocket.JSON.Receive(ws, &msg)).To(Succeed())
+		return msg
+	}
+
+	It("should send a snapshot followed by broadcasts", func() {
+		st.apply(queueStats("a", 1))
+		ws := dial()
+		defer ws.Close()
+
+		Expect(receive(ws)["message"]).To(Equal("snapshot"))
+		Eventually(clients.count).Should(Equal(1))
+
+		clients.broadcast(&appUpdate{Message: "app", App: "Test"})
+		Expect(receive(ws)["message"]).To(Equal("app"))
+	})
+
+	It("should only send the updates a socket subscribed to", func() {
+		ws := dial()
+		defer ws.Close()
+		Expect(receive(ws)["message"]).To(Equal("snapshot"))
+
+		Expect(websocket.JSON.Send(ws, payload{Message: "subscribe", App: "Test", Status: "failed"})).To(Succeed())
+		Expect(receive(ws)["message"]).To(Equal("snapshot"))
+
+		clients.broadcast(&summary.JobUpdate{App: "Other", QueueId: "a", Job: &summary.Job{Id: "1", Status: "failed"}})
+		clients.broadcast(&summary.JobUpdate{App: "Test", QueueId: "a", Job: &summary.Job{Id: "2", Status: "processed"}})
+		clients.broadcast(&summary.JobUpdate{App: "Test", QueueId: "a", Job: &summary.Job{Id: "3", Status: "failed"}})
+
+		msg := receive(ws)
+		Expect(msg["app"]).To(Equal("Test"))
+		Expect(msg["job"]).To(HaveKeyWithValue("id", "3"))
+	})
+
+	It("should drop a slow socket without holding up the others", func() {
+		slow := dial()
+		defer slow.Close()
+		fast := dial()
+		defer fast.Close()
+		Expect(receive(fast)["message"]).To(Equal("snapshot"))
+		Expect(websocket.JSON.Send(fast, payload{Message: "subscribe", App: "Test"})).To(Succeed())
+		Expect(receive(fast)["message"]).To(Equal("snapshot"))
+		Eventually(clients.count).Should(Equal(2))
+
+		// the slow socket never reads, its writer blocks once the
+		// connection's buffers are full and its queue fills up behind it
+		big := &appUpdate{Message: "app", App: strings.Repeat("x", 8<<20)}
+		for i := 0; i < 2*socketBuffer && clients.count() == 2; i++ {
+			clients.broadcast(big)
+			time.Sleep(time.Millisecond)
+		}
+		Eventually(clients.count).Should(Equal(1))
+
+		clients.broadcast(&appUpdate{Message: "app", App: "Test"})
+		Expect(receive(fast)["app"]).To(Equal("Test"))
+	})
+})
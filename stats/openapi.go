@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// schemas builds the JSON schemas of response types, named struct types are
+// described once in the components and referenced elsewhere
+type schemas struct {
+	components map[string]interface{}
+}
+
+var rawMessage = reflect.TypeOf(json.RawMessage{})
+
+func (s *schemas) of(t reflect.Type) map[string]interface{} {
+	if t == rawMessage {
+		// raw JSON is only used for objects whose fields depend on the job
+		return map[string]interface{}{"type": "object"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.of(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			// registered before describing the fields for recursive types
+			s.components[t.Name()] = nil
+			s.components[t.Name()] = s.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+func (s *schemas) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{}, 0)
+	s.fields(t, properties)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// fields describes the fields of a struct as encoding/json would encode them,
+// embedded structs without a name have their fields promoted
+func (s *schemas) fields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || strings.HasPrefix(f.Name, "XXX_") {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.fields(embedded, properties)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = s.of(f.Type)
+	}
+}
+
+// describe generates the OpenAPI description of the routes
+func describe(routes []*route) map[string]interface{} {
+	s := &schemas{components: make(map[string]interface{}, 0)}
+	paths := make(map[string]interface{}, 0)
+
+	for _, rt := range routes {
+		parameters := make([]interface{}, 0)
+		for _, segment := range strings.Split(strings.Trim(rt.Pattern, "/"), "/") {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+				parameters = append(parameters, map[string]interface{}{
+					"name":     segment[1 : len(segment)-1],
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		for _, p := range rt.Query {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        p.Name,
+				"in":          "query",
+				"description": p.Description,
+				"schema":      paramSchema(p.Type),
+			})
+		}
+
+		status := rt.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		responses := map[string]interface{}{
+			strconv.Itoa(status): map[string]interface{}{
+				"description": http.StatusText(status),
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": s.of(reflect.TypeOf(rt.Response))},
+				},
+			},
+		}
+		errors := rt.Errors
+		if len(rt.Query) > 0 {
+			errors = append(errors, http.StatusBadRequest)
+		}
+		if len(parameters) > len(rt.Query) {
+			errors = append(errors, http.StatusNotFound)
+		}
+		for _, code := range errors {
+			responses[strconv.Itoa(code)] = map[string]interface{}{"description": http.StatusText(code)}
+		}
+
+		path := apiPrefix + rt.Pattern
+		operations, ok := paths[path].(map[string]interface{})
+		if !ok {
+			operations = make(map[string]interface{}, 0)
+			paths[path] = operations
+		}
+		operation := map[string]interface{}{
+			"summary":    rt.Summary,
+			"parameters": parameters,
+			"responses":  responses,
+		}
+		if rt.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": s.of(reflect.TypeOf(rt.Request))},
+				},
+			}
+		}
+		operations[strings.ToLower(rt.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Rift stats server",
+			"version": "1",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": s.components},
+	}
+}
+
+func paramSchema(typ string) map[string]interface{} {
+	switch typ {
+	case "integer":
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case "date-time":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "duration":
+		return map[string]interface{}{"type": "string", "example": "1h30m"}
+	}
+	return map[string]interface{}{"type": "string"}
+}
@@ -1,23 +1,31 @@
 package main
 
 import (
+	"context"
+	cryptotls "crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"html/template"
+	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
-	"sync"
+	"path/filepath"
+	"time"
 
-	"context"
 	"golang.org/x/net/websocket"
 
 	rice "github.com/GeertJohan/go.rice"
+	"github.com/bmartel/rift/metrics"
 	"github.com/bmartel/rift/summary"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -25,95 +33,204 @@ var (
 	certFile = flag.String("cert_file", "", "The TLS cert file")
 	keyFile  = flag.String("key_file", "", "The TLS key file")
 	port     = flag.Int("port", 9147, "The server port")
-)
+	caFile   = flag.String("client_ca_file", "", "The CA file queue certificates are verified against, requires TLS and enables mutual TLS if set")
 
-type client struct {
-	connections map[*websocket.Conn]bool
-	mtx         sync.RWMutex
-}
+	token             = flag.String("token", "", "The token queues and API clients present as a bearer token, authentication is off if empty")
+	dashboardUser     = flag.String("dashboard_user", "", "The user the dashboard asks for, the dashboard is open if neither it nor a token is set")
+	dashboardPassword = flag.String("dashboard_password", "", "The password of the dashboard user")
 
-func (c *client) add(conn *websocket.Conn) {
-	c.mtx.Lock()
-	c.connections[conn] = true
-	c.mtx.Unlock()
-}
+	historyDir        = flag.String("history_dir", "history", "The directory history is persisted to, resolved to an absolute path at startup. Set it empty to keep history in memory only")
+	historyRaw        = flag.Duration("history_raw", 24*time.Hour, "How long individual job events are kept before being downsampled")
+	historyRetention  = flag.Duration("history_retention", 30*24*time.Hour, "How long downsampled history is kept")
+	historyResolution = flag.Duration("history_resolution", time.Minute, "The width of a downsampled history bucket")
+	historySample     = flag.Duration("history_sample", 10*time.Second, "The least time between two stats samples of a queue")
+	historySegment    = flag.Duration("history_segment", time.Hour, "The time window held by each history log file, history is downsampled and expires a file at a time")
 
-func (c *client) exists(conn *websocket.Conn) bool {
-	c.mtx.RLock()
-	_, ok := c.connections[conn]
-	c.mtx.RUnlock()
-	return ok
-}
+	commandTimeout = flag.Duration("command_timeout", 10*time.Second, "How long a queue has to reply to a command")
+	chartInterval  = flag.Duration("chart_interval", 5*time.Second, "How often the latest chart points are sent to dashboard clients")
 
-func (c *client) remove(conn *websocket.Conn) {
-	c.mtx.Lock()
-	if _, ok := c.connections[conn]; ok {
-		delete(c.connections, conn)
-	}
-	c.mtx.Unlock()
-}
+	instanceTimeout = flag.Duration("instance_timeout", 30*time.Second, "How long a queue instance can go without reporting or a heartbeat before it is shown offline")
+	instanceTTL     = flag.Duration("instance_ttl", time.Hour, "How long an offline or gone queue instance is kept, along with its jobs, before it is removed")
 
-func (c *client) broadcast(data interface{}) {
-	c.mtx.Lock()
-	for cl := range c.connections {
-		if err := websocket.JSON.Send(cl, data); err != nil {
-			log.Printf("Socket Error: %v\n", err)
-		}
-	}
-	c.mtx.Unlock()
-}
+	alertRules    = flag.String("alert_rules", "", "The JSON file of alert rules, alerting is off if empty")
+	alertWebhook  = flag.String("alert_webhook", "", "The URL alerts are posted to as they fire and resolve")
+	alertInterval = flag.Duration("alert_interval", 30*time.Second, "How often the alert rules are evaluated")
+)
 
+// payload is a message from a dashboard socket. A subscribe message narrows
+// the updates the socket receives to the app, tag and status it names, empty
+// fields match everything.
 type payload struct {
 	Message string `json:"message"`
+	App     string `json:"app"`
+	Tag     string `json:"tag"`
+	Status  string `json:"status"`
 }
 
 type statsServer struct {
 	statsStream chan *summary.Stats
 	jobStream   chan *summary.JobUpdate
+	appStream   chan *appUpdate
+	exporter    *metrics.Exporter
+	state       *state
+	history     *history
+	commands    *router
+}
+
+// Report receives the batched job updates and stats of a queue instance until
+// it closes the stream, the instance is gone once the stream ends
+func (s *statsServer) Report(stream summary.Summary_ReportServer) error {
+	var reports uint64
+	var app, queueID string
+	defer func() {
+		if app != "" {
+			s.state.gone(app, queueID)
+			s.appStream <- s.state.update(app)
+		}
+	}()
+
+	for {
+		report, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&summary.ReportAck{Reports: reports})
+		}
+		if err != nil {
+			return err
+		}
+		reports++
+
+		if hb := report.Heartbeat; hb != nil {
+			s.state.heartbeat(hb)
+			app, queueID = hb.App, hb.QueueId
+			s.appStream <- s.state.update(hb.App)
+		}
+		stats := report.Stats
+		if stats == nil {
+			continue
+		}
+		// the state is updated first so a client connecting in between gets
+		// a snapshot including these updates
+		s.state.apply(stats)
+		app, queueID = stats.App, stats.QueueId
+		s.history.record(report.Jobs, stats)
+		for _, job := range report.Jobs {
+			s.exporter.ObserveJob(stats.App, job)
+			s.jobStream <- &summary.JobUpdate{App: stats.App, QueueId: stats.QueueId, Job: job}
+		}
+		s.exporter.ObserveStats(stats)
+		s.statsStream <- stats
+		s.appStream <- s.state.update(stats.App)
+	}
 }
 
-func (s *statsServer) UpdateStats(ctx context.Context, stats *summary.Stats) (*summary.Stats, error) {
-	s.statsStream <- stats
-	return stats, nil
+// Enqueue creates a job on a queue instance of the app subscribed to
+// commands, the instance is picked by the server unless given
+func (s *statsServer) Enqueue(ctx context.Context, req *summary.EnqueueRequest) (*summary.EnqueueReply, error) {
+	return s.commands.enqueue(ctx, s.state, req)
 }
 
-func (s *statsServer) UpdateJob(ctx context.Context, job *summary.JobUpdate) (*summary.Job, error) {
-	s.jobStream <- job
-	return job.Job, nil
+// Commands sends the commands routed to a queue instance and receives their
+// replies, the first message from the queue identifies it
+func (s *statsServer) Commands(stream summary.Summary_CommandsServer) error {
+	hello, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if hello.App == "" || hello.QueueId == "" {
+		return status.Error(codes.InvalidArgument, "app and queue id are required")
+	}
+
+	sub := s.commands.subscribe(hello.App, hello.QueueId)
+	defer s.commands.unsubscribe(sub)
+
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			reply, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			s.commands.reply(reply)
+		}
+	}()
+
+	for {
+		select {
+		case cmd := <-sub.commands:
+			if err := stream.Send(cmd); err != nil {
+				return err
+			}
+		case err := <-errs:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
 }
 
-func setupStatsServer(statsStream chan *summary.Stats, jobStream chan *summary.JobUpdate) {
+func setupStatsServer(auth *authenticator, statsStream chan *summary.Stats, jobStream chan *summary.JobUpdate, appStream chan *appUpdate, exporter *metrics.Exporter, st *state, hist *history, commands *router) {
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
 	if err != nil {
 		grpclog.Fatalf("failed to listen: %v", err)
 	}
-	var opts []grpc.ServerOption
+	opts := auth.serverOptions()
 	if *tls {
-		creds, err := credentials.NewServerTLSFromFile(*certFile, *keyFile)
+		config, err := serverTLS(*certFile, *keyFile, *caFile)
 		if err != nil {
 			grpclog.Fatalf("Failed to generate credentials %v", err)
 		}
-		opts = []grpc.ServerOption{grpc.Creds(creds)}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
 	}
 	grpcServer := grpc.NewServer(opts...)
 	srv := new(statsServer)
 	srv.statsStream = statsStream
 	srv.jobStream = jobStream
+	srv.appStream = appStream
+	srv.exporter = exporter
+	srv.state = st
+	srv.history = hist
+	srv.commands = commands
 	summary.RegisterSummaryServer(grpcServer, srv)
 
 	grpcServer.Serve(lis)
 }
 
-func socket(clients *client) func(*websocket.Conn) {
+// serverTLS loads the server certificate, queues have to present a
+// certificate signed by the CA as well if a CA file is given
+func serverTLS(certFile, keyFile, caFile string) (*cryptotls.Config, error) {
+	cert, err := cryptotls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &cryptotls.Config{Certificates: []cryptotls.Certificate{cert}}
+	if caFile == "" {
+		return config, nil
+	}
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = cryptotls.RequireAndVerifyClientCert
+	return config, nil
+}
+
+func socket(clients *hub, st *state) func(*websocket.Conn) {
 	return func(ws *websocket.Conn) {
 		var p payload
 
-		clients.add(ws)
+		conn := clients.add(ws, st.snapshot())
 
-		defer func(conn *websocket.Conn, cl *client) {
+		defer func(ws *websocket.Conn, h *hub) {
 			log.Println("Closing socket connection")
-			cl.remove(conn)
-			conn.Close()
+			h.remove(ws)
+			ws.Close()
 		}(ws, clients)
 
 		for {
@@ -125,18 +242,88 @@ func socket(clients *client) func(*websocket.Conn) {
 			case "disconnect":
 				log.Println("Disconnected client")
 				return
+			case "subscribe":
+				conn.subscribe(eventFilter{App: p.App, Tag: p.Tag, Status: p.Status})
+				// the snapshot resets the socket to the state it subscribed to
+				if !conn.push(st.snapshot()) {
+					return
+				}
 			}
+			p = payload{}
 		}
 	}
 }
 
-func socketStream(statsStream chan *summary.Stats, jobStream chan *summary.JobUpdate, clients *client) {
+// socketStream relays the updates from queues to dashboard sockets and event
+// streams
+func socketStream(statsStream chan *summary.Stats, jobStream chan *summary.JobUpdate, appStream chan *appUpdate, clients *hub, events *events) {
 	for {
 		select {
 		case data := <-statsStream:
 			clients.broadcast(data)
+			events.publish(data)
 		case data := <-jobStream:
 			clients.broadcast(data)
+			events.publish(data)
+		case data := <-appStream:
+			clients.broadcast(data)
+			events.publish(data)
+		}
+	}
+}
+
+// seriesUpdate carries the point of the current history step of every app and
+// tag, keyed by app and tag with the empty tag holding the whole app
+type seriesUpdate struct {
+	Message string                       `json:"message"`
+	Step    int64                        `json:"step"`
+	Points  map[string]map[string]*point `json:"points"`
+}
+
+// seriesStream sends the latest chart points to dashboard clients on an
+// interval until the done channel closes
+func seriesStream(hist *history, clients *hub, interval time.Duration, done chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			clients.broadcast(&seriesUpdate{
+				Message: "series",
+				Step:    int64(hist.opts.Resolution),
+				Points:  hist.latest(),
+			})
+		case <-done:
+			return
+		}
+	}
+}
+
+// livenessStream marks queue instances which stopped sending heartbeats
+// offline and removes instances offline or gone for longer than the ttl,
+// sending the apps they belong to on to dashboard clients until the done
+// channel closes
+func livenessStream(st *state, appStream chan *appUpdate, timeout, ttl time.Duration, done chan bool) {
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, app := range st.sweep(timeout) {
+				appStream <- st.update(app)
+			}
+			for app, removed := range st.prune(ttl) {
+				update := st.update(app)
+				if update == nil {
+					update = &appUpdate{Message: "app", App: app, Instances: make(map[string]*instance, 0)}
+				}
+				update.Removed = removed
+				appStream <- update
+			}
+		case <-done:
+			return
 		}
 	}
 }
@@ -172,27 +359,93 @@ func main() {
 
 	statsStream := make(chan *summary.Stats)
 	jobStream := make(chan *summary.JobUpdate)
-	clients := &client{
-		connections: make(map[*websocket.Conn]bool, 0),
-		mtx:         sync.RWMutex{},
-	}
-	defer func(stats chan *summary.Stats, job chan *summary.JobUpdate, cl *client) {
+	appStream := make(chan *appUpdate)
+	clients := newHub()
+	defer func(stats chan *summary.Stats, job chan *summary.JobUpdate, app chan *appUpdate, cl *hub) {
 		close(stats)
 		close(job)
+		close(app)
 		cl = nil
-	}(statsStream, jobStream, clients)
+	}(statsStream, jobStream, appStream, clients)
+
+	exporter := metrics.NewExporter()
+	st := newState()
+
+	// the options leave a zero segment to the default, the flag has one
+	if *historySegment <= 0 {
+		log.Fatalf("history_segment must be positive, got %s", *historySegment)
+	}
+	var dir string
+	if *historyDir != "" {
+		abs, err := filepath.Abs(*historyDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		dir = abs
+		log.Printf("Persisting history to %s\n", dir)
+	} else {
+		log.Println("History is kept in memory only and is lost on restart")
+	}
+	hist, err := openHistory(historyOptions{
+		Dir:            dir,
+		Raw:            *historyRaw,
+		Retention:      *historyRetention,
+		Resolution:     *historyResolution,
+		SampleInterval: *historySample,
+		Segment:        *historySegment,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer hist.Close()
+	compactDone := make(chan bool)
+	defer close(compactDone)
+	go hist.compactEvery(*historyResolution, compactDone)
+
+	commands := newRouter(*commandTimeout)
+
+	var rules []*alertRule
+	if *alertRules != "" {
+		if rules, err = loadRules(*alertRules); err != nil {
+			log.Fatal(err)
+		}
+	}
+	alerts := newAlerter(rules, st, hist, *alertWebhook)
+	auth := &authenticator{token: *token, user: *dashboardUser, password: *dashboardPassword}
 
-	go setupStatsServer(statsStream, jobStream)
-	go socketStream(statsStream, jobStream, clients)
+	go setupStatsServer(auth, statsStream, jobStream, appStream, exporter, st, hist, commands)
+	events := newEvents(st)
+	go socketStream(statsStream, jobStream, appStream, clients, events)
+	seriesDone := make(chan bool)
+	defer close(seriesDone)
+	go seriesStream(hist, clients, *chartInterval, seriesDone)
+	livenessDone := make(chan bool)
+	defer close(livenessDone)
+	go livenessStream(st, appStream, *instanceTimeout, *instanceTTL, livenessDone)
+	if len(rules) > 0 {
+		alertDone := make(chan bool)
+		defer close(alertDone)
+		go alertStream(alerts, clients, events, *alertInterval, alertDone)
+	}
 
-	http.HandleFunc("/", serveTemplate(indexTmpl))
+	http.Handle("/", auth.wrap(http.HandlerFunc(serveTemplate(indexTmpl))))
 
-	http.Handle("/ws", websocket.Handler(socket(clients)))
+	http.Handle("/metrics", auth.wrap(exporter))
+
+	http.Handle("/api/v1/", auth.wrap(newAPI(st, hist, commands, alerts)))
+
+	http.Handle("/ws", auth.wrap(websocket.Handler(socket(clients, st))))
+
+	http.Handle("/events", auth.wrap(events))
 
 	box := rice.MustFindBox("static/dist")
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(box.HTTPBox())))
+	http.Handle("/static/", auth.wrap(http.StripPrefix("/static/", http.FileServer(box.HTTPBox()))))
 
 	srvPort := fmt.Sprintf(":%d", *port+1)
 	fmt.Println("Listening on port " + srvPort)
+	if *tls {
+		http.ListenAndServeTLS(srvPort, *certFile, *keyFile, nil)
+		return
+	}
 	http.ListenAndServe(srvPort, nil)
 }
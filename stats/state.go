@@ -0,0 +1,497 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bmartel/rift/summary"
+	"github.com/golang/protobuf/proto"
+)
+
+// totals are the job counters of an app summed across its queue instances
+type totals struct {
+	ActiveJobs    uint32 `json:"active_jobs"`
+	QueuedJobs    uint32 `json:"queued_jobs"`
+	ProcessedJobs uint32 `json:"processed_jobs"`
+	DeferredJobs  uint32 `json:"deferred_jobs"`
+	FailedJobs    uint32 `json:"failed_jobs"`
+	RequeuedJobs  uint32 `json:"requeued_jobs"`
+	CancelledJobs uint32 `json:"cancelled_jobs"`
+}
+
+// add sums the counters of a queue instance, the jobs of an instance which is
+// gone are no longer active
+func (t *totals) add(queue *queueState) {
+	stats := queue.stats
+	if queue.instance.Status != instanceGone {
+		t.ActiveJobs += stats.ActiveJobs
+	}
+	t.QueuedJobs += stats.QueuedJobs
+	t.ProcessedJobs += stats.ProcessedJobs
+	t.DeferredJobs += stats.DeferredJobs
+	t.FailedJobs += stats.FailedJobs
+	t.RequeuedJobs += stats.RequeuedJobs
+	t.CancelledJobs += stats.CancelledJobs
+}
+
+// Liveness of a queue instance
+const (
+	instanceOnline  = "online"
+	instanceOffline = "offline"
+	instanceGone    = "gone"
+)
+
+// instance is the liveness of a queue instance, it is online while its report
+// stream is open and gone once the stream ends. An instance which stops
+// sending heartbeats while its stream looks open is offline.
+type instance struct {
+	QueueID   string             `json:"queue_id"`
+	Status    string             `json:"status"`
+	FirstSeen int64              `json:"first_seen"`
+	LastSeen  int64              `json:"last_seen"`
+	Heartbeat *summary.Heartbeat `json:"heartbeat,omitempty"`
+}
+
+// queueJob is a job along with the queue instance holding it
+type queueJob struct {
+	*summary.Job
+	QueueID string `json:"queue_id"`
+}
+
+// appSnapshot is the state of one app as sent to dashboard clients
+type appSnapshot struct {
+	App    string                    `json:"app"`
+	Totals totals                    `json:"totals"`
+	Queues map[string]*summary.Stats `json:"queues"`
+	Jobs   map[string]*queueJob      `json:"jobs"`
+	// Instances is the liveness of every queue instance which reported
+	Instances map[string]*instance `json:"instances"`
+	// Latencies holds the latency percentiles per tag of the queue instance
+	// which processed the most jobs of that tag
+	Latencies  map[string]*summary.Latency `json:"latencies"`
+	Blueprints []*summary.JobBlueprint     `json:"blueprints"`
+}
+
+// appUpdate is the aggregate state of one app, sent to dashboard clients
+// whenever one of its queue instances reports or goes away
+type appUpdate struct {
+	Message   string               `json:"message"`
+	App       string               `json:"app"`
+	Totals    totals               `json:"totals"`
+	Instances map[string]*instance `json:"instances"`
+	// Removed lists the instances pruned since the last update, their jobs
+	// are gone from the state
+	Removed []string `json:"removed,omitempty"`
+}
+
+// snapshot is the state of every app, sent to dashboard clients when they connect
+type snapshot struct {
+	Message string                  `json:"message"`
+	Apps    map[string]*appSnapshot `json:"apps"`
+}
+
+// queueState is the latest stats of a queue instance, its jobs are kept
+// apart as reports only carry the jobs which changed
+type queueState struct {
+	stats    *summary.Stats
+	jobs     map[string]*summary.Job
+	instance instance
+}
+
+// state is the authoritative view of every app and queue instance reporting
+// to the server, built up from their reports
+type state struct {
+	mutex sync.RWMutex
+	apps  map[string]map[string]*queueState
+	now   func() time.Time
+}
+
+func newState() *state {
+	return &state{
+		apps: make(map[string]map[string]*queueState, 0),
+		now:  time.Now,
+	}
+}
+
+// apply merges a queue's reported stats into the state
+func (s *state) apply(stats *summary.Stats) {
+	if stats == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	queue := s.seen(stats.App, stats.QueueId)
+
+	for id, job := range stats.Jobs {
+		queue.jobs[id] = job
+	}
+	for _, id := range stats.EvictedJobs {
+		delete(queue.jobs, id)
+	}
+
+	queue.stats = proto.Clone(stats).(*summary.Stats)
+	queue.stats.Jobs = nil
+	queue.stats.EvictedJobs = nil
+}
+
+// heartbeat records that a queue instance is still running
+func (s *state) heartbeat(hb *summary.Heartbeat) {
+	if hb == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	queue := s.seen(hb.App, hb.QueueId)
+	queue.instance.Heartbeat = proto.Clone(hb).(*summary.Heartbeat)
+}
+
+// seen marks a queue instance online, adding it if it hasn't reported before.
+// It must be called holding the lock.
+func (s *state) seen(app, queueID string) *queueState {
+	queues, ok := s.apps[app]
+	if !ok {
+		queues = make(map[string]*queueState, 0)
+		s.apps[app] = queues
+	}
+
+	now := s.now().UnixNano()
+	queue, ok := queues[queueID]
+	if !ok {
+		queue = &queueState{
+			stats:    &summary.Stats{App: app, QueueId: queueID},
+			jobs:     make(map[string]*summary.Job, 0),
+			instance: instance{QueueID: queueID, FirstSeen: now},
+		}
+		queues[queueID] = queue
+	}
+	queue.instance.Status = instanceOnline
+	queue.instance.LastSeen = now
+	return queue
+}
+
+// sweep marks the online instances which haven't been heard from within the
+// timeout offline, returning the apps with instances which went offline
+func (s *state) sweep(timeout time.Duration) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stale := s.now().Add(-timeout).UnixNano()
+	apps := make([]string, 0)
+	for app, queues := range s.apps {
+		changed := false
+		for _, queue := range queues {
+			if queue.instance.Status == instanceOnline && queue.instance.LastSeen < stale {
+				queue.instance.Status = instanceOffline
+				changed = true
+			}
+		}
+		if changed {
+			apps = append(apps, app)
+		}
+	}
+	sort.Strings(apps)
+	return apps
+}
+
+// gone marks a queue instance which stopped reporting, its jobs and counters
+// are kept but none of its jobs count as active anymore
+func (s *state) gone(app, queueID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if queue, ok := s.apps[app][queueID]; ok {
+		queue.instance.Status = instanceGone
+	}
+}
+
+// prune removes the instances which have been offline or gone for longer
+// than the ttl along with their jobs and counters, as well as apps left
+// without instances. It returns the removed queue ids by app.
+func (s *state) prune(ttl time.Duration) map[string][]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	expired := s.now().Add(-ttl).UnixNano()
+	removed := make(map[string][]string, 0)
+	for app, queues := range s.apps {
+		for id, queue := range queues {
+			if queue.instance.Status != instanceOnline && queue.instance.LastSeen < expired {
+				delete(queues, id)
+				removed[app] = append(removed[app], id)
+			}
+		}
+		if len(queues) == 0 {
+			delete(s.apps, app)
+		}
+		sort.Strings(removed[app])
+	}
+	return removed
+}
+
+// update aggregates the counters and liveness of the queue instances of an
+// app, nil if it has never reported
+func (s *state) update(app string) *appUpdate {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	queues, ok := s.apps[app]
+	if !ok {
+		return nil
+	}
+	update := &appUpdate{
+		Message:   "app",
+		App:       app,
+		Instances: make(map[string]*instance, len(queues)),
+	}
+	for id, queue := range queues {
+		update.Totals.add(queue)
+		instance := queue.instance
+		update.Instances[id] = &instance
+	}
+	return update
+}
+
+// snapshot copies the state of every app
+func (s *state) snapshot() *snapshot {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	apps := make(map[string]*appSnapshot, len(s.apps))
+	for app := range s.apps {
+		apps[app] = s.appSnapshot(app)
+	}
+	return &snapshot{Message: "snapshot", Apps: apps}
+}
+
+// app copies the state of a single app, nil if it has never reported
+func (s *state) app(app string) *appSnapshot {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, ok := s.apps[app]; !ok {
+		return nil
+	}
+	return s.appSnapshot(app)
+}
+
+// appSnapshot must be called holding the lock
+func (s *state) appSnapshot(app string) *appSnapshot {
+	snap := &appSnapshot{
+		App:        app,
+		Queues:     make(map[string]*summary.Stats, 0),
+		Jobs:       make(map[string]*queueJob, 0),
+		Instances:  make(map[string]*instance, 0),
+		Latencies:  make(map[string]*summary.Latency, 0),
+		Blueprints: make([]*summary.JobBlueprint, 0),
+	}
+
+	blueprints := make(map[string]bool, 0)
+	for id, queue := range s.apps[app] {
+		stats := proto.Clone(queue.stats).(*summary.Stats)
+		snap.Queues[id] = stats
+		snap.Totals.add(queue)
+		instance := queue.instance
+		snap.Instances[id] = &instance
+
+		for jobID, job := range queue.jobs {
+			snap.Jobs[jobID] = &queueJob{proto.Clone(job).(*summary.Job), id}
+		}
+		for tag, latency := range stats.Latencies {
+			if current, ok := snap.Latencies[tag]; !ok || total(latency) > total(current) {
+				snap.Latencies[tag] = latency
+			}
+		}
+		for _, blueprint := range stats.JobBlueprints {
+			if !blueprints[blueprint.JobName] {
+				blueprints[blueprint.JobName] = true
+				snap.Blueprints = append(snap.Blueprints, blueprint)
+			}
+		}
+	}
+	sort.Sort(byJobName(snap.Blueprints))
+	return snap
+}
+
+type byJobName []*summary.JobBlueprint
+
+func (b byJobName) Len() int           { return len(b) }
+func (b byJobName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byJobName) Less(i, j int) bool { return b[i].JobName < b[j].JobName }
+
+func total(latency *summary.Latency) uint64 {
+	if latency.Total == nil {
+		return 0
+	}
+	return latency.Total.Count
+}
+
+// appSummary describes an app without its jobs
+type appSummary struct {
+	App    string `json:"app"`
+	Queues int    `json:"queues"`
+	// Online is the number of queue instances still reporting
+	Online int    `json:"online"`
+	Totals totals `json:"totals"`
+}
+
+// summaries describes every app, ordered by name
+func (s *state) summaries() []*appSummary {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	apps := make([]*appSummary, 0, len(s.apps))
+	for app, queues := range s.apps {
+		summary := &appSummary{App: app, Queues: len(queues)}
+		for _, queue := range queues {
+			summary.Totals.add(queue)
+			if queue.instance.Status == instanceOnline {
+				summary.Online++
+			}
+		}
+		apps = append(apps, summary)
+	}
+	sort.Sort(byApp(apps))
+	return apps
+}
+
+// queues copies the stats of every queue instance of an app without their
+// jobs, ordered by queue id
+func (s *state) queues(app string) ([]*summary.Stats, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	queues, ok := s.apps[app]
+	if !ok {
+		return nil, false
+	}
+	stats := make([]*summary.Stats, 0, len(queues))
+	for _, queue := range queues {
+		stats = append(stats, proto.Clone(queue.stats).(*summary.Stats))
+	}
+	sort.Sort(byQueueID(stats))
+	return stats, true
+}
+
+// queue copies the stats of a queue instance along with its jobs
+func (s *state) queue(app, queueID string) (*summary.Stats, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	queue, ok := s.apps[app][queueID]
+	if !ok {
+		return nil, false
+	}
+	stats := proto.Clone(queue.stats).(*summary.Stats)
+	stats.Jobs = make(map[string]*summary.Job, len(queue.jobs))
+	for id, job := range queue.jobs {
+		stats.Jobs[id] = proto.Clone(job).(*summary.Job)
+	}
+	return stats, true
+}
+
+// job gets a job held by a queue instance, with its payload and attempts
+func (s *state) job(app, queueID, id string) (*queueJob, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	queue, ok := s.apps[app][queueID]
+	if !ok {
+		return nil, false
+	}
+	job, ok := queue.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	return &queueJob{proto.Clone(job).(*summary.Job), queueID}, true
+}
+
+type byApp []*appSummary
+
+func (a byApp) Len() int           { return len(a) }
+func (a byApp) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byApp) Less(i, j int) bool { return a[i].App < a[j].App }
+
+type byQueueID []*summary.Stats
+
+func (q byQueueID) Len() int           { return len(q) }
+func (q byQueueID) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q byQueueID) Less(i, j int) bool { return q[i].QueueId < q[j].QueueId }
+
+// jobs copies the jobs of an app matching the filter, the most recently
+// updated first
+func (s *state) jobs(app string, f historyFilter) ([]*queueJob, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	queues, ok := s.apps[app]
+	if !ok {
+		return nil, false
+	}
+	jobs := make([]*queueJob, 0)
+	for id, queue := range queues {
+		for _, job := range queue.jobs {
+			if f.matches(app, id, job.Tag, job.Status) {
+				jobs = append(jobs, &queueJob{proto.Clone(job).(*summary.Job), id})
+			}
+		}
+	}
+	sort.Sort(byUpdatedAt(jobs))
+	return jobs, true
+}
+
+type byUpdatedAt []*queueJob
+
+func (j byUpdatedAt) Len() int      { return len(j) }
+func (j byUpdatedAt) Swap(i, k int) { j[i], j[k] = j[k], j[i] }
+func (j byUpdatedAt) Less(i, k int) bool {
+	if j[i].UpdatedAt != j[k].UpdatedAt {
+		return j[i].UpdatedAt > j[k].UpdatedAt
+	}
+	return j[i].Id < j[k].Id
+}
+
+// owner picks the instance of an app to create a job of the tag, among those
+// given. Instances known to have registered the tag are preferred, the one
+// with the fewest active jobs first.
+func (s *state) owner(app, tag string, queues map[string]bool) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var owner *summary.Stats
+	for id := range queues {
+		queue, ok := s.apps[app][id]
+		if !ok || !registered(queue.stats, tag) {
+			continue
+		}
+		if owner == nil || queue.stats.ActiveJobs < owner.ActiveJobs ||
+			(queue.stats.ActiveJobs == owner.ActiveJobs && id < owner.QueueId) {
+			owner = queue.stats
+		}
+	}
+	if owner != nil {
+		return owner.QueueId
+	}
+
+	// the queue validates the tag itself when no blueprint has been reported
+	var first string
+	for id := range queues {
+		if first == "" || id < first {
+			first = id
+		}
+	}
+	return first
+}
+
+func registered(stats *summary.Stats, tag string) bool {
+	for _, blueprint := range stats.JobBlueprints {
+		if blueprint.JobName == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/bmartel/rift/metrics"
+	"github.com/bmartel/rift/summary"
+	"google.golang.org/grpc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func queueStats(queueID string, processed uint32, jobs ...*summary.Job) *summary.Stats {
+	stats := &summary.Stats{
+		App:           "Test",
+		QueueId:       queueID,
+		QueuedJobs:    processed,
+		ProcessedJobs: processed,
+		Jobs:          make(map[string]*summary.Job, 0),
+		JobBlueprints: []*summary.JobBlueprint{{JobName: "SampleJob", Fields: map[string]string{"id": "int"}}},
+	}
+	for _, job := range jobs {
+		stats.Jobs[job.Id] = job
+	}
+	return stats
+}
+
+var _ = Describe("State", func() {
+	var st *state
+
+	BeforeEach(func() {
+		st = newState()
+	})
+
+	It("should sum the latest counters of every queue instance of an app", func() {
+		st.apply(queueStats("a", 1, &summary.Job{Id: "1", Status: "processed"}))
+		st.apply(queueStats("a", 2, &summary.Job{Id: "2", Status: "processed"}))
+		st.apply(queueStats("b", 3, &summary.Job{Id: "3", Status: "queued"}))
+
+		app := st.app("Test")
+		Expect(app.Totals.ProcessedJobs).To(Equal(uint32(5)))
+		Expect(app.Totals.QueuedJobs).To(Equal(uint32(5)))
+		Expect(app.Queues).To(HaveLen(2))
+		Expect(app.Jobs).To(HaveLen(3))
+		Expect(app.Jobs["3"].QueueID).To(Equal("b"))
+		Expect(app.Blueprints).To(HaveLen(1))
+	})
+
+	It("should update and evict jobs as queues report them", func() {
+		st.apply(queueStats("a", 0, &summary.Job{Id: "1", Status: "queued"}, &summary.Job{Id: "2", Status: "queued"}))
+
+		stats := queueStats("a", 1, &summary.Job{Id: "1", Status: "processed"})
+		stats.EvictedJobs = []string{"2"}
+		st.apply(stats)
+
+		app := st.app("Test")
+		Expect(app.Jobs).To(HaveLen(1))
+		Expect(app.Jobs["1"].Status).To(Equal("processed"))
+		Expect(app.Queues["a"].Jobs).To(BeEmpty())
+	})
+
+	It("should stop counting the active jobs of instances which are gone", func() {
+		a := queueStats("a", 1)
+		a.ActiveJobs = 2
+		st.apply(a)
+		b := queueStats("b", 3)
+		b.ActiveJobs = 1
+		st.apply(b)
+
+		update := st.update("Test")
+		Expect(update.Message).To(Equal("app"))
+		Expect(update.Totals.ActiveJobs).To(Equal(uint32(3)))
+		Expect(update.Instances).To(HaveLen(2))
+		Expect(update.Instances["a"].Status).To(Equal(instanceOnline))
+
+		st.gone("Test", "a")
+		update = st.update("Test")
+		Expect(update.Totals.ActiveJobs).To(Equal(uint32(1)))
+		Expect(update.Totals.ProcessedJobs).To(Equal(uint32(4)))
+		Expect(update.Instances["a"].Status).To(Equal(instanceGone))
+		Expect(st.summaries()[0].Online).To(Equal(1))
+		Expect(st.app("Test").Instances["a"].Status).To(Equal(instanceGone))
+
+		// an instance reporting again is back online
+		st.apply(a)
+		Expect(st.update("Test").Instances["a"].Status).To(Equal(instanceOnline))
+		Expect(st.update("Missing")).To(BeNil())
+	})
+
+	It("should show instances which stop sending heartbeats as offline", func() {
+		now := time.Now()
+		st.now = func() time.Time { return now }
+		st.heartbeat(&summary.Heartbeat{App: "Test", QueueId: "a", Uptime: int64(time.Minute), Workers: 4, Backlog: 2, Version: "1.0.0"})
+		a := queueStats("b", 1)
+		a.ActiveJobs = 1
+		st.apply(a)
+
+		app := st.app("Test")
+		Expect(app.Instances["a"].Status).To(Equal(instanceOnline))
+		Expect(app.Instances["a"].Heartbeat.Backlog).To(Equal(uint32(2)))
+		Expect(app.Queues["a"].App).To(Equal("Test"))
+		Expect(st.sweep(30 * time.Second)).To(BeEmpty())
+
+		now = now.Add(20 * time.Second)
+		st.heartbeat(&summary.Heartbeat{App: "Test", QueueId: "a", Uptime: int64(time.Minute + 20*time.Second)})
+		now = now.Add(20 * time.Second)
+		Expect(st.sweep(30 * time.Second)).To(Equal([]string{"Test"}))
+
+		update := st.update("Test")
+		Expect(update.Instances["a"].Status).To(Equal(instanceOnline))
+		Expect(update.Instances["b"].Status).To(Equal(instanceOffline))
+		// an offline instance may still be running its jobs
+		Expect(update.Totals.ActiveJobs).To(Equal(uint32(1)))
+		Expect(st.sweep(30 * time.Second)).To(BeEmpty())
+	})
+
+	It("should remove instances offline or gone past the ttl along with their jobs", func() {
+		now := time.Now()
+		st.now = func() time.Time { return now }
+		st.apply(queueStats("a", 1, &summary.Job{Id: "1", Status: "processed"}))
+		st.apply(queueStats("b", 1, &summary.Job{Id: "2", Status: "processed"}))
+		other := queueStats("c", 1)
+		other.App = "Other"
+		st.apply(other)
+
+		now = now.Add(time.Minute)
+		st.apply(queueStats("b", 2))
+		st.gone("Test", "a")
+		Expect(st.sweep(30 * time.Second)).To(ConsistOf("Other"))
+		Expect(st.prune(time.Hour)).To(BeEmpty())
+
+		now = now.Add(time.Hour)
+		st.apply(queueStats("b", 3))
+		Expect(st.prune(time.Hour)).To(Equal(map[string][]string{"Test": {"a"}, "Other": {"c"}}))
+
+		update := st.update("Test")
+		Expect(update.Instances).To(HaveLen(1))
+		Expect(update.Instances).To(HaveKey("b"))
+		Expect(update.Totals.ProcessedJobs).To(Equal(uint32(3)))
+		_, ok := st.job("Test", "a", "1")
+		Expect(ok).To(BeFalse())
+		Expect(st.app("Other")).To(BeNil())
+		Expect(st.summaries()).To(HaveLen(1))
+	})
+
+	It("should mark an instance gone once its report stream ends", func() {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		server := grpc.NewServer()
+		defer server.Stop()
+		hist, err := openHistory(historyOptions{Raw: time.Hour, Retention: time.Hour, Resolution: time.Minute})
+		Expect(err).ToNot(HaveOccurred())
+		srv := &statsServer{
+			statsStream: make(chan *summary.Stats, 10),
+			jobStream:   make(chan *summary.JobUpdate, 10),
+			appStream:   make(chan *appUpdate, 10),
+			exporter:    metrics.NewExporter(),
+			state:       st,
+			history:     hist,
+		}
+		summary.RegisterSummaryServer(server, srv)
+		go server.Serve(lis)
+
+		conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		stream, err := summary.NewSummaryClient(conn).Report(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stream.Send(&summary.StatsReport{Stats: queueStats("a", 1)})).To(Succeed())
+
+		var update *appUpdate
+		Eventually(srv.appStream).Should(Receive(&update))
+		Expect(update.Instances["a"].Status).To(Equal(instanceOnline))
+
+		Expect(stream.Send(&summary.StatsReport{Heartbeat: &summary.Heartbeat{App: "Test", QueueId: "a", Workers: 2}})).To(Succeed())
+		Eventually(srv.appStream).Should(Receive(&update))
+		Expect(update.Instances["a"].Heartbeat.Workers).To(Equal(uint32(2)))
+
+		_, err = stream.CloseAndRecv()
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(srv.appStream).Should(Receive(&update))
+		Expect(update.Instances["a"].Status).To(Equal(instanceGone))
+	})
+
+	It("should serve snapshots over http", func() {
+		st.apply(queueStats("a", 1, &summary.Job{Id: "1", Status: "processed"}))
+
+		rec := httptest.NewRecorder()
+		newAPI(st, nil, nil, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/state", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var snap snapshot
+		Expect(json.Unmarshal(rec.Body.Bytes(), &snap)).To(Succeed())
+		Expect(snap.Message).To(Equal("snapshot"))
+		Expect(snap.Apps).To(HaveKey("Test"))
+		Expect(snap.Apps["Test"].Totals.ProcessedJobs).To(Equal(uint32(1)))
+
+		rec = httptest.NewRecorder()
+		newAPI(st, nil, nil, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/apps/Test", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"queue_id":"a"`))
+
+		rec = httptest.NewRecorder()
+		newAPI(st, nil, nil, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/apps/Missing", nil))
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -1,6 +1,7 @@
 import m from 'mithril';
 import Layout from './components/layout';
 import Dashboard from './components/dashboard';
+import JobDetail from './components/detail';
 import './app.css';
 import './app.html';
 
@@ -9,5 +10,6 @@ m.route.prefix('');
 m.route(document.body, '/', // eslint-disable-line
   {
     '/': { view: () => m(Layout, m(Dashboard)) },
+    '/apps/:app/queues/:queue/jobs/:id': { view: vnode => m(Layout, m(JobDetail, { key: vnode.attrs.id, ...vnode.attrs })) },
   },
 );
@@ -0,0 +1,74 @@
+import m from 'mithril';
+import { includes } from 'lodash';
+import stats from '../models/job';
+import './actions.css';
+
+const finished = ['processed', 'failed', 'deferred', 'cancelled'];
+const cancellable = ['queued', 'requeued', 'started'];
+
+// run calls a command on the stats server, keeping the error of a rejected
+// command on the component until the next one
+const run = (state, command) => {
+  state.busy = true;
+  state.error = '';
+  command()
+    .then(() => {
+      state.busy = false;
+    })
+    .catch((e) => {
+      state.busy = false;
+      state.error = e.message;
+    });
+};
+
+// JobActions lists the commands which apply to a job in its current status,
+// the queue holding the job reports their outcome as job updates
+export const JobActions = {
+  oninit() {
+    this.busy = false;
+    this.error = '';
+  },
+
+  view(vnode) {
+    const { app, job } = vnode.attrs;
+    const button = (label, style, command) => m(`button.btn.btn-sm.${style}[type=button]`, {
+      disabled: this.busy,
+      onclick: () => run(this, () => command(app, job)),
+    }, label);
+
+    return m('.job-actions', [
+      job.status === 'failed' ? button('Retry', 'btn-outline-primary', stats.retryJob.bind(stats)) : null,
+      includes(cancellable, job.status) ? button('Cancel', 'btn-outline-warning', stats.cancelJob.bind(stats)) : null,
+      includes(finished, job.status) ? button('Purge', 'btn-outline-danger', stats.purgeJob.bind(stats)) : null,
+      this.error ? m('small.text-danger', this.error) : null,
+    ]);
+  },
+};
+
+// PurgeHistory removes the finished jobs of every queue of an app, either all
+// of them or only those with the chosen status
+export const PurgeHistory = {
+  oninit() {
+    this.busy = false;
+    this.error = '';
+    this.status = '';
+  },
+
+  view(vnode) {
+    const { app } = vnode.attrs;
+
+    return m('form.purge-history.form-inline.mb-3', {
+      onsubmit: (e) => {
+        e.preventDefault();
+        run(this, () => stats.purgeJobs(app, this.status));
+      },
+    }, [
+      m('select.form-control.form-control-sm', { value: this.status, onchange: (e) => { this.status = e.target.value; } }, [
+        m('option', { value: '' }, 'All finished jobs'),
+        finished.map(status => m('option', { value: status }, status)),
+      ]),
+      m('button.btn.btn-sm.btn-outline-danger[type=submit]', { disabled: this.busy }, 'Purge history'),
+      this.error ? m('small.text-danger', this.error) : null,
+    ]);
+  },
+};
@@ -0,0 +1,42 @@
+import m from 'mithril';
+import { map, sortBy } from 'lodash';
+import './alerts.css';
+
+const labels = {
+  failure_rate: 'Failure rate',
+  depth: 'Queue depth',
+  latency: 'Latency',
+  offline: 'Instance offline',
+};
+
+const value = (alert) => {
+  switch (alert.kind) {
+    case 'failure_rate':
+      return `${(alert.value * 100).toFixed(1)}% over ${(alert.threshold * 100).toFixed(1)}%`;
+    case 'latency':
+      return `${alert.value.toFixed(2)}s over ${alert.threshold}s`;
+    case 'offline':
+      return `offline for ${Math.round(alert.value)}s`;
+    default:
+      return `${alert.value} over ${alert.threshold}`;
+  }
+};
+
+// Alerts lists the alert rules firing for an app
+const Alerts = {
+  view(vnode) {
+    const alerts = sortBy(vnode.attrs.alerts, 'since');
+    if (!alerts.length) return null;
+
+    return m('.alerts', map(alerts, alert => m('.alert.alert-danger', { key: alert.id }, [
+      m('strong', `${labels[alert.kind] || alert.kind}: `),
+      alert.rule,
+      alert.tag ? ` (${alert.tag})` : '',
+      alert.queue_id ? ` on ${alert.queue_id}` : '',
+      m('span.alert-value', value(alert)),
+      m('small.alert-since', `since ${new Date(alert.since / 1e6).toLocaleTimeString()}`),
+    ])));
+  },
+};
+
+export default Alerts;
@@ -0,0 +1,109 @@
+import m from 'mithril';
+import { map, includes, keys } from 'lodash';
+import stats from '../models/job';
+import './blueprints.css';
+
+const integerKinds = ['int', 'int8', 'int16', 'int32', 'int64', 'uint', 'uint8', 'uint16', 'uint32', 'uint64'];
+const floatKinds = ['float32', 'float64'];
+const textKinds = ['string'];
+
+// input renders the control matching a field kind, kinds without one of their
+// own are entered as JSON
+const input = (kind, value, onchange) => {
+  if (kind === 'bool') {
+    return m('input.form-check-input[type=checkbox]', { checked: !!value, onchange: e => onchange(e.target.checked) });
+  }
+  if (includes(integerKinds, kind)) {
+    return m('input.form-control[type=number]', { step: 1, min: kind.indexOf('uint') === 0 ? 0 : undefined, value, oninput: e => onchange(e.target.value) });
+  }
+  if (includes(floatKinds, kind)) {
+    return m('input.form-control[type=number]', { step: 'any', value, oninput: e => onchange(e.target.value) });
+  }
+  if (includes(textKinds, kind)) {
+    return m('input.form-control[type=text]', { value, oninput: e => onchange(e.target.value) });
+  }
+  return m('textarea.form-control', { rows: 2, placeholder: `${kind} as JSON`, value, oninput: e => onchange(e.target.value) });
+};
+
+// fieldValue converts an entered value to the JSON sent for its kind, number
+// fields left empty are sent as their zero value
+const fieldValue = (kind, value) => {
+  if (kind === 'bool') return !!value;
+  if (includes(integerKinds, kind) || includes(floatKinds, kind)) return value === undefined || value === '' ? 0 : Number(value);
+  if (includes(textKinds, kind)) return value || '';
+  return value ? JSON.parse(value) : null;
+};
+
+// JobForm creates a job of one blueprint through the stats server, which
+// routes it to a queue of the app that registered it
+const JobForm = {
+  oninit() {
+    this.values = {};
+    this.retry = 0;
+    this.error = '';
+    this.busy = false;
+  },
+
+  submit(app, blueprint) {
+    const data = {};
+    try {
+      map(blueprint.fields, (kind, name) => {
+        data[name] = fieldValue(kind, this.values[name]);
+      });
+    } catch (e) {
+      this.error = e.message;
+      return;
+    }
+
+    this.busy = true;
+    this.error = '';
+    stats.createJob(app, blueprint.job_name, data, Number(this.retry) || 0)
+      .then(() => {
+        this.busy = false;
+      })
+      .catch((e) => {
+        this.busy = false;
+        this.error = e.message;
+      });
+  },
+
+  view(vnode) {
+    const { app, blueprint } = vnode.attrs;
+    const names = keys(blueprint.fields).sort();
+
+    return m('form.blueprint.card.mb-3', {
+      onsubmit: (e) => {
+        e.preventDefault();
+        this.submit(app, blueprint);
+      },
+    }, m('.card-block', [
+      m('h4.card-title', blueprint.job_name),
+      map(names, name => m('.form-group', { key: name }, [
+        m('label', [name, m('small.text-muted', ` ${blueprint.fields[name]}`)]),
+        input(blueprint.fields[name], this.values[name], (value) => { this.values[name] = value; }),
+      ])),
+      m('.form-group', [
+        m('label', 'Retries'),
+        m('input.form-control[type=number]', { min: 0, max: 255, step: 1, value: this.retry, oninput: (e) => { this.retry = e.target.value; } }),
+      ]),
+      this.error ? m('.alert.alert-danger', this.error) : null,
+      m('button.btn.btn-primary[type=submit]', { disabled: this.busy }, 'Create job'),
+    ]));
+  },
+};
+
+// Blueprints lists a form for every job registered by the queues of an app
+const Blueprints = {
+  view(vnode) {
+    const { app, blueprints } = vnode.attrs;
+    if (!blueprints || !blueprints.length) return null;
+
+    return m('.blueprints', [
+      m('h3', 'Create a job'),
+      m('.blueprint-forms', map(blueprints, blueprint =>
+        m(JobForm, { key: blueprint.job_name, app, blueprint }))),
+    ]);
+  },
+};
+
+export default Blueprints;
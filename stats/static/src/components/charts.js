@@ -0,0 +1,128 @@
+import m from 'mithril';
+import { map, max, last } from 'lodash';
+import stats from '../models/job';
+import './charts.css';
+
+const minute = 60e9;
+
+// windows are the time ranges a chart can show, each split into steps wide
+// enough to keep the charts readable
+const windows = [
+  { label: '15m', window: '15m', step: '1m', nanos: minute },
+  { label: '1h', window: '1h', step: '1m', nanos: minute },
+  { label: '6h', window: '6h', step: '5m', nanos: 5 * minute },
+  { label: '24h', window: '24h', step: '15m', nanos: 15 * minute },
+  { label: '7d', window: '168h', step: '2h', nanos: 120 * minute },
+];
+
+const seconds = (value) => {
+  if (!value) return '0ms';
+  if (value < 1) return `${(value * 1000).toFixed(1)}ms`;
+  return `${value.toFixed(2)}s`;
+};
+
+const charts = [
+  { key: 'throughput', title: 'Throughput', format: value => `${(value || 0).toFixed(2)}/s` },
+  { key: 'failure_rate', title: 'Failure rate', format: value => `${((value || 0) * 100).toFixed(1)}%` },
+  { key: 'depth', title: 'Queue depth', format: value => `${value || 0}` },
+  { key: 'latency', title: 'Latency', format: seconds },
+];
+
+// line draws the values of a series on a 100 by 40 canvas scaled to its peak
+const line = (points, key) => {
+  const peak = max(map(points, key)) || 1;
+  const width = Math.max(points.length - 1, 1);
+  return map(points, (p, i) => `${(100 * i) / width},${40 - ((40 * (p[key] || 0)) / peak)}`).join(' ');
+};
+
+const Chart = {
+  view(vnode) {
+    const { chart, points } = vnode.attrs;
+    const latest = last(points) || {};
+
+    return m('.chart.card', m('.card-block', [
+      m('.chart-title', [m('span', chart.title), m('span.chart-value', chart.format(latest[chart.key]))]),
+      m('svg.chart-line', { viewBox: '0 0 100 40', preserveAspectRatio: 'none' },
+        m('polyline', { points: line(points, chart.key) })),
+    ]));
+  },
+};
+
+// Charts plots the throughput, failure rate, queue depth and latency of an app
+// or one of its tags over a chosen window. Points of the current step arrive
+// over the socket, a window with wider steps is fetched again once the
+// current step has passed.
+const Charts = {
+  oninit(vnode) {
+    this.app = vnode.attrs.app;
+    this.window = windows[1];
+    this.tag = '';
+    this.points = [];
+    this.error = '';
+    this.fetch();
+    this.unsubscribe = stats.subscribeSeries(this.live.bind(this));
+  },
+
+  onremove() {
+    this.unsubscribe();
+  },
+
+  fetch() {
+    stats.fetchSeries(this.app, this.tag, this.window.window, this.window.step)
+      .then((points) => {
+        this.points = points;
+        this.error = '';
+      })
+      .catch((e) => {
+        this.error = e.message;
+      });
+  },
+
+  live(update) {
+    const point = (update.points[this.app] || {})[this.tag];
+    const latest = last(this.points);
+    if (!point || !latest) return;
+
+    if (update.step !== this.window.nanos) {
+      if (point.at >= latest.at + this.window.nanos) this.fetch();
+      return;
+    }
+    if (point.at === latest.at) {
+      this.points = [...this.points.slice(0, -1), point];
+    } else if (point.at > latest.at) {
+      this.points = [...this.points.slice(1), point];
+    }
+  },
+
+  select(window, tag) {
+    this.window = window;
+    this.tag = tag;
+    this.points = [];
+    this.fetch();
+  },
+
+  view(vnode) {
+    const { tags } = vnode.attrs;
+
+    return m('.charts', [
+      m('.charts-controls', [
+        m('h3', 'Activity'),
+        m('.btn-group.btn-group-sm', map(windows, w => m('button.btn[type=button]', {
+          class: w === this.window ? 'btn-primary' : 'btn-secondary',
+          onclick: () => this.select(w, this.tag),
+        }, w.label))),
+        m('select.form-control.form-control-sm', {
+          value: this.tag,
+          onchange: e => this.select(this.window, e.target.value),
+        }, [
+          m('option', { value: '' }, 'All tags'),
+          map(tags, tag => m('option', { value: tag }, tag)),
+        ]),
+      ]),
+      this.error ? m('.alert.alert-danger', this.error) : null,
+      m('.charts-grid', map(charts, chart => m(Chart, { key: chart.key, chart, points: this.points }))),
+    ]);
+  },
+};
+
+export default Charts;
@@ -1,6 +1,12 @@
 import m from 'mithril';
-import { map } from 'lodash';
+import { map, union, keys, filter } from 'lodash';
 import stats from '../models/job';
+import Latency from './latency';
+import Charts from './charts';
+import Instances from './instances';
+import Alerts from './alerts';
+import Blueprints from './blueprints';
+import { JobActions, PurgeHistory } from './actions';
 import './dashboard.css';
 
 const Dashboard = {
@@ -15,14 +21,27 @@ const Dashboard = {
       map(this.vm.apps(), (app, name) => [
         m('h2', name),
 
+        m(Alerts, { alerts: filter(this.vm.alerts(), { app: name }) }),
+
         m('.row', [
           m('.col', m('.card.card-inverse.card-info.mb-3.text-center', m('.card-block.d-flex.flex-column.justify-content-center', [m('span.text-white', 'Active'), m('span', app.totals.active_jobs)]))),
           m('.col', m('.card.card-inverse.card-primary.mb-3.text-center', m('.card-block.d-flex.flex-column.justify-content-center', [m('span.text-white', 'Queued'), m('span', app.totals.queued_jobs)]))),
           m('.col', m('.card.card-inverse.card-success.mb-3.text-center', m('.card-block.d-flex.flex-column.justify-content-center', [m('span.text-white', 'Processed'), m('span', app.totals.processed_jobs)]))),
           m('.col', m('.card.card-inverse.card-danger.mb-3.text-center', m('.card-block.d-flex.flex-column.justify-content-center', [m('span.text-white', 'Failed'), m('span', app.totals.failed_jobs)]))),
           m('.col', m('.card.card-inverse.card-warning.mb-3.text-center', m('.card-block.d-flex.flex-column.justify-content-center', [m('span.text-white', 'Retried'), m('span', app.totals.requeued_jobs)]))),
+          m('.col', m('.card.card-inverse.card-secondary.mb-3.text-center', m('.card-block.d-flex.flex-column.justify-content-center', [m('span', 'Cancelled'), m('span', app.totals.cancelled_jobs)]))),
         ]),
 
+        m(Instances, { instances: app.instances }),
+
+        m(Charts, { key: name, app: name, tags: union(map(app.blueprints, 'job_name'), keys(app.latencies)).sort() }),
+
+        m(Latency, { latencies: app.latencies }),
+
+        m(Blueprints, { app: name, blueprints: app.blueprints }),
+
+        m(PurgeHistory, { app: name }),
+
         m('table.table', [
           m('thead.thead-default',
             m('tr', [
@@ -30,14 +49,16 @@ const Dashboard = {
               m('th', 'Queue'),
               m('th', 'Job Tag'),
               m('th', 'Status'),
+              m('th', 'Actions'),
             ]),
           ),
           m('tbody',
-            map(app.jobs, job => m('tr', { key: job.id }, [
-              m('td', job.id),
+            map(app.jobs, job => m('tr', { key: job.id, class: job.id === this.vm.created()[name] ? 'table-success' : '' }, [
+              m('td', m('a', { href: `/apps/${encodeURIComponent(name)}/queues/${encodeURIComponent(job.queue_id)}/jobs/${job.id}`, oncreate: m.route.link }, job.id)),
               m('td', job.queue_id),
               m('td', job.tag),
               m('td', job.status),
+              m('td', m(JobActions, { app: name, job })),
             ])),
           ),
         ]),
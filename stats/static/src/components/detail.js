@@ -0,0 +1,93 @@
+import m from 'mithril';
+import { map } from 'lodash';
+import stats from '../models/job';
+import { JobActions } from './actions';
+import './detail.css';
+
+const time = nanos => (nanos ? new Date(nanos / 1e6).toLocaleString() : '');
+
+const duration = (nanos) => {
+  if (!nanos) return '0ms';
+  const seconds = nanos / 1e9;
+  if (seconds < 1) return `${(seconds * 1000).toFixed(1)}ms`;
+  return `${seconds.toFixed(2)}s`;
+};
+
+// payload pretty prints the job values, falling back to the raw payload if it
+// isn't valid JSON
+const payload = (data) => {
+  try {
+    return JSON.stringify(JSON.parse(data), null, 2);
+  } catch (e) {
+    return data;
+  }
+};
+
+// JobDetail shows the payload of a job and every attempt to process it, kept
+// up to date with the updates of its queue. A job not held by the dashboard
+// yet is fetched from the stats server.
+const JobDetail = {
+  oninit(vnode) {
+    const { app, queue, id } = vnode.attrs;
+    this.fetched = null;
+    this.error = '';
+    stats.fetchJob(app, queue, id)
+      .then((job) => {
+        this.fetched = job;
+      })
+      .catch((e) => {
+        this.error = e.message;
+      });
+  },
+
+  view(vnode) {
+    const { app, id } = vnode.attrs;
+    const held = (stats.apps()[app] || {}).jobs || {};
+    const job = held[id] || this.fetched;
+
+    return m('.job-detail', [
+      m('a', { href: '/', oncreate: m.route.link }, 'Back to the dashboard'),
+      m('h1', id),
+      !job ? m('.alert.alert-warning', this.error || 'Loading job') : [
+        m('dl.row', [
+          m('dt.col-sm-2', 'App'), m('dd.col-sm-10', app),
+          m('dt.col-sm-2', 'Queue'), m('dd.col-sm-10', job.queue_id),
+          m('dt.col-sm-2', 'Job Tag'), m('dd.col-sm-10', job.tag),
+          m('dt.col-sm-2', 'Status'), m('dd.col-sm-10', job.status),
+          m('dt.col-sm-2', 'Requested'), m('dd.col-sm-10', time(job.requested_at)),
+          m('dt.col-sm-2', 'Updated'), m('dd.col-sm-10', time(job.updated_at)),
+        ]),
+        m(JobActions, { app, job }),
+
+        m('h3', 'Payload'),
+        m('pre.job-payload', payload(job.payload || '{}')),
+
+        m('h3', 'Attempts'),
+        m('table.table', [
+          m('thead.thead-default',
+            m('tr', [
+              m('th', '#'),
+              m('th', 'Worker'),
+              m('th', 'Started'),
+              m('th', 'Finished'),
+              m('th', 'Duration'),
+              m('th', 'Error'),
+            ]),
+          ),
+          m('tbody',
+            map(job.attempts, (attempt, i) => m('tr', { key: i, class: attempt.error ? 'table-danger' : '' }, [
+              m('td', i + 1),
+              m('td', attempt.worker),
+              m('td', time(attempt.started_at)),
+              m('td', attempt.finished_at ? time(attempt.finished_at) : 'running'),
+              m('td', attempt.finished_at ? duration(attempt.duration) : ''),
+              m('td.job-error', attempt.error),
+            ])),
+          ),
+        ]),
+      ],
+    ]);
+  },
+};
+
+export default JobDetail;
@@ -0,0 +1,67 @@
+import m from 'mithril';
+import { map, sortBy, filter } from 'lodash';
+import './instances.css';
+
+const badges = {
+  online: 'badge-success',
+  offline: 'badge-warning',
+  gone: 'badge-default',
+};
+
+const time = nanos => (nanos ? new Date(nanos / 1e6).toLocaleString() : '');
+
+// uptime formats a duration in nanoseconds down to the second
+const uptime = (nanos) => {
+  let seconds = Math.floor((nanos || 0) / 1e9);
+  const days = Math.floor(seconds / 86400);
+  seconds %= 86400;
+  const hours = Math.floor(seconds / 3600);
+  seconds %= 3600;
+  const minutes = Math.floor(seconds / 60);
+  seconds %= 60;
+  if (days) return `${days}d ${hours}h`;
+  if (hours) return `${hours}h ${minutes}m`;
+  if (minutes) return `${minutes}m ${seconds}s`;
+  return `${seconds}s`;
+};
+
+// Instances lists the queue instances of an app and whether they are still
+// reporting to the stats server, along with their latest heartbeat
+const Instances = {
+  view(vnode) {
+    const instances = sortBy(vnode.attrs.instances, 'queue_id');
+    if (!instances.length) return null;
+    const online = filter(instances, { status: 'online' }).length;
+
+    return m('.instances', [
+      m('h3', `Queue instances (${online} of ${instances.length} online)`),
+      m('table.table.table-sm', [
+        m('thead.thead-default',
+          m('tr', [
+            m('th', 'Queue'),
+            m('th', 'Status'),
+            m('th', 'Uptime'),
+            m('th', 'Workers'),
+            m('th', 'Backlog'),
+            m('th', 'Version'),
+            m('th', 'Last seen'),
+          ]),
+        ),
+        m('tbody', map(instances, (instance) => {
+          const heartbeat = instance.heartbeat || {};
+          return m('tr', { key: instance.queue_id, class: instance.status === 'online' ? '' : 'instance-gone' }, [
+            m('td', instance.queue_id),
+            m('td', m(`span.badge.${badges[instance.status] || 'badge-default'}`, instance.status)),
+            m('td', instance.heartbeat ? uptime(heartbeat.uptime) : ''),
+            m('td', instance.heartbeat ? `${(heartbeat.workers || 0) - (heartbeat.idle_workers || 0)} busy of ${heartbeat.workers || 0}` : ''),
+            m('td', instance.heartbeat ? heartbeat.backlog || 0 : ''),
+            m('td', heartbeat.version || ''),
+            m('td', { title: `First seen ${time(instance.first_seen)}` }, time(instance.last_seen)),
+          ]);
+        })),
+      ]),
+    ]);
+  },
+};
+
+export default Instances;
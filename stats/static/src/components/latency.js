@@ -0,0 +1,50 @@
+import m from 'mithril';
+import { map, max, flatMap } from 'lodash';
+import './latency.css';
+
+const stages = [
+  { key: 'wait', label: 'Wait' },
+  { key: 'run', label: 'Run' },
+  { key: 'total', label: 'Total' },
+];
+
+const quantiles = ['p50', 'p95', 'p99'];
+
+const format = (seconds) => {
+  if (!seconds) return '0ms';
+  if (seconds < 1) return `${(seconds * 1000).toFixed(1)}ms`;
+  return `${seconds.toFixed(2)}s`;
+};
+
+const bar = (seconds, scale, quantile) => m(`.latency-bar.latency-${quantile}`, {
+  style: { width: `${scale ? (100 * (seconds || 0)) / scale : 0}%` },
+  title: `${quantile} ${format(seconds)}`,
+});
+
+// Latency charts the wait, run and total latency percentiles of every job tag
+// on a shared scale so tags can be compared
+const Latency = {
+  view(vnode) {
+    const latencies = vnode.attrs.latencies || {};
+    const scale = max(flatMap(latencies, latency =>
+      map(stages, stage => (latency[stage.key] || {}).p99 || 0))) || 0;
+
+    return m('.latency', [
+      m('h3', 'Latency'),
+      m('.latency-legend', map(quantiles, q => m(`span.latency-key.latency-${q}`, q))),
+      map(latencies, (latency, tag) => m('.latency-tag', { key: tag }, [
+        m('h4', tag),
+        map(stages, (stage) => {
+          const percentiles = latency[stage.key] || {};
+          return m('.latency-row', [
+            m('span.latency-label', stage.label),
+            m('.latency-bars', map(quantiles, q => bar(percentiles[q], scale, q))),
+            m('span.latency-value', `${format(percentiles.p50)} / ${format(percentiles.p95)} / ${format(percentiles.p99)}`),
+          ]);
+        }),
+      ])),
+    ]);
+  },
+};
+
+export default Latency;
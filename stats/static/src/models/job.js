@@ -1,49 +1,31 @@
 /* eslint-disable no-undef*/
 import m from 'mithril';
 import prop from 'mithril/stream';
+import { mapValues, unionBy, sortBy, omit, omitBy, keyBy } from 'lodash';
 
 export class Job {
   constructor() {
     this.apps = prop({});
     this.error = prop('');
+    // the id of the job last created from the dashboard, by app
+    this.created = prop({});
+    // listeners of the chart points sent for the current history step
+    this.seriesListeners = [];
+    // the alerts which are firing, by id
+    this.alerts = prop({});
 
     const wsScheme = (window.location.protocol === 'https:') ? 'wss://' : 'ws://';
     this.socket = new WebSocket(`${wsScheme}${window.location.host}/ws`);
-    // this.socket.onopen = this.init.bind(this);
     this.socket.onmessage = this.receivedUpdate.bind(this);
     this.socket.onerror = this.receivedError.bind(this);
     window.addEventListener('unload', this.disconnect.bind(this));
+    this.fetchAlerts();
   }
 
   disconnect() {
     this.socket.send({ message: 'disconnect' });
   }
 
-  // init() {
-  //   this.apps = prop({});
-  //   this.error = prop('');
-  //
-  //   // Need to send request to get current state on servers
-  //   const apps = this.apps()[stats.app] || {};
-  //   const totals = apps.totals || {};
-  //   const jobs = apps.jobs || {};
-  //   return {
-  //     totals: {
-  //       ...totals,
-  //       active_jobs: stats.active_jobs || 0,
-  //       queued_jobs: (stats.queued_jobs || 0) + (totals.queued_jobs || 0),
-  //       requeued_jobs: (stats.requeued_jobs || 0) + (totals.requeued_jobs || 0),
-  //       failed_jobs: (stats.failed_jobs || 0) + (totals.failed_jobs || 0),
-  //       processed_jobs: (stats.processed_jobs || 0) + (totals.processed_jobs || 0),
-  //     },
-  //     jobs: {
-  //       ...jobs,
-  //       ...stats.jobs,
-  //     },
-  //   };
-  //   m.redraw();
-  // }
-
   static initialAppTotals(totals) {
     return totals || {
       active_jobs: 0,
@@ -51,43 +33,99 @@ export class Job {
       processed_jobs: 0,
       failed_jobs: 0,
       requeued_jobs: 0,
+      cancelled_jobs: 0,
     };
   }
 
+  // updateApps applies a job update or the stats of a queue instance to its
+  // app, the totals are left to the aggregates sent by the server as a job
+  // goes through several statuses across instances
   updateApps(stats) {
     const app = this.apps()[stats.app] || {};
     const totals = Job.initialAppTotals(app.totals);
+    const instances = app.instances || {};
     const jobs = app.jobs || {};
+    const latencies = app.latencies || {};
+    const blueprints = app.blueprints || [];
 
     if (stats.job) {
       const job = stats.job;
       job.queue_id = stats.queue_id;
 
       return {
-        totals: {
-          ...totals,
-          [`${job.status}_jobs`]: (totals[`${job.status}_jobs`] || 0) + 1,
-        },
+        ...app,
+        totals,
+        instances,
         jobs: {
           ...jobs,
           [job.id]: job,
         },
+        latencies,
+        blueprints,
       };
     }
 
-    const { active_jobs } = stats;
+    if (stats.message === 'app') {
+      // instances removed by the server take their jobs with them
+      const removed = stats.removed || [];
+      return {
+        ...app,
+        totals: Job.initialAppTotals(stats.totals),
+        instances: stats.instances || {},
+        queues: omit(app.queues, removed),
+        jobs: omitBy(jobs, job => removed.indexOf(job.queue_id) !== -1),
+        latencies,
+        blueprints,
+      };
+    }
 
     return {
-      jobs,
-      totals: {
-        ...totals,
-        active_jobs: active_jobs || 0, //eslint-disable-line
+      ...app,
+      totals,
+      instances,
+      jobs: omit(jobs, stats.evicted_jobs || []),
+      queues: {
+        ...app.queues,
+        [stats.queue_id]: stats,
+      },
+      latencies: {
+        ...latencies,
+        ...stats.latencies,
       },
+      blueprints: sortBy(unionBy(blueprints, stats.job_blueprints || [], 'job_name'), 'job_name'),
     };
   }
 
+  // receivedSnapshot replaces every app with the state held by the server,
+  // sent when the socket connects
+  receivedSnapshot(snapshot) {
+    this.apps(mapValues(snapshot.apps, app => ({
+      totals: Job.initialAppTotals(app.totals),
+      instances: app.instances || {},
+      jobs: app.jobs || {},
+      latencies: app.latencies || {},
+      queues: app.queues || {},
+      blueprints: app.blueprints || [],
+    })));
+    m.redraw();
+  }
+
   receivedUpdate(event) {
     const stats = JSON.parse(event.data);
+    if (stats.message === 'snapshot') {
+      this.receivedSnapshot(stats);
+      return;
+    }
+    if (stats.message === 'alert') {
+      this.receivedAlert(stats);
+      return;
+    }
+    if (stats.message === 'series') {
+      this.seriesListeners.forEach(fn => fn(stats));
+      m.redraw();
+      return;
+    }
+
     this.apps({
       ...this.apps(),
       [stats.app]: this.updateApps(stats),
@@ -95,6 +133,96 @@ export class Job {
     m.redraw();
   }
 
+  // receivedAlert keeps an alert while it fires and drops it once resolved
+  receivedAlert(alert) {
+    this.alerts(alert.state === 'firing'
+      ? { ...this.alerts(), [alert.id]: alert }
+      : omit(this.alerts(), alert.id));
+    m.redraw();
+  }
+
+  // fetchAlerts gets the alerts already firing when the dashboard loads,
+  // later changes arrive over the socket
+  fetchAlerts() {
+    return m.request({
+      method: 'GET',
+      url: '/api/v1/alerts',
+    }).then((alerts) => {
+      this.alerts({ ...keyBy(alerts, 'id'), ...this.alerts() });
+    });
+  }
+
+  // createJob sends a job through the stats server to a queue of the app, the
+  // job then shows up with the updates of that queue
+  createJob(app, tag, data, retry) {
+    return m.request({
+      method: 'POST',
+      url: `/api/v1/apps/${encodeURIComponent(app)}/jobs`,
+      data: { tag, data, retry },
+    }).then((reply) => {
+      this.created({ ...this.created(), [app]: reply.id });
+      return reply;
+    });
+  }
+
+  // fetchSeries gets the activity of an app, or one tag of it, in steps over a
+  // window back from now
+  fetchSeries(app, tag, window, step) {
+    return m.request({
+      method: 'GET',
+      url: '/api/v1/history/series',
+      data: { app, tag, window, step },
+    });
+  }
+
+  // subscribeSeries calls fn with the chart points of every app and tag sent
+  // for the current history step, returning a function which unsubscribes
+  subscribeSeries(fn) {
+    this.seriesListeners.push(fn);
+    return () => {
+      this.seriesListeners = this.seriesListeners.filter(listener => listener !== fn);
+    };
+  }
+
+  // fetchJob gets a job with its payload and attempts from the stats server
+  fetchJob(app, queue, id) {
+    return m.request({
+      method: 'GET',
+      url: `/api/v1/apps/${encodeURIComponent(app)}/queues/${encodeURIComponent(queue)}/jobs/${encodeURIComponent(id)}`,
+    });
+  }
+
+  // jobCommand sends a command for a job to the queue instance holding it,
+  // the outcome shows up with the updates of that queue
+  jobCommand(method, app, job, action) {
+    const queue = `/api/v1/apps/${encodeURIComponent(app)}/queues/${encodeURIComponent(job.queue_id)}`;
+    return m.request({
+      method,
+      url: `${queue}/jobs/${encodeURIComponent(job.id)}${action ? `/${action}` : ''}`,
+    });
+  }
+
+  retryJob(app, job) {
+    return this.jobCommand('POST', app, job, 'retry');
+  }
+
+  cancelJob(app, job) {
+    return this.jobCommand('POST', app, job, 'cancel');
+  }
+
+  purgeJob(app, job) {
+    return this.jobCommand('DELETE', app, job);
+  }
+
+  // purgeJobs removes the finished jobs of every queue of the app, or only
+  // those with the status if given
+  purgeJobs(app, status) {
+    return m.request({
+      method: 'DELETE',
+      url: `/api/v1/apps/${encodeURIComponent(app)}/jobs${status ? `?status=${encodeURIComponent(status)}` : ''}`,
+    });
+  }
+
   receivedError(event) {
     this.error(event.data);
     m.redraw();
@@ -24,20 +24,26 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Job struct {
-	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Tag                  string   `protobuf:"bytes,2,opt,name=tag,proto3" json:"tag,omitempty"`
-	Status               string   `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
-	Worker               string   `protobuf:"bytes,4,opt,name=worker,proto3" json:"worker,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Id                   string     `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Tag                  string     `protobuf:"bytes,2,opt,name=tag,proto3" json:"tag,omitempty"`
+	Status               string     `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Worker               string     `protobuf:"bytes,4,opt,name=worker,proto3" json:"worker,omitempty"`
+	UpdatedAt            int64      `protobuf:"varint,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	RequestedAt          int64      `protobuf:"varint,6,opt,name=requested_at,json=requestedAt,proto3" json:"requested_at,omitempty"`
+	StartedAt            int64      `protobuf:"varint,7,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	Payload              string     `protobuf:"bytes,8,opt,name=payload,proto3" json:"payload,omitempty"`
+	Attempts             []*Attempt `protobuf:"bytes,9,rep,name=attempts,proto3" json:"attempts,omitempty"`
+	QueuedAt             int64      `protobuf:"varint,10,opt,name=queued_at,json=queuedAt,proto3" json:"queued_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
 func (m *Job) Reset()         { *m = Job{} }
 func (m *Job) String() string { return proto.CompactTextString(m) }
 func (*Job) ProtoMessage()    {}
 func (*Job) Descriptor() ([]byte, []int) {
-	return fileDescriptor_summary_741b49c5a0045d04, []int{0}
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{0}
 }
 func (m *Job) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Job.Unmarshal(m, b)
@@ -85,6 +91,118 @@ func (m *Job) GetWorker() string {
 	return ""
 }
 
+func (m *Job) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
+func (m *Job) GetRequestedAt() int64 {
+	if m != nil {
+		return m.RequestedAt
+	}
+	return 0
+}
+
+func (m *Job) GetStartedAt() int64 {
+	if m != nil {
+		return m.StartedAt
+	}
+	return 0
+}
+
+func (m *Job) GetPayload() string {
+	if m != nil {
+		return m.Payload
+	}
+	return ""
+}
+
+func (m *Job) GetAttempts() []*Attempt {
+	if m != nil {
+		return m.Attempts
+	}
+	return nil
+}
+
+func (m *Job) GetQueuedAt() int64 {
+	if m != nil {
+		return m.QueuedAt
+	}
+	return 0
+}
+
+type Attempt struct {
+	Worker               string   `protobuf:"bytes,1,opt,name=worker,proto3" json:"worker,omitempty"`
+	StartedAt            int64    `protobuf:"varint,2,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt           int64    `protobuf:"varint,3,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	Duration             int64    `protobuf:"varint,4,opt,name=duration,proto3" json:"duration,omitempty"`
+	Error                string   `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Attempt) Reset()         { *m = Attempt{} }
+func (m *Attempt) String() string { return proto.CompactTextString(m) }
+func (*Attempt) ProtoMessage()    {}
+func (*Attempt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{1}
+}
+func (m *Attempt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Attempt.Unmarshal(m, b)
+}
+func (m *Attempt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Attempt.Marshal(b, m, deterministic)
+}
+func (dst *Attempt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Attempt.Merge(dst, src)
+}
+func (m *Attempt) XXX_Size() int {
+	return xxx_messageInfo_Attempt.Size(m)
+}
+func (m *Attempt) XXX_DiscardUnknown() {
+	xxx_messageInfo_Attempt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Attempt proto.InternalMessageInfo
+
+func (m *Attempt) GetWorker() string {
+	if m != nil {
+		return m.Worker
+	}
+	return ""
+}
+
+func (m *Attempt) GetStartedAt() int64 {
+	if m != nil {
+		return m.StartedAt
+	}
+	return 0
+}
+
+func (m *Attempt) GetFinishedAt() int64 {
+	if m != nil {
+		return m.FinishedAt
+	}
+	return 0
+}
+
+func (m *Attempt) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+func (m *Attempt) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 type JobUpdate struct {
 	App                  string   `protobuf:"bytes,1,opt,name=app,proto3" json:"app,omitempty"`
 	QueueId              string   `protobuf:"bytes,2,opt,name=queue_id,json=queueId,proto3" json:"queue_id,omitempty"`
@@ -98,7 +216,7 @@ func (m *JobUpdate) Reset()         { *m = JobUpdate{} }
 func (m *JobUpdate) String() string { return proto.CompactTextString(m) }
 func (*JobUpdate) ProtoMessage()    {}
 func (*JobUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_summary_741b49c5a0045d04, []int{1}
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{2}
 }
 func (m *JobUpdate) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_JobUpdate.Unmarshal(m, b)
@@ -151,7 +269,7 @@ func (m *JobBlueprint) Reset()         { *m = JobBlueprint{} }
 func (m *JobBlueprint) String() string { return proto.CompactTextString(m) }
 func (*JobBlueprint) ProtoMessage()    {}
 func (*JobBlueprint) Descriptor() ([]byte, []int) {
-	return fileDescriptor_summary_741b49c5a0045d04, []int{2}
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{3}
 }
 func (m *JobBlueprint) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_JobBlueprint.Unmarshal(m, b)
@@ -185,27 +303,228 @@ func (m *JobBlueprint) GetFields() map[string]string {
 	return nil
 }
 
+type Percentiles struct {
+	Count                uint64   `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	P50                  float64  `protobuf:"fixed64,2,opt,name=p50,proto3" json:"p50,omitempty"`
+	P95                  float64  `protobuf:"fixed64,3,opt,name=p95,proto3" json:"p95,omitempty"`
+	P99                  float64  `protobuf:"fixed64,4,opt,name=p99,proto3" json:"p99,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Percentiles) Reset()         { *m = Percentiles{} }
+func (m *Percentiles) String() string { return proto.CompactTextString(m) }
+func (*Percentiles) ProtoMessage()    {}
+func (*Percentiles) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{4}
+}
+func (m *Percentiles) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Percentiles.Unmarshal(m, b)
+}
+func (m *Percentiles) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Percentiles.Marshal(b, m, deterministic)
+}
+func (dst *Percentiles) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Percentiles.Merge(dst, src)
+}
+func (m *Percentiles) XXX_Size() int {
+	return xxx_messageInfo_Percentiles.Size(m)
+}
+func (m *Percentiles) XXX_DiscardUnknown() {
+	xxx_messageInfo_Percentiles.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Percentiles proto.InternalMessageInfo
+
+func (m *Percentiles) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *Percentiles) GetP50() float64 {
+	if m != nil {
+		return m.P50
+	}
+	return 0
+}
+
+func (m *Percentiles) GetP95() float64 {
+	if m != nil {
+		return m.P95
+	}
+	return 0
+}
+
+func (m *Percentiles) GetP99() float64 {
+	if m != nil {
+		return m.P99
+	}
+	return 0
+}
+
+type Latency struct {
+	Wait                 *Percentiles `protobuf:"bytes,1,opt,name=wait,proto3" json:"wait,omitempty"`
+	Run                  *Percentiles `protobuf:"bytes,2,opt,name=run,proto3" json:"run,omitempty"`
+	Total                *Percentiles `protobuf:"bytes,3,opt,name=total,proto3" json:"total,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Latency) Reset()         { *m = Latency{} }
+func (m *Latency) String() string { return proto.CompactTextString(m) }
+func (*Latency) ProtoMessage()    {}
+func (*Latency) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{5}
+}
+func (m *Latency) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Latency.Unmarshal(m, b)
+}
+func (m *Latency) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Latency.Marshal(b, m, deterministic)
+}
+func (dst *Latency) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Latency.Merge(dst, src)
+}
+func (m *Latency) XXX_Size() int {
+	return xxx_messageInfo_Latency.Size(m)
+}
+func (m *Latency) XXX_DiscardUnknown() {
+	xxx_messageInfo_Latency.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Latency proto.InternalMessageInfo
+
+func (m *Latency) GetWait() *Percentiles {
+	if m != nil {
+		return m.Wait
+	}
+	return nil
+}
+
+func (m *Latency) GetRun() *Percentiles {
+	if m != nil {
+		return m.Run
+	}
+	return nil
+}
+
+func (m *Latency) GetTotal() *Percentiles {
+	if m != nil {
+		return m.Total
+	}
+	return nil
+}
+
+type Monitoring struct {
+	State                string   `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	BufferedJobs         uint32   `protobuf:"varint,2,opt,name=buffered_jobs,json=bufferedJobs,proto3" json:"buffered_jobs,omitempty"`
+	DroppedJobs          uint64   `protobuf:"varint,3,opt,name=dropped_jobs,json=droppedJobs,proto3" json:"dropped_jobs,omitempty"`
+	Reconnects           uint32   `protobuf:"varint,4,opt,name=reconnects,proto3" json:"reconnects,omitempty"`
+	LastError            string   `protobuf:"bytes,5,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	ConnectedAt          int64    `protobuf:"varint,6,opt,name=connected_at,json=connectedAt,proto3" json:"connected_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Monitoring) Reset()         { *m = Monitoring{} }
+func (m *Monitoring) String() string { return proto.CompactTextString(m) }
+func (*Monitoring) ProtoMessage()    {}
+func (*Monitoring) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{6}
+}
+func (m *Monitoring) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Monitoring.Unmarshal(m, b)
+}
+func (m *Monitoring) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Monitoring.Marshal(b, m, deterministic)
+}
+func (dst *Monitoring) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Monitoring.Merge(dst, src)
+}
+func (m *Monitoring) XXX_Size() int {
+	return xxx_messageInfo_Monitoring.Size(m)
+}
+func (m *Monitoring) XXX_DiscardUnknown() {
+	xxx_messageInfo_Monitoring.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Monitoring proto.InternalMessageInfo
+
+func (m *Monitoring) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *Monitoring) GetBufferedJobs() uint32 {
+	if m != nil {
+		return m.BufferedJobs
+	}
+	return 0
+}
+
+func (m *Monitoring) GetDroppedJobs() uint64 {
+	if m != nil {
+		return m.DroppedJobs
+	}
+	return 0
+}
+
+func (m *Monitoring) GetReconnects() uint32 {
+	if m != nil {
+		return m.Reconnects
+	}
+	return 0
+}
+
+func (m *Monitoring) GetLastError() string {
+	if m != nil {
+		return m.LastError
+	}
+	return ""
+}
+
+func (m *Monitoring) GetConnectedAt() int64 {
+	if m != nil {
+		return m.ConnectedAt
+	}
+	return 0
+}
+
 type Stats struct {
-	App                  string          `protobuf:"bytes,1,opt,name=app,proto3" json:"app,omitempty"`
-	QueueId              string          `protobuf:"bytes,2,opt,name=queue_id,json=queueId,proto3" json:"queue_id,omitempty"`
-	Jobs                 map[string]*Job `protobuf:"bytes,3,rep,name=jobs,proto3" json:"jobs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	ActiveJobs           uint32          `protobuf:"varint,4,opt,name=active_jobs,json=activeJobs,proto3" json:"active_jobs,omitempty"`
-	QueuedJobs           uint32          `protobuf:"varint,5,opt,name=queued_jobs,json=queuedJobs,proto3" json:"queued_jobs,omitempty"`
-	ProcessedJobs        uint32          `protobuf:"varint,6,opt,name=processed_jobs,json=processedJobs,proto3" json:"processed_jobs,omitempty"`
-	DeferredJobs         uint32          `protobuf:"varint,7,opt,name=deferred_jobs,json=deferredJobs,proto3" json:"deferred_jobs,omitempty"`
-	FailedJobs           uint32          `protobuf:"varint,8,opt,name=failed_jobs,json=failedJobs,proto3" json:"failed_jobs,omitempty"`
-	RequeuedJobs         uint32          `protobuf:"varint,9,opt,name=requeued_jobs,json=requeuedJobs,proto3" json:"requeued_jobs,omitempty"`
-	JobBlueprints        []*JobBlueprint `protobuf:"bytes,10,rep,name=job_blueprints,json=jobBlueprints,proto3" json:"job_blueprints,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
-	XXX_unrecognized     []byte          `json:"-"`
-	XXX_sizecache        int32           `json:"-"`
+	App                  string              `protobuf:"bytes,1,opt,name=app,proto3" json:"app,omitempty"`
+	QueueId              string              `protobuf:"bytes,2,opt,name=queue_id,json=queueId,proto3" json:"queue_id,omitempty"`
+	Jobs                 map[string]*Job     `protobuf:"bytes,3,rep,name=jobs,proto3" json:"jobs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	ActiveJobs           uint32              `protobuf:"varint,4,opt,name=active_jobs,json=activeJobs,proto3" json:"active_jobs,omitempty"`
+	QueuedJobs           uint32              `protobuf:"varint,5,opt,name=queued_jobs,json=queuedJobs,proto3" json:"queued_jobs,omitempty"`
+	ProcessedJobs        uint32              `protobuf:"varint,6,opt,name=processed_jobs,json=processedJobs,proto3" json:"processed_jobs,omitempty"`
+	DeferredJobs         uint32              `protobuf:"varint,7,opt,name=deferred_jobs,json=deferredJobs,proto3" json:"deferred_jobs,omitempty"`
+	FailedJobs           uint32              `protobuf:"varint,8,opt,name=failed_jobs,json=failedJobs,proto3" json:"failed_jobs,omitempty"`
+	RequeuedJobs         uint32              `protobuf:"varint,9,opt,name=requeued_jobs,json=requeuedJobs,proto3" json:"requeued_jobs,omitempty"`
+	JobBlueprints        []*JobBlueprint     `protobuf:"bytes,10,rep,name=job_blueprints,json=jobBlueprints,proto3" json:"job_blueprints,omitempty"`
+	DroppedEvents        uint64              `protobuf:"varint,11,opt,name=dropped_events,json=droppedEvents,proto3" json:"dropped_events,omitempty"`
+	EvictedJobs          []string            `protobuf:"bytes,12,rep,name=evicted_jobs,json=evictedJobs,proto3" json:"evicted_jobs,omitempty"`
+	Workers              uint32              `protobuf:"varint,13,opt,name=workers,proto3" json:"workers,omitempty"`
+	IdleWorkers          uint32              `protobuf:"varint,14,opt,name=idle_workers,json=idleWorkers,proto3" json:"idle_workers,omitempty"`
+	Latencies            map[string]*Latency `protobuf:"bytes,15,rep,name=latencies,proto3" json:"latencies,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Monitoring           *Monitoring         `protobuf:"bytes,16,opt,name=monitoring,proto3" json:"monitoring,omitempty"`
+	CancelledJobs        uint32              `protobuf:"varint,17,opt,name=cancelled_jobs,json=cancelledJobs,proto3" json:"cancelled_jobs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
 }
 
 func (m *Stats) Reset()         { *m = Stats{} }
 func (m *Stats) String() string { return proto.CompactTextString(m) }
 func (*Stats) ProtoMessage()    {}
 func (*Stats) Descriptor() ([]byte, []int) {
-	return fileDescriptor_summary_741b49c5a0045d04, []int{3}
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{7}
 }
 func (m *Stats) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Stats.Unmarshal(m, b)
@@ -295,151 +614,867 @@ func (m *Stats) GetJobBlueprints() []*JobBlueprint {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*Job)(nil), "Job")
-	proto.RegisterType((*JobUpdate)(nil), "JobUpdate")
-	proto.RegisterType((*JobBlueprint)(nil), "JobBlueprint")
-	proto.RegisterMapType((map[string]string)(nil), "JobBlueprint.FieldsEntry")
-	proto.RegisterType((*Stats)(nil), "Stats")
-	proto.RegisterMapType((map[string]*Job)(nil), "Stats.JobsEntry")
+func (m *Stats) GetDroppedEvents() uint64 {
+	if m != nil {
+		return m.DroppedEvents
+	}
+	return 0
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *Stats) GetEvictedJobs() []string {
+	if m != nil {
+		return m.EvictedJobs
+	}
+	return nil
+}
 
-// SummaryClient is the client API for Summary service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type SummaryClient interface {
-	UpdateStats(ctx context.Context, in *Stats, opts ...grpc.CallOption) (*Stats, error)
-	UpdateJob(ctx context.Context, in *JobUpdate, opts ...grpc.CallOption) (*Job, error)
+func (m *Stats) GetWorkers() uint32 {
+	if m != nil {
+		return m.Workers
+	}
+	return 0
 }
 
-type summaryClient struct {
-	cc *grpc.ClientConn
+func (m *Stats) GetIdleWorkers() uint32 {
+	if m != nil {
+		return m.IdleWorkers
+	}
+	return 0
 }
 
-func NewSummaryClient(cc *grpc.ClientConn) SummaryClient {
-	return &summaryClient{cc}
+func (m *Stats) GetLatencies() map[string]*Latency {
+	if m != nil {
+		return m.Latencies
+	}
+	return nil
 }
 
-func (c *summaryClient) UpdateStats(ctx context.Context, in *Stats, opts ...grpc.CallOption) (*Stats, error) {
-	out := new(Stats)
-	err := c.cc.Invoke(ctx, "/Summary/UpdateStats", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *Stats) GetMonitoring() *Monitoring {
+	if m != nil {
+		return m.Monitoring
 	}
-	return out, nil
+	return nil
 }
 
-func (c *summaryClient) UpdateJob(ctx context.Context, in *JobUpdate, opts ...grpc.CallOption) (*Job, error) {
-	out := new(Job)
-	err := c.cc.Invoke(ctx, "/Summary/UpdateJob", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *Stats) GetCancelledJobs() uint32 {
+	if m != nil {
+		return m.CancelledJobs
 	}
-	return out, nil
+	return 0
 }
 
-// SummaryServer is the server API for Summary service.
-type SummaryServer interface {
-	UpdateStats(context.Context, *Stats) (*Stats, error)
-	UpdateJob(context.Context, *JobUpdate) (*Job, error)
+type Heartbeat struct {
+	App                  string   `protobuf:"bytes,1,opt,name=app,proto3" json:"app,omitempty"`
+	QueueId              string   `protobuf:"bytes,2,opt,name=queue_id,json=queueId,proto3" json:"queue_id,omitempty"`
+	SentAt               int64    `protobuf:"varint,3,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	Uptime               int64    `protobuf:"varint,4,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	Workers              uint32   `protobuf:"varint,5,opt,name=workers,proto3" json:"workers,omitempty"`
+	IdleWorkers          uint32   `protobuf:"varint,6,opt,name=idle_workers,json=idleWorkers,proto3" json:"idle_workers,omitempty"`
+	Backlog              uint32   `protobuf:"varint,7,opt,name=backlog,proto3" json:"backlog,omitempty"`
+	Version              string   `protobuf:"bytes,8,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func RegisterSummaryServer(s *grpc.Server, srv SummaryServer) {
-	s.RegisterService(&_Summary_serviceDesc, srv)
+func (m *Heartbeat) Reset()         { *m = Heartbeat{} }
+func (m *Heartbeat) String() string { return proto.CompactTextString(m) }
+func (*Heartbeat) ProtoMessage()    {}
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{8}
+}
+func (m *Heartbeat) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Heartbeat.Unmarshal(m, b)
+}
+func (m *Heartbeat) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Heartbeat.Marshal(b, m, deterministic)
+}
+func (dst *Heartbeat) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Heartbeat.Merge(dst, src)
+}
+func (m *Heartbeat) XXX_Size() int {
+	return xxx_messageInfo_Heartbeat.Size(m)
+}
+func (m *Heartbeat) XXX_DiscardUnknown() {
+	xxx_messageInfo_Heartbeat.DiscardUnknown(m)
 }
 
-func _Summary_UpdateStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Stats)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(SummaryServer).UpdateStats(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/Summary/UpdateStats",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(SummaryServer).UpdateStats(ctx, req.(*Stats))
+var xxx_messageInfo_Heartbeat proto.InternalMessageInfo
+
+func (m *Heartbeat) GetApp() string {
+	if m != nil {
+		return m.App
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-func _Summary_UpdateJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(JobUpdate)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
+func (m *Heartbeat) GetQueueId() string {
+	if m != nil {
+		return m.QueueId
+	}
+	return ""
+}
+
+func (m *Heartbeat) GetSentAt() int64 {
+	if m != nil {
+		return m.SentAt
+	}
+	return 0
+}
+
+func (m *Heartbeat) GetUptime() int64 {
+	if m != nil {
+		return m.Uptime
+	}
+	return 0
+}
+
+func (m *Heartbeat) GetWorkers() uint32 {
+	if m != nil {
+		return m.Workers
+	}
+	return 0
+}
+
+func (m *Heartbeat) GetIdleWorkers() uint32 {
+	if m != nil {
+		return m.IdleWorkers
+	}
+	return 0
+}
+
+func (m *Heartbeat) GetBacklog() uint32 {
+	if m != nil {
+		return m.Backlog
+	}
+	return 0
+}
+
+func (m *Heartbeat) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+type StatsReport struct {
+	Jobs                 []*Job     `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	Stats                *Stats     `protobuf:"bytes,2,opt,name=stats,proto3" json:"stats,omitempty"`
+	Heartbeat            *Heartbeat `protobuf:"bytes,3,opt,name=heartbeat,proto3" json:"heartbeat,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *StatsReport) Reset()         { *m = StatsReport{} }
+func (m *StatsReport) String() string { return proto.CompactTextString(m) }
+func (*StatsReport) ProtoMessage()    {}
+func (*StatsReport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{9}
+}
+func (m *StatsReport) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsReport.Unmarshal(m, b)
+}
+func (m *StatsReport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsReport.Marshal(b, m, deterministic)
+}
+func (dst *StatsReport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsReport.Merge(dst, src)
+}
+func (m *StatsReport) XXX_Size() int {
+	return xxx_messageInfo_StatsReport.Size(m)
+}
+func (m *StatsReport) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsReport.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsReport proto.InternalMessageInfo
+
+func (m *StatsReport) GetJobs() []*Job {
+	if m != nil {
+		return m.Jobs
+	}
+	return nil
+}
+
+func (m *StatsReport) GetStats() *Stats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+func (m *StatsReport) GetHeartbeat() *Heartbeat {
+	if m != nil {
+		return m.Heartbeat
+	}
+	return nil
+}
+
+type ReportAck struct {
+	Reports              uint64   `protobuf:"varint,1,opt,name=reports,proto3" json:"reports,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReportAck) Reset()         { *m = ReportAck{} }
+func (m *ReportAck) String() string { return proto.CompactTextString(m) }
+func (*ReportAck) ProtoMessage()    {}
+func (*ReportAck) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{10}
+}
+func (m *ReportAck) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReportAck.Unmarshal(m, b)
+}
+func (m *ReportAck) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReportAck.Marshal(b, m, deterministic)
+}
+func (dst *ReportAck) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReportAck.Merge(dst, src)
+}
+func (m *ReportAck) XXX_Size() int {
+	return xxx_messageInfo_ReportAck.Size(m)
+}
+func (m *ReportAck) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReportAck.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReportAck proto.InternalMessageInfo
+
+func (m *ReportAck) GetReports() uint64 {
+	if m != nil {
+		return m.Reports
+	}
+	return 0
+}
+
+type EnqueueRequest struct {
+	App                  string   `protobuf:"bytes,1,opt,name=app,proto3" json:"app,omitempty"`
+	QueueId              string   `protobuf:"bytes,2,opt,name=queue_id,json=queueId,proto3" json:"queue_id,omitempty"`
+	Tag                  string   `protobuf:"bytes,3,opt,name=tag,proto3" json:"tag,omitempty"`
+	Data                 string   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Retry                uint32   `protobuf:"varint,5,opt,name=retry,proto3" json:"retry,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EnqueueRequest) Reset()         { *m = EnqueueRequest{} }
+func (m *EnqueueRequest) String() string { return proto.CompactTextString(m) }
+func (*EnqueueRequest) ProtoMessage()    {}
+func (*EnqueueRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{11}
+}
+func (m *EnqueueRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EnqueueRequest.Unmarshal(m, b)
+}
+func (m *EnqueueRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EnqueueRequest.Marshal(b, m, deterministic)
+}
+func (dst *EnqueueRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EnqueueRequest.Merge(dst, src)
+}
+func (m *EnqueueRequest) XXX_Size() int {
+	return xxx_messageInfo_EnqueueRequest.Size(m)
+}
+func (m *EnqueueRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EnqueueRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EnqueueRequest proto.InternalMessageInfo
+
+func (m *EnqueueRequest) GetApp() string {
+	if m != nil {
+		return m.App
+	}
+	return ""
+}
+
+func (m *EnqueueRequest) GetQueueId() string {
+	if m != nil {
+		return m.QueueId
+	}
+	return ""
+}
+
+func (m *EnqueueRequest) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+func (m *EnqueueRequest) GetData() string {
+	if m != nil {
+		return m.Data
+	}
+	return ""
+}
+
+func (m *EnqueueRequest) GetRetry() uint32 {
+	if m != nil {
+		return m.Retry
+	}
+	return 0
+}
+
+type EnqueueReply struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	QueueId              string   `protobuf:"bytes,2,opt,name=queue_id,json=queueId,proto3" json:"queue_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EnqueueReply) Reset()         { *m = EnqueueReply{} }
+func (m *EnqueueReply) String() string { return proto.CompactTextString(m) }
+func (*EnqueueReply) ProtoMessage()    {}
+func (*EnqueueReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{12}
+}
+func (m *EnqueueReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EnqueueReply.Unmarshal(m, b)
+}
+func (m *EnqueueReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EnqueueReply.Marshal(b, m, deterministic)
+}
+func (dst *EnqueueReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EnqueueReply.Merge(dst, src)
+}
+func (m *EnqueueReply) XXX_Size() int {
+	return xxx_messageInfo_EnqueueReply.Size(m)
+}
+func (m *EnqueueReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_EnqueueReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EnqueueReply proto.InternalMessageInfo
+
+func (m *EnqueueReply) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *EnqueueReply) GetQueueId() string {
+	if m != nil {
+		return m.QueueId
+	}
+	return ""
+}
+
+type JobCommand struct {
+	JobId                string   `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status               string   `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *JobCommand) Reset()         { *m = JobCommand{} }
+func (m *JobCommand) String() string { return proto.CompactTextString(m) }
+func (*JobCommand) ProtoMessage()    {}
+func (*JobCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{13}
+}
+func (m *JobCommand) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_JobCommand.Unmarshal(m, b)
+}
+func (m *JobCommand) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_JobCommand.Marshal(b, m, deterministic)
+}
+func (dst *JobCommand) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JobCommand.Merge(dst, src)
+}
+func (m *JobCommand) XXX_Size() int {
+	return xxx_messageInfo_JobCommand.Size(m)
+}
+func (m *JobCommand) XXX_DiscardUnknown() {
+	xxx_messageInfo_JobCommand.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JobCommand proto.InternalMessageInfo
+
+func (m *JobCommand) GetJobId() string {
+	if m != nil {
+		return m.JobId
+	}
+	return ""
+}
+
+func (m *JobCommand) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+type Command struct {
+	Id                   string          `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Enqueue              *EnqueueRequest `protobuf:"bytes,2,opt,name=enqueue,proto3" json:"enqueue,omitempty"`
+	Retry                *JobCommand     `protobuf:"bytes,3,opt,name=retry,proto3" json:"retry,omitempty"`
+	Cancel               *JobCommand     `protobuf:"bytes,4,opt,name=cancel,proto3" json:"cancel,omitempty"`
+	Purge                *JobCommand     `protobuf:"bytes,5,opt,name=purge,proto3" json:"purge,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *Command) Reset()         { *m = Command{} }
+func (m *Command) String() string { return proto.CompactTextString(m) }
+func (*Command) ProtoMessage()    {}
+func (*Command) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{14}
+}
+func (m *Command) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Command.Unmarshal(m, b)
+}
+func (m *Command) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Command.Marshal(b, m, deterministic)
+}
+func (dst *Command) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Command.Merge(dst, src)
+}
+func (m *Command) XXX_Size() int {
+	return xxx_messageInfo_Command.Size(m)
+}
+func (m *Command) XXX_DiscardUnknown() {
+	xxx_messageInfo_Command.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Command proto.InternalMessageInfo
+
+func (m *Command) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Command) GetEnqueue() *EnqueueRequest {
+	if m != nil {
+		return m.Enqueue
+	}
+	return nil
+}
+
+func (m *Command) GetRetry() *JobCommand {
+	if m != nil {
+		return m.Retry
+	}
+	return nil
+}
+
+func (m *Command) GetCancel() *JobCommand {
+	if m != nil {
+		return m.Cancel
+	}
+	return nil
+}
+
+func (m *Command) GetPurge() *JobCommand {
+	if m != nil {
+		return m.Purge
+	}
+	return nil
+}
+
+type CommandReply struct {
+	Id                   string        `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	App                  string        `protobuf:"bytes,2,opt,name=app,proto3" json:"app,omitempty"`
+	QueueId              string        `protobuf:"bytes,3,opt,name=queue_id,json=queueId,proto3" json:"queue_id,omitempty"`
+	Code                 uint32        `protobuf:"varint,4,opt,name=code,proto3" json:"code,omitempty"`
+	Error                string        `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Enqueue              *EnqueueReply `protobuf:"bytes,6,opt,name=enqueue,proto3" json:"enqueue,omitempty"`
+	JobIds               []string      `protobuf:"bytes,7,rep,name=job_ids,json=jobIds,proto3" json:"job_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *CommandReply) Reset()         { *m = CommandReply{} }
+func (m *CommandReply) String() string { return proto.CompactTextString(m) }
+func (*CommandReply) ProtoMessage()    {}
+func (*CommandReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_summary_d4a517f9a5ddab0c, []int{15}
+}
+func (m *CommandReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommandReply.Unmarshal(m, b)
+}
+func (m *CommandReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommandReply.Marshal(b, m, deterministic)
+}
+func (dst *CommandReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommandReply.Merge(dst, src)
+}
+func (m *CommandReply) XXX_Size() int {
+	return xxx_messageInfo_CommandReply.Size(m)
+}
+func (m *CommandReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommandReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommandReply proto.InternalMessageInfo
+
+func (m *CommandReply) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *CommandReply) GetApp() string {
+	if m != nil {
+		return m.App
+	}
+	return ""
+}
+
+func (m *CommandReply) GetQueueId() string {
+	if m != nil {
+		return m.QueueId
+	}
+	return ""
+}
+
+func (m *CommandReply) GetCode() uint32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *CommandReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *CommandReply) GetEnqueue() *EnqueueReply {
+	if m != nil {
+		return m.Enqueue
+	}
+	return nil
+}
+
+func (m *CommandReply) GetJobIds() []string {
+	if m != nil {
+		return m.JobIds
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Job)(nil), "Job")
+	proto.RegisterType((*Attempt)(nil), "Attempt")
+	proto.RegisterType((*JobUpdate)(nil), "JobUpdate")
+	proto.RegisterType((*JobBlueprint)(nil), "JobBlueprint")
+	proto.RegisterMapType((map[string]string)(nil), "JobBlueprint.FieldsEntry")
+	proto.RegisterType((*Percentiles)(nil), "Percentiles")
+	proto.RegisterType((*Latency)(nil), "Latency")
+	proto.RegisterType((*Monitoring)(nil), "Monitoring")
+	proto.RegisterType((*Stats)(nil), "Stats")
+	proto.RegisterMapType((map[string]*Job)(nil), "Stats.JobsEntry")
+	proto.RegisterMapType((map[string]*Latency)(nil), "Stats.LatenciesEntry")
+	proto.RegisterType((*Heartbeat)(nil), "Heartbeat")
+	proto.RegisterType((*StatsReport)(nil), "StatsReport")
+	proto.RegisterType((*ReportAck)(nil), "ReportAck")
+	proto.RegisterType((*EnqueueRequest)(nil), "EnqueueRequest")
+	proto.RegisterType((*EnqueueReply)(nil), "EnqueueReply")
+	proto.RegisterType((*JobCommand)(nil), "JobCommand")
+	proto.RegisterType((*Command)(nil), "Command")
+	proto.RegisterType((*CommandReply)(nil), "CommandReply")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// SummaryClient is the client API for Summary service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SummaryClient interface {
+	Report(ctx context.Context, opts ...grpc.CallOption) (Summary_ReportClient, error)
+	Enqueue(ctx context.Context, in *EnqueueRequest, opts ...grpc.CallOption) (*EnqueueReply, error)
+	Commands(ctx context.Context, opts ...grpc.CallOption) (Summary_CommandsClient, error)
+}
+
+type summaryClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSummaryClient(cc *grpc.ClientConn) SummaryClient {
+	return &summaryClient{cc}
+}
+
+func (c *summaryClient) Report(ctx context.Context, opts ...grpc.CallOption) (Summary_ReportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Summary_serviceDesc.Streams[0], "/Summary/Report", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &summaryReportClient{stream}
+	return x, nil
+}
+
+type Summary_ReportClient interface {
+	Send(*StatsReport) error
+	CloseAndRecv() (*ReportAck, error)
+	grpc.ClientStream
+}
+
+type summaryReportClient struct {
+	grpc.ClientStream
+}
+
+func (x *summaryReportClient) Send(m *StatsReport) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *summaryReportClient) CloseAndRecv() (*ReportAck, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ReportAck)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *summaryClient) Enqueue(ctx context.Context, in *EnqueueRequest, opts ...grpc.CallOption) (*EnqueueReply, error) {
+	out := new(EnqueueReply)
+	err := c.cc.Invoke(ctx, "/Summary/Enqueue", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *summaryClient) Commands(ctx context.Context, opts ...grpc.CallOption) (Summary_CommandsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Summary_serviceDesc.Streams[1], "/Summary/Commands", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &summaryCommandsClient{stream}
+	return x, nil
+}
+
+type Summary_CommandsClient interface {
+	Send(*CommandReply) error
+	Recv() (*Command, error)
+	grpc.ClientStream
+}
+
+type summaryCommandsClient struct {
+	grpc.ClientStream
+}
+
+func (x *summaryCommandsClient) Send(m *CommandReply) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *summaryCommandsClient) Recv() (*Command, error) {
+	m := new(Command)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SummaryServer is the server API for Summary service.
+type SummaryServer interface {
+	Report(Summary_ReportServer) error
+	Enqueue(context.Context, *EnqueueRequest) (*EnqueueReply, error)
+	Commands(Summary_CommandsServer) error
+}
+
+func RegisterSummaryServer(s *grpc.Server, srv SummaryServer) {
+	s.RegisterService(&_Summary_serviceDesc, srv)
+}
+
+func _Summary_Report_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SummaryServer).Report(&summaryReportServer{stream})
+}
+
+type Summary_ReportServer interface {
+	SendAndClose(*ReportAck) error
+	Recv() (*StatsReport, error)
+	grpc.ServerStream
+}
+
+type summaryReportServer struct {
+	grpc.ServerStream
+}
+
+func (x *summaryReportServer) SendAndClose(m *ReportAck) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *summaryReportServer) Recv() (*StatsReport, error) {
+	m := new(StatsReport)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Summary_Enqueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnqueueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
 	if interceptor == nil {
-		return srv.(SummaryServer).UpdateJob(ctx, in)
+		return srv.(SummaryServer).Enqueue(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/Summary/UpdateJob",
+		FullMethod: "/Summary/Enqueue",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(SummaryServer).UpdateJob(ctx, req.(*JobUpdate))
+		return srv.(SummaryServer).Enqueue(ctx, req.(*EnqueueRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Summary_Commands_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SummaryServer).Commands(&summaryCommandsServer{stream})
+}
+
+type Summary_CommandsServer interface {
+	Send(*Command) error
+	Recv() (*CommandReply, error)
+	grpc.ServerStream
+}
+
+type summaryCommandsServer struct {
+	grpc.ServerStream
+}
+
+func (x *summaryCommandsServer) Send(m *Command) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *summaryCommandsServer) Recv() (*CommandReply, error) {
+	m := new(CommandReply)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Summary_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "Summary",
 	HandlerType: (*SummaryServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "UpdateStats",
-			Handler:    _Summary_UpdateStats_Handler,
+			MethodName: "Enqueue",
+			Handler:    _Summary_Enqueue_Handler,
 		},
+	},
+	Streams: []grpc.StreamDesc{
 		{
-			MethodName: "UpdateJob",
-			Handler:    _Summary_UpdateJob_Handler,
+			StreamName:    "Report",
+			Handler:       _Summary_Report_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Commands",
+			Handler:       _Summary_Commands_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "summary/summary.proto",
 }
 
-func init() { proto.RegisterFile("summary/summary.proto", fileDescriptor_summary_741b49c5a0045d04) }
-
-var fileDescriptor_summary_741b49c5a0045d04 = []byte{
-	// 453 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x93, 0xcf, 0x8e, 0xd3, 0x30,
-	0x10, 0xc6, 0xb7, 0x49, 0x9b, 0x6e, 0xa7, 0x9b, 0x6a, 0x65, 0xc1, 0x2a, 0x9b, 0xcb, 0xae, 0x02,
-	0x48, 0x7b, 0x0a, 0xa2, 0x70, 0x00, 0x24, 0x2e, 0x48, 0x20, 0x11, 0x24, 0x84, 0xb2, 0x42, 0x1c,
-	0x2b, 0x7b, 0xed, 0xa2, 0xa4, 0x7f, 0x1c, 0x6c, 0x67, 0x51, 0x9f, 0x84, 0x07, 0xe0, 0x45, 0x91,
-	0xc7, 0x76, 0x09, 0x07, 0x0e, 0x9c, 0x92, 0xf9, 0xf2, 0xcb, 0x37, 0xe3, 0x99, 0x31, 0x3c, 0xd4,
-	0xfd, 0x6e, 0x47, 0xd5, 0xe1, 0xa9, 0x7f, 0x96, 0x9d, 0x92, 0x46, 0x16, 0x5f, 0x21, 0xae, 0x24,
-	0x23, 0x0b, 0x88, 0x1a, 0x9e, 0x8d, 0xae, 0x47, 0x37, 0xb3, 0x3a, 0x6a, 0x38, 0x39, 0x87, 0xd8,
-	0xd0, 0x6f, 0x59, 0x84, 0x82, 0x7d, 0x25, 0x17, 0x90, 0x68, 0x43, 0x4d, 0xaf, 0xb3, 0x18, 0x45,
-	0x1f, 0x59, 0xfd, 0x87, 0x54, 0x1b, 0xa1, 0xb2, 0xb1, 0xd3, 0x5d, 0x54, 0x7c, 0x86, 0x59, 0x25,
-	0xd9, 0x97, 0x8e, 0x53, 0x23, 0xac, 0x1d, 0xed, 0x3a, 0xef, 0x6f, 0x5f, 0xc9, 0x25, 0x9c, 0x7e,
-	0xef, 0x45, 0x2f, 0x56, 0x0d, 0xf7, 0x59, 0xa6, 0x18, 0x7f, 0xe0, 0xe4, 0x02, 0xe2, 0x56, 0x32,
-	0x4c, 0x33, 0x5f, 0x8e, 0xcb, 0x4a, 0xb2, 0xda, 0x0a, 0xc5, 0xcf, 0x11, 0x9c, 0x55, 0x92, 0xbd,
-	0xdd, 0xf6, 0xa2, 0x53, 0xcd, 0xde, 0x58, 0x8f, 0x56, 0xb2, 0xd5, 0x9e, 0xee, 0x84, 0xb7, 0x9e,
-	0xb6, 0x92, 0x7d, 0xa2, 0x3b, 0x41, 0x9e, 0x41, 0xb2, 0x6e, 0xc4, 0x96, 0xeb, 0x2c, 0xba, 0x8e,
-	0x6f, 0xe6, 0xcb, 0xcb, 0x72, 0xf8, 0x67, 0xf9, 0x1e, 0xbf, 0xbd, 0xdb, 0x1b, 0x75, 0xa8, 0x3d,
-	0x98, 0xbf, 0x82, 0xf9, 0x40, 0xb6, 0x25, 0x6f, 0xc4, 0x21, 0x94, 0xbc, 0x11, 0x07, 0xf2, 0x00,
-	0x26, 0xf7, 0x74, 0xdb, 0x0b, 0x5f, 0xaf, 0x0b, 0x5e, 0x47, 0x2f, 0x47, 0xc5, 0xaf, 0x18, 0x26,
-	0xb7, 0x86, 0x1a, 0xfd, 0x7f, 0x07, 0x7d, 0x0c, 0xe3, 0x56, 0x32, 0xdb, 0x50, 0x5b, 0xe2, 0x79,
-	0x89, 0x16, 0xb6, 0x50, 0x5f, 0x19, 0x7e, 0x25, 0x57, 0x30, 0xa7, 0x77, 0xa6, 0xb9, 0x17, 0x2b,
-	0x84, 0x6d, 0x97, 0xd3, 0x1a, 0x9c, 0x54, 0x79, 0x00, 0x1d, 0xb9, 0x03, 0x26, 0x0e, 0x70, 0x12,
-	0x02, 0x4f, 0x60, 0xd1, 0x29, 0x79, 0x27, 0xb4, 0x0e, 0x4c, 0x82, 0x4c, 0x7a, 0x54, 0x11, 0x7b,
-	0x04, 0x29, 0x17, 0x6b, 0xa1, 0x54, 0xa0, 0xa6, 0x48, 0x9d, 0x05, 0x31, 0x24, 0x5b, 0xd3, 0x66,
-	0x1b, 0x90, 0x53, 0x97, 0xcc, 0x49, 0xc1, 0x45, 0x89, 0x61, 0x3d, 0x33, 0xe7, 0x12, 0x44, 0x84,
-	0x5e, 0xc0, 0xc2, 0x4e, 0x8e, 0x85, 0x81, 0xe8, 0x0c, 0xb0, 0x07, 0xe9, 0x5f, 0x63, 0xaa, 0xd3,
-	0x76, 0x10, 0xe9, 0xfc, 0x0d, 0xae, 0xd4, 0x3f, 0xe7, 0x93, 0x0f, 0xe7, 0x13, 0x36, 0xe7, 0xcf,
-	0x94, 0x96, 0x1f, 0x61, 0x7a, 0xeb, 0x76, 0xdf, 0x9e, 0xc2, 0x6d, 0xa6, 0x9b, 0x5a, 0xe2, 0x5a,
-	0x9f, 0xfb, 0x67, 0x71, 0x42, 0xae, 0x60, 0xe6, 0x00, 0x7b, 0x39, 0xa0, 0x3c, 0x6e, 0x72, 0x8e,
-	0xae, 0xc5, 0x09, 0x4b, 0xf0, 0xfa, 0x3c, 0xff, 0x1d, 0x00, 0x00, 0xff, 0xff, 0x48, 0xd9, 0xcf,
-	0x5f, 0x57, 0x03, 0x00, 0x00,
+func init() { proto.RegisterFile("summary/summary.proto", fileDescriptor_summary_d4a517f9a5ddab0c) }
+
+var fileDescriptor_summary_d4a517f9a5ddab0c = []byte{
+	// 1253 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0xcf, 0x8e, 0xdc, 0x44,
+	0x13, 0x8f, 0xc7, 0x33, 0xf6, 0xb8, 0x3c, 0xb3, 0xc9, 0xd7, 0xfa, 0x92, 0x38, 0x03, 0x84, 0x8d,
+	0x93, 0xc0, 0x46, 0x91, 0x4c, 0xd8, 0x10, 0x89, 0x05, 0x71, 0x18, 0xd0, 0x46, 0x30, 0x0a, 0x28,
+	0x72, 0x84, 0x10, 0xa7, 0x55, 0xdb, 0xee, 0xdd, 0x78, 0xd7, 0xe3, 0x76, 0xba, 0xdb, 0x1b, 0xcd,
+	0x23, 0x70, 0x46, 0x88, 0xc7, 0xe0, 0xc8, 0x4b, 0xf0, 0x04, 0xbc, 0x0a, 0x17, 0xd4, 0xff, 0x3c,
+	0x9e, 0xdd, 0x09, 0xd2, 0x9e, 0xec, 0xfa, 0x55, 0x75, 0x75, 0xd5, 0xaf, 0xba, 0xaa, 0x1b, 0x6e,
+	0xf2, 0x76, 0xb9, 0xc4, 0x6c, 0xf5, 0x89, 0xf9, 0x26, 0x0d, 0xa3, 0x82, 0xc6, 0xbf, 0x0d, 0xc0,
+	0x5d, 0xd0, 0x0c, 0xed, 0xc0, 0xa0, 0x2c, 0x22, 0x67, 0xd7, 0xd9, 0x0b, 0xd2, 0x41, 0x59, 0xa0,
+	0x1b, 0xe0, 0x0a, 0x7c, 0x12, 0x0d, 0x14, 0x20, 0x7f, 0xd1, 0x2d, 0xf0, 0xb8, 0xc0, 0xa2, 0xe5,
+	0x91, 0xab, 0x40, 0x23, 0x49, 0xfc, 0x2d, 0x65, 0x67, 0x84, 0x45, 0x43, 0x8d, 0x6b, 0x09, 0x7d,
+	0x00, 0xd0, 0x36, 0x05, 0x16, 0xa4, 0x38, 0xc2, 0x22, 0x1a, 0xed, 0x3a, 0x7b, 0x6e, 0x1a, 0x18,
+	0x64, 0x2e, 0xd0, 0x3d, 0x98, 0x30, 0xf2, 0xa6, 0x25, 0xdc, 0x18, 0x78, 0xca, 0x20, 0xec, 0xb0,
+	0xb9, 0x90, 0x1e, 0xb8, 0xc0, 0xcc, 0x18, 0xf8, 0xda, 0x83, 0x41, 0xe6, 0x02, 0x45, 0xe0, 0x37,
+	0x78, 0x55, 0x51, 0x5c, 0x44, 0x63, 0xb5, 0xb3, 0x15, 0xd1, 0x03, 0x18, 0x63, 0x21, 0xc8, 0xb2,
+	0x11, 0x3c, 0x0a, 0x76, 0xdd, 0xbd, 0x70, 0x7f, 0x9c, 0xcc, 0x35, 0x90, 0x76, 0x1a, 0xf4, 0x1e,
+	0x04, 0x6f, 0x5a, 0xd2, 0x6a, 0xef, 0xa0, 0xbc, 0x8f, 0x35, 0x30, 0x17, 0xf1, 0xaf, 0x0e, 0xf8,
+	0x66, 0x49, 0x2f, 0x43, 0xe7, 0x62, 0x86, 0xbd, 0xf8, 0x06, 0x17, 0xe3, 0xfb, 0x10, 0xc2, 0xe3,
+	0xb2, 0x2e, 0xf9, 0x6b, 0xad, 0x77, 0x95, 0x1e, 0x2c, 0x34, 0x17, 0x68, 0x06, 0xe3, 0xa2, 0x65,
+	0x58, 0x94, 0xb4, 0x56, 0xdc, 0xb9, 0x69, 0x27, 0xa3, 0xff, 0xc3, 0x88, 0x30, 0x46, 0x99, 0x22,
+	0x2e, 0x48, 0xb5, 0x10, 0xbf, 0x84, 0x60, 0x41, 0xb3, 0x1f, 0x15, 0x89, 0xb2, 0x44, 0xb8, 0x69,
+	0x4c, 0x4c, 0xf2, 0x17, 0xdd, 0x01, 0x9d, 0xc0, 0x51, 0x59, 0x98, 0xca, 0xf9, 0x4a, 0xfe, 0xae,
+	0x40, 0xb7, 0xc0, 0x3d, 0xa5, 0x99, 0x0a, 0x22, 0xdc, 0x1f, 0x26, 0x0b, 0x9a, 0xa5, 0x12, 0x88,
+	0x7f, 0x77, 0x60, 0xb2, 0xa0, 0xd9, 0xd7, 0x55, 0x4b, 0x1a, 0x56, 0xd6, 0x42, 0xfa, 0x38, 0xa5,
+	0xd9, 0x51, 0x8d, 0x97, 0xc4, 0xb8, 0xf6, 0x4f, 0x69, 0xf6, 0x03, 0x5e, 0x12, 0xf4, 0x29, 0x78,
+	0xc7, 0x25, 0xa9, 0x0a, 0x1e, 0x0d, 0x14, 0xa9, 0x77, 0x92, 0xfe, 0xca, 0xe4, 0xb9, 0xd2, 0x1d,
+	0xd6, 0x82, 0xad, 0x52, 0x63, 0x38, 0x3b, 0x80, 0xb0, 0x07, 0xcb, 0x90, 0xcf, 0xc8, 0xca, 0x86,
+	0x7c, 0x46, 0x56, 0x32, 0xcf, 0x73, 0x5c, 0xb5, 0xc4, 0xc4, 0xab, 0x85, 0x2f, 0x06, 0x9f, 0x3b,
+	0xf1, 0xcf, 0x10, 0xbe, 0x24, 0x2c, 0x27, 0xb5, 0x28, 0x2b, 0xc2, 0xa5, 0x61, 0x4e, 0xdb, 0x5a,
+	0xa8, 0xc5, 0xc3, 0x54, 0x0b, 0xd2, 0x61, 0xf3, 0xec, 0x89, 0x5a, 0xec, 0xa4, 0xf2, 0x57, 0x21,
+	0x07, 0xcf, 0x22, 0xd7, 0x20, 0x07, 0xcf, 0x34, 0x72, 0x10, 0x0d, 0x2d, 0x72, 0x10, 0x53, 0xf0,
+	0x5f, 0x60, 0x41, 0xea, 0x7c, 0x85, 0x76, 0x61, 0xf8, 0x16, 0x97, 0xda, 0x6b, 0xb8, 0x3f, 0x49,
+	0x7a, 0x5b, 0xa6, 0x4a, 0x83, 0xee, 0x82, 0xcb, 0xda, 0x3a, 0x1a, 0x6c, 0x31, 0x90, 0x0a, 0x14,
+	0xc3, 0x48, 0x50, 0x81, 0xab, 0xc8, 0xdd, 0x62, 0xa1, 0x55, 0xf1, 0x5f, 0x0e, 0xc0, 0xf7, 0xb4,
+	0x2e, 0x05, 0x65, 0x65, 0x7d, 0x22, 0x73, 0x91, 0xcd, 0x63, 0x09, 0xd6, 0x02, 0xba, 0x0f, 0xd3,
+	0xac, 0x3d, 0x3e, 0x26, 0x8c, 0x14, 0x47, 0xa7, 0x34, 0xe3, 0x6a, 0xcb, 0x69, 0x3a, 0xb1, 0xe0,
+	0x82, 0x66, 0x5c, 0xb6, 0x4d, 0xc1, 0x68, 0xd3, 0x58, 0x1b, 0x57, 0xb1, 0x11, 0x1a, 0x4c, 0x99,
+	0xdc, 0x05, 0x60, 0x24, 0xa7, 0x75, 0x4d, 0x72, 0xc1, 0x55, 0xda, 0xd3, 0xb4, 0x87, 0xc8, 0x63,
+	0x5b, 0x61, 0x2e, 0x8e, 0xfa, 0xe7, 0x2b, 0x90, 0xc8, 0xa1, 0x04, 0xe4, 0x0e, 0xc6, 0x74, 0xa3,
+	0x31, 0x3b, 0x6c, 0x2e, 0xe2, 0x7f, 0x46, 0x30, 0x7a, 0x25, 0xb0, 0xe0, 0x57, 0x3b, 0x83, 0x0f,
+	0x60, 0x68, 0x62, 0x96, 0xa7, 0xe7, 0x46, 0xa2, 0x5c, 0xc8, 0x33, 0x64, 0x0e, 0x8d, 0xd2, 0xca,
+	0xb6, 0xc1, 0xb9, 0x28, 0xcf, 0x89, 0x4e, 0xd0, 0xc4, 0xaf, 0xa1, 0x85, 0x31, 0x30, 0x7d, 0xab,
+	0x0c, 0x46, 0xda, 0x40, 0x43, 0xca, 0xe0, 0x21, 0xec, 0x34, 0x8c, 0xe6, 0x84, 0x73, 0x6b, 0xe3,
+	0x29, 0x9b, 0x69, 0x87, 0x2a, 0xb3, 0xfb, 0x30, 0x2d, 0xc8, 0x31, 0x61, 0x1d, 0xdf, 0xbe, 0xe6,
+	0xdb, 0x82, 0x76, 0xb3, 0x63, 0x5c, 0x56, 0xd6, 0x64, 0xac, 0x37, 0xd3, 0x90, 0xf5, 0xc2, 0x48,
+	0x3f, 0x9e, 0x40, 0x7b, 0xb1, 0xa0, 0x32, 0xfa, 0x0c, 0x76, 0x64, 0x53, 0x65, 0xb6, 0x57, 0x78,
+	0x04, 0x8a, 0x83, 0xe9, 0x46, 0x07, 0xa5, 0xd3, 0xd3, 0x9e, 0xa4, 0xf2, 0xb0, 0xb5, 0x26, 0xe7,
+	0x44, 0xae, 0x0a, 0x55, 0xb5, 0xa7, 0x06, 0x3d, 0x54, 0xa0, 0x2c, 0x18, 0x39, 0x2f, 0x73, 0x61,
+	0x03, 0x98, 0xec, 0xba, 0x7b, 0x41, 0x1a, 0x1a, 0x4c, 0xed, 0x1f, 0x81, 0xaf, 0x67, 0x16, 0x8f,
+	0xa6, 0x2a, 0x3c, 0x2b, 0xca, 0xc5, 0x65, 0x51, 0x91, 0x23, 0xab, 0xde, 0x51, 0xea, 0x50, 0x62,
+	0x3f, 0x19, 0x93, 0xa7, 0x10, 0x54, 0xaa, 0x5b, 0x4a, 0xc2, 0xa3, 0xeb, 0x2a, 0xee, 0x9b, 0xa6,
+	0x76, 0x2f, 0x2c, 0xae, 0x0b, 0xb8, 0xb6, 0x43, 0x8f, 0x01, 0x96, 0xdd, 0x81, 0x8f, 0x6e, 0xa8,
+	0xd6, 0x08, 0x93, 0x75, 0x0f, 0xa4, 0x3d, 0xb5, 0x4c, 0x34, 0xc7, 0x75, 0x4e, 0xaa, 0x8e, 0xe7,
+	0xff, 0xe9, 0x82, 0x75, 0xa8, 0xcc, 0x62, 0xf6, 0x95, 0x9a, 0x7e, 0xef, 0x1c, 0x25, 0xb3, 0xfe,
+	0x28, 0xb1, 0x43, 0x6e, 0x3d, 0x50, 0x66, 0xcf, 0x61, 0x67, 0x33, 0xde, 0x2d, 0x3e, 0xee, 0x6e,
+	0xfa, 0x18, 0x9b, 0x0c, 0x57, 0xfd, 0xc1, 0xf4, 0xb7, 0x03, 0xc1, 0xb7, 0x04, 0x33, 0x91, 0x11,
+	0x2c, 0xae, 0xd6, 0x01, 0xb7, 0xc1, 0xe7, 0xa4, 0x16, 0xeb, 0xeb, 0xc0, 0x93, 0xe2, 0x5c, 0x5d,
+	0x31, 0x6d, 0x23, 0xca, 0x25, 0x31, 0x17, 0x81, 0x91, 0xfa, 0x85, 0x1b, 0xfd, 0x77, 0xe1, 0xbc,
+	0xcb, 0x85, 0x8b, 0xc0, 0xcf, 0x70, 0x7e, 0x56, 0xd1, 0x13, 0x73, 0xb4, 0xad, 0x28, 0x35, 0xe7,
+	0x84, 0x71, 0x79, 0xf1, 0x98, 0xab, 0xd3, 0x88, 0x31, 0x85, 0x50, 0x95, 0x36, 0x25, 0x0d, 0x65,
+	0xf2, 0x8e, 0xd5, 0x2d, 0xeb, 0xec, 0xba, 0x1d, 0xa5, 0x0a, 0x41, 0xef, 0xeb, 0x19, 0xc6, 0x0d,
+	0x53, 0x9e, 0x3e, 0x11, 0x7a, 0x96, 0x71, 0xb4, 0x07, 0xc1, 0x6b, 0x4b, 0x91, 0x19, 0x8c, 0x90,
+	0x74, 0xa4, 0xa5, 0x6b, 0x65, 0xfc, 0x10, 0x02, 0xbd, 0xd7, 0x3c, 0x3f, 0x93, 0x71, 0x31, 0x25,
+	0x70, 0x33, 0xe6, 0xad, 0x18, 0xaf, 0x60, 0xe7, 0xb0, 0x56, 0x34, 0xa6, 0xfa, 0x85, 0x70, 0x35,
+	0xe2, 0xcd, 0x73, 0xc6, 0x5d, 0x3f, 0x67, 0x10, 0x0c, 0x0b, 0x2c, 0xb0, 0x79, 0xb4, 0xa8, 0x7f,
+	0x39, 0x97, 0x19, 0x11, 0x6c, 0x65, 0xb8, 0xd6, 0x42, 0x7c, 0x00, 0x93, 0x6e, 0xeb, 0xa6, 0x5a,
+	0x5d, 0x7a, 0x2a, 0xbd, 0x7b, 0xdb, 0xf8, 0x4b, 0x80, 0x05, 0xcd, 0xbe, 0xa1, 0xcb, 0x25, 0xae,
+	0x0b, 0x74, 0x13, 0x3c, 0x39, 0x05, 0xba, 0xc5, 0xa3, 0x53, 0x9a, 0xa9, 0xab, 0xd9, 0x3e, 0xac,
+	0x06, 0xfd, 0x87, 0x55, 0xfc, 0x87, 0x03, 0xbe, 0x5d, 0x7a, 0x71, 0xcf, 0x47, 0xe0, 0x13, 0x1d,
+	0x93, 0xe1, 0xff, 0x7a, 0xb2, 0x49, 0x4f, 0x6a, 0xf5, 0xe8, 0x9e, 0x4d, 0xca, 0x35, 0x4d, 0xb8,
+	0x8e, 0xc8, 0x64, 0x88, 0xee, 0x83, 0xa7, 0x3b, 0x2d, 0x1a, 0x5e, 0xb6, 0x31, 0x2a, 0xe9, 0xa7,
+	0x69, 0xd9, 0x09, 0x89, 0x46, 0x97, 0x6d, 0xb4, 0x26, 0xfe, 0xd3, 0x81, 0x89, 0x85, 0xb6, 0x52,
+	0x65, 0x6a, 0x36, 0xd8, 0x5e, 0x33, 0x77, 0xb3, 0x66, 0x08, 0x86, 0x39, 0x2d, 0x88, 0xb9, 0x01,
+	0xd4, 0xff, 0xf6, 0x67, 0x11, 0xfa, 0x78, 0xcd, 0x86, 0xa7, 0x82, 0x9b, 0x26, 0xfd, 0x8a, 0xad,
+	0xb9, 0xb8, 0x0d, 0xbe, 0xae, 0x80, 0x1c, 0xf6, 0x72, 0x4a, 0x7a, 0xaa, 0x04, 0x7c, 0xff, 0x17,
+	0x07, 0xfc, 0x57, 0xfa, 0x61, 0x8c, 0x3e, 0x02, 0xcf, 0x9c, 0xfe, 0x49, 0xd2, 0xeb, 0x85, 0x19,
+	0x24, 0xdd, 0x41, 0x8d, 0xaf, 0xed, 0x39, 0xe8, 0x31, 0xf8, 0x66, 0x17, 0x74, 0x91, 0xfd, 0xd9,
+	0x66, 0x00, 0xf1, 0x35, 0xf4, 0x08, 0xc6, 0x86, 0x19, 0x8e, 0xa6, 0x49, 0x9f, 0xa4, 0xd9, 0xd8,
+	0x8a, 0xd2, 0xeb, 0x13, 0x27, 0xf3, 0xd4, 0xcb, 0xfc, 0xe9, 0xbf, 0x03, 0x00, 0x87, 0xec, 0x00,
+	0xb6, 0xb2, 0x0b, 0x00, 0x00,
 }
@@ -0,0 +1,320 @@
+// Package trace records spans for queued jobs, propagating W3C trace context
+// so traces started by an OpenTelemetry instrumented caller continue through
+// the queue
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// spanBuffer is the number of ended spans held for the exporter before new
+// spans are dropped
+const spanBuffer = 2048
+
+// closeTimeout is how long closing a tracer waits for the buffered spans to
+// be exported
+const closeTimeout = time.Second
+
+// TraceID identifies a whole trace
+type TraceID [16]byte
+
+// IsValid reports whether the id is not all zeroes
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// IsValid reports whether the id is not all zeroes
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of a span propagated to its children
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether the span context identifies a span
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats the span context as a W3C traceparent header value,
+// empty when the span context is not valid
+func (sc SpanContext) Traceparent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent reads a span context from a W3C traceparent header value
+func ParseTraceparent(traceparent string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("invalid traceparent %q", traceparent)
+	}
+	if err := decode(sc.TraceID[:], parts[1]); err != nil {
+		return sc, fmt.Errorf("invalid trace id in traceparent %q", traceparent)
+	}
+	if err := decode(sc.SpanID[:], parts[2]); err != nil {
+		return sc, fmt.Errorf("invalid span id in traceparent %q", traceparent)
+	}
+	var flags [1]byte
+	if err := decode(flags[:], parts[3]); err != nil {
+		return sc, fmt.Errorf("invalid flags in traceparent %q", traceparent)
+	}
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent %q", traceparent)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+func decode(dst []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(dst)) {
+		return fmt.Errorf("expected %d hex characters", hex.EncodedLen(len(dst)))
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+// MarshalText encodes the span context as a traceparent
+func (sc SpanContext) MarshalText() ([]byte, error) {
+	return []byte(sc.Traceparent()), nil
+}
+
+// UnmarshalText decodes a traceparent, an empty value is the zero span context
+func (sc *SpanContext) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*sc = SpanContext{}
+		return nil
+	}
+	parsed, err := ParseTraceparent(string(text))
+	if err != nil {
+		return err
+	}
+	*sc = parsed
+	return nil
+}
+
+type contextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying the span context, so
+// jobs queued with it join the trace
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, contextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context carried by ctx, the zero
+// span context if there is none
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	sc, _ := ctx.Value(contextKey{}).(SpanContext)
+	return sc
+}
+
+// Span is a timed operation within a trace
+type Span struct {
+	Name        string
+	SpanContext SpanContext
+	Parent      SpanContext
+	StartTime   time.Time
+	EndTime     time.Time
+	Attributes  map[string]string
+	// Error is the message of the error the operation failed with
+	Error string
+
+	tracer *Tracer
+}
+
+// SetAttribute records a key value pair describing the span
+func (s *Span) SetAttribute(key, value string) {
+	s.Attributes[key] = value
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if err != nil {
+		s.Error = err.Error()
+	}
+}
+
+// End finishes the span at the given time and exports it
+func (s *Span) End(at time.Time) {
+	s.EndTime = at
+	s.tracer.export(*s)
+}
+
+// Exporter receives finished spans. Spans are exported in batches from a
+// single goroutine, a slow exporter only causes spans to be dropped.
+type Exporter interface {
+	ExportSpans(spans []Span) error
+}
+
+// Tracer starts spans and hands them to an exporter once they end, without
+// ever blocking the operation being traced
+type Tracer struct {
+	exporter Exporter
+	spans    chan Span
+	removed  chan bool
+	mutex    sync.RWMutex
+	closed   bool
+	dropped  uint64
+}
+
+// NewTracer creates a tracer exporting to the given exporter
+func NewTracer(exporter Exporter) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		spans:    make(chan Span, spanBuffer),
+		removed:  make(chan bool),
+	}
+	go t.run()
+	return t
+}
+
+// Dropped is the number of spans discarded because the exporter fell behind
+func (t *Tracer) Dropped() uint64 {
+	return atomic.LoadUint64(&t.dropped)
+}
+
+// Close exports the spans still buffered and stops the tracer, waiting at
+// most a second on the exporter. Spans ending afterwards are dropped.
+func (t *Tracer) Close() {
+	t.mutex.Lock()
+	if t.closed {
+		t.mutex.Unlock()
+		return
+	}
+	t.closed = true
+	close(t.spans)
+	t.mutex.Unlock()
+
+	select {
+	case <-t.removed:
+	case <-time.After(closeTimeout):
+	}
+}
+
+// run exports spans as they arrive, batching whatever is buffered at the time
+func (t *Tracer) run() {
+	defer close(t.removed)
+
+	for span := range t.spans {
+		batch := []Span{span}
+	buffered:
+		for len(batch) < spanBuffer {
+			select {
+			case span, ok := <-t.spans:
+				if !ok {
+					break buffered
+				}
+				batch = append(batch, span)
+			default:
+				break buffered
+			}
+		}
+		t.exporter.ExportSpans(batch)
+	}
+}
+
+// Start begins a span at the given time as a child of parent, or as the root
+// of a new trace if parent is not valid
+func (t *Tracer) Start(name string, parent SpanContext, at time.Time) *Span {
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: true}
+	if parent.IsValid() {
+		sc.Sampled = parent.Sampled
+	} else {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+
+	return &Span{
+		Name:        name,
+		SpanContext: sc,
+		Parent:      parent,
+		StartTime:   at,
+		Attributes:  make(map[string]string, 0),
+		tracer:      t,
+	}
+}
+
+func (t *Tracer) export(span Span) {
+	if t == nil || t.exporter == nil || !span.SpanContext.Sampled {
+		return
+	}
+	span.tracer = nil
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if t.closed {
+		atomic.AddUint64(&t.dropped, 1)
+		return
+	}
+	select {
+	case t.spans <- span:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// InMemoryExporter holds every exported span, intended for tests
+type InMemoryExporter struct {
+	mutex sync.Mutex
+	spans []Span
+}
+
+// NewInMemoryExporter creates an empty in memory exporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{spans: make([]Span, 0)}
+}
+
+// ExportSpans stores the spans
+func (e *InMemoryExporter) ExportSpans(spans []Span) error {
+	e.mutex.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mutex.Unlock()
+	return nil
+}
+
+// Spans returns a copy of the spans exported so far
+func (e *InMemoryExporter) Spans() []Span {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]Span{}, e.spans...)
+}
+
+// Reset discards the exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mutex.Lock()
+	e.spans = make([]Span, 0)
+	e.mutex.Unlock()
+}
@@ -0,0 +1,45 @@
+package rift
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/bmartel/rift/trace"
+)
+
+// startSpan begins a span for an operation on a job, returns nil when tracing
+// is disabled
+func (q *Queue) startSpan(operation string, parent trace.SpanContext, job ReservedJob, at time.Time) *trace.Span {
+	if q.tracer == nil {
+		return nil
+	}
+
+	span := q.tracer.Start(job.Job.Tag()+" "+operation, parent, at)
+	span.SetAttribute("messaging.system", "rift")
+	span.SetAttribute("messaging.operation", operation)
+	span.SetAttribute("messaging.destination.name", q.stats.App)
+	span.SetAttribute("messaging.message.id", job.ID.String())
+	span.SetAttribute("rift.queue_id", q.id)
+	span.SetAttribute("rift.job.tag", job.Job.Tag())
+	span.SetAttribute("rift.job.attempt", strconv.Itoa(int(job.Requeued)+1))
+	return span
+}
+
+// process runs a job through the handler, recording the time this attempt
+// waited in the queue and the time it took to process as spans
+func (w *Worker) process(handler Handler, job ReservedJob) error {
+	wait := w.queue.startSpan("wait", job.Trace, job, job.queuedAt())
+	if wait == nil {
+		return handler(w, job, w.service)
+	}
+	wait.SetAttribute("rift.worker_id", w.ID.String())
+	wait.End(job.StartedAt)
+
+	span := w.queue.startSpan("process", job.Trace, job, job.StartedAt)
+	span.SetAttribute("rift.worker_id", w.ID.String())
+
+	err := handler(w, job, w.service)
+	span.SetError(err)
+	span.End(time.Now())
+	return err
+}
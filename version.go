@@ -0,0 +1,4 @@
+package rift
+
+// Version is the version of rift, reported to the stats server in heartbeats
+const Version = "0.5.0"
@@ -1,12 +1,14 @@
 package rift
 
 import (
+	"context"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/bmartel/rift/summary"
+	"github.com/bmartel/rift/trace"
 	"github.com/satori/go.uuid"
-	"go.uber.org/zap"
 )
 
 var (
@@ -24,13 +26,79 @@ type Job interface {
 	Process(Service) error
 }
 
+// ContextJob is a job which can be interrupted, the context it processes with
+// is cancelled when the job is cancelled while running
+type ContextJob interface {
+	Job
+	ProcessContext(context.Context, Service) error
+}
+
 // ReservedJob is the serializable job which is queued and consumed
 type ReservedJob struct {
 	ID          uuid.UUID
 	Job         Job
 	RequestedAt time.Time
-	Retry       uint8
-	Requeued    uint8
+	// QueuedAt is when the current attempt was queued, the same as RequestedAt
+	// until the job is retried
+	QueuedAt  time.Time
+	StartedAt time.Time
+	Retry     uint8
+	Requeued  uint8
+	// Trace is the span context the job was queued under, its queue wait and
+	// processing are recorded as children of it
+	Trace trace.SpanContext
+
+	batch *Batch
+	ctx   context.Context
+	// payload and attempts are reported with every update of the job, the
+	// attempts are never changed once reported
+	payload  string
+	attempts []*summary.Attempt
+}
+
+func newReservedJob(job Job, retry uint8) ReservedJob {
+	return ReservedJob{
+		ID:          uuid.NewV4(),
+		Job:         job,
+		RequestedAt: time.Now(),
+		Retry:       retry,
+	}
+}
+
+// queuedAt is when the current attempt was queued, falling back to when the
+// job was requested for jobs queued before it was recorded
+func (j ReservedJob) queuedAt() time.Time {
+	if j.QueuedAt.IsZero() {
+		return j.RequestedAt
+	}
+	return j.QueuedAt
+}
+
+// Context is cancelled when the job is cancelled while running
+func (j ReservedJob) Context() context.Context {
+	if j.ctx == nil {
+		return context.Background()
+	}
+	return j.ctx
+}
+
+// attempt records an attempt of the job, replacing the latest one if it is
+// the same attempt finishing
+func (j *ReservedJob) attempt(a *summary.Attempt, finished bool) {
+	n := len(j.attempts)
+	if finished && n > 0 {
+		n--
+	}
+	attempts := make([]*summary.Attempt, n, n+1)
+	copy(attempts, j.attempts)
+	j.attempts = append(attempts, a)
+}
+
+// finish reports the final outcome of the job to its batch, if it belongs to one
+func (j ReservedJob) finish(err error) {
+	if j.batch != nil {
+		j.batch.done(err)
+	}
 }
 
 // Worker represents the worker that executes the job
@@ -43,8 +111,7 @@ type Worker struct {
 	queue   *Queue
 	service Service
 
-	logger  *zap.Logger
-	verbose bool
+	logger *eventLogger
 }
 
 func dispatchWorker(queue *Queue, service Service, opts *Options) uuid.UUID {
@@ -56,9 +123,11 @@ func dispatchWorker(queue *Queue, service Service, opts *Options) uuid.UUID {
 		quit:    make(chan bool),
 		queue:   queue,
 		service: service,
-		logger:  queue.logger.With(zap.String("worker", id.String())),
-		verbose: opts.Verbose,
+		logger:  queue.logger.with(field("worker", id.String())),
 	}
+	// register the worker before it starts so closing the queue straight
+	// after it was created still finds every worker
+	queue.workers <- w
 	go w.Open()
 	return id
 }
@@ -66,35 +135,16 @@ func dispatchWorker(queue *Queue, service Service, opts *Options) uuid.UUID {
 // Open method starts the run loop for the worker, listening for a quit channel in
 // case we need to stop
 func (w *Worker) Open() {
-	if w.verbose {
-		w.logger.Info("worker started")
-	}
-
-	// register the current worker into the worker queue.
-	w.queue.workers <- w
+	w.logger.log(LogWorkerStarted)
 
 	for {
 		select {
 		case job := <-w.channel:
-			w.queue.metrics <- &summary.Job{Id: job.ID.String(), Tag: job.Job.Tag(), Status: "started", Worker: w.ID.String()}
-			w.logger.Info("job started", zap.String("job", job.ID.String()))
-			// we have received a work request.
-			if err := job.Job.Process(w.service); err != nil {
-				w.queue.metrics <- &summary.Job{Id: job.ID.String(), Tag: job.Job.Tag(), Status: "failed", Worker: w.ID.String()}
-				w.logger.Error("job failed: "+err.Error(), zap.String("job", job.ID.String()))
-				if job.Retry > job.Requeued {
-					w.queue.metrics <- &summary.Job{Id: job.ID.String(), Tag: job.Job.Tag(), Status: "requeued", Worker: w.ID.String()}
-					w.logger.Info("job requeued", zap.String("job", job.ID.String()))
-					// requeue the job
-					job.Requeued++
-					w.queue.channel <- job
-				}
-			} else {
-				w.queue.metrics <- &summary.Job{Id: job.ID.String(), Tag: job.Job.Tag(), Status: "processed", Worker: w.ID.String()}
-				w.logger.Info("job processed", zap.String("job", job.ID.String()), zap.Float64("duration", time.Since(job.RequestedAt).Seconds()))
-				// Put the worker back into the queue reserve for another job to use
-				w.queue.workers <- w
-			}
+			w.run(job)
+			// Put the worker back into the queue reserve for another job to
+			// use, whatever the outcome of the job. A worker lost after a
+			// failure would shrink the pool until no jobs run at all.
+			w.queue.workers <- w
 		case <-w.quit:
 			close(w.channel)
 			close(w.quit)
@@ -104,12 +154,75 @@ func (w *Worker) Open() {
 	}
 }
 
+// run processes a job, skipping it if it was cancelled while queued
+func (w *Worker) run(job ReservedJob) {
+	atomic.AddInt64(&w.queue.backlog, -1)
+	if job.payload == "" {
+		job.payload = w.queue.registry.encodePayload(job)
+	}
+	if w.queue.takeCancelled(job.ID) {
+		w.track(EventCancelled, job, ErrJobCancelled)
+		job.finish(ErrJobCancelled)
+		return
+	}
+
+	job.StartedAt = time.Now()
+	job.ctx = w.queue.startRunning(job.ID)
+	job.attempt(&summary.Attempt{Worker: w.ID.String(), StartedAt: job.StartedAt.UnixNano()}, false)
+	w.track(EventStarted, job, nil)
+	// we have received a work request, run it through the middleware chain.
+	handler := w.queue.handler(job.Job.Tag())
+	err := w.process(handler, job)
+
+	finished := time.Now()
+	attempt := &summary.Attempt{
+		Worker:     w.ID.String(),
+		StartedAt:  job.StartedAt.UnixNano(),
+		FinishedAt: finished.UnixNano(),
+		Duration:   int64(finished.Sub(job.StartedAt)),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	job.attempt(attempt, true)
+
+	if w.queue.stopRunning(job.ID) {
+		w.track(EventCancelled, job, ErrJobCancelled)
+		job.finish(ErrJobCancelled)
+	} else if err != nil {
+		w.track(EventFailed, job, err)
+		if job.Retry > job.Requeued {
+			w.track(EventRetried, job, err)
+			w.logger.log(LogJobRequeued, field("job", job.ID.String()))
+			// requeue the job
+			job.Requeued++
+			job.QueuedAt = time.Now()
+			job.ctx = nil
+			w.queue.markQueued(job.ID)
+			atomic.AddInt64(&w.queue.backlog, 1)
+			w.queue.channel <- job
+		} else {
+			w.queue.bury(job)
+			w.track(EventDead, job, err)
+			job.finish(err)
+		}
+	} else {
+		w.track(EventSucceeded, job, nil)
+		job.finish(nil)
+	}
+}
+
+// track records a lifecycle event for a job handled by this worker
+func (w *Worker) track(eventType EventType, job ReservedJob, err error) {
+	e := newEvent(eventType, job, err)
+	e.Worker = w.ID
+	w.queue.track(e)
+}
+
 // Close signals the worker to stop listening for work requests.
 func (w *Worker) Close() {
 	w.quit <- true
 	<-w.removed // wait for the worker to exit
 	close(w.removed)
-	if w.verbose {
-		w.logger.Info("worker stopped")
-	}
+	w.logger.log(LogWorkerStopped)
 }